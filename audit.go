@@ -0,0 +1,194 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// MarshalJSON encodes Err as its message.
+func (r AuditRecord) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Time       time.Time         `json:"time"`
+		User       string            `json:"user"`
+		Host       string            `json:"host,omitempty"`
+		Operation  string            `json:"operation"`
+		Service    string            `json:"service"`
+		Parameters map[string]string `json:"parameters,omitempty"`
+		Result     string            `json:"result"`
+		Error      string            `json:"error,omitempty"`
+	}{Time: r.Time, User: r.User, Host: r.Host, Operation: r.Operation, Service: r.Service, Parameters: r.Parameters, Result: "success"}
+	if r.Err != nil {
+		v.Result = "failure"
+		v.Error = r.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+var (
+	auditMu sync.RWMutex
+	auditor Auditor
+)
+
+// SetAuditor makes a receive a record of every management operation
+// performed from now on. A nil a turns auditing off, which is the default.
+// Use MultiAuditor to send records to several destinations.
+func SetAuditor(a Auditor) {
+	auditMu.Lock()
+	auditor = a
+	auditMu.Unlock()
+}
+
+// audit records an operation on the named service if auditing is on,
+// traces it through ETW and notifies the observers.
+func (m *Manager) audit(op, name string, params map[string]string, err error) {
+	m.auditState(op, name, params, controlOps[op], err)
+}
+
+// auditState is like audit for a control operation that was seen to leave
+// the service in state.
+func (m *Manager) auditState(op, name string, params map[string]string, state State, err error) {
+	traceOperation(op, m.host, name, err)
+	m.observe(op, name, params, state, err)
+
+	auditMu.RLock()
+	a := auditor
+	auditMu.RUnlock()
+	if a == nil {
+		return
+	}
+	a.Audit(AuditRecord{
+		Time:       time.Now(),
+		User:       auditUser(),
+		Host:       m.host,
+		Operation:  op,
+		Service:    name,
+		Parameters: params,
+		Err:        err,
+	})
+}
+
+// auditResults records an operation on a batch of services, which left
+// them in state, one record per service.
+func (m *Manager) auditResults(op string, state State, results []BatchResult) {
+	for _, r := range results {
+		m.auditState(op, r.Name, nil, state, r.Err)
+	}
+}
+
+// auditSetResults records an operation on a set of services, which left
+// them in state, one record per service that was not skipped.
+func (m *Manager) auditSetResults(op string, state State, results []SetResult) {
+	for _, r := range results {
+		if !r.Skipped {
+			m.auditState(op, r.Name, nil, state, r.Err)
+		}
+	}
+}
+
+// auditUser returns the account of the current process token.
+var auditUser = sync.OnceValue(func() string {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return "unknown"
+	}
+	account, domain, _, err := user.User.Sid.LookupAccount("")
+	if err != nil {
+		return user.User.Sid.String()
+	}
+	return domain + `\` + account
+})
+
+type multiAuditor []Auditor
+
+func (m multiAuditor) Audit(r AuditRecord) {
+	for _, a := range m {
+		a.Audit(r)
+	}
+}
+
+// MultiAuditor returns an Auditor that passes every record to each of
+// auditors.
+func MultiAuditor(auditors ...Auditor) Auditor {
+	return multiAuditor(auditors)
+}
+
+// auditEventID is the event ID of audit records written to the event log.
+const auditEventID = 100
+
+// EventLogAuditor writes audit records to the Application event log as JSON,
+// as information events for successful operations and warning events for
+// failed ones.
+type EventLogAuditor struct {
+	log *eventlog.Log
+}
+
+// NewEventLogAuditor returns an EventLogAuditor writing with the given
+// event source, which must already be registered, for example by
+// InstallService for the service that performs the operations.
+func NewEventLogAuditor(source string) (*EventLogAuditor, error) {
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return &EventLogAuditor{log: l}, nil
+}
+
+// Audit implements Auditor.
+func (a *EventLogAuditor) Audit(r AuditRecord) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	if r.Err != nil {
+		a.log.Warning(auditEventID, string(data))
+	} else {
+		a.log.Info(auditEventID, string(data))
+	}
+}
+
+// Close closes the event log.
+func (a *EventLogAuditor) Close() error {
+	return a.log.Close()
+}
+
+// FileAuditor appends audit records to a file, one JSON object per line.
+type FileAuditor struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileAuditor returns a FileAuditor appending to the file at path,
+// creating it if necessary.
+func NewFileAuditor(path string) (*FileAuditor, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &FileAuditor{f: f}, nil
+}
+
+// Audit implements Auditor.
+func (a *FileAuditor) Audit(r AuditRecord) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.f.Write(append(data, '\n'))
+}
+
+// Close closes the audit file.
+func (a *FileAuditor) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.f.Close()
+}
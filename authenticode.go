@@ -0,0 +1,129 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// VerifySignature checks that the executable at path carries a valid
+// Authenticode signature that chains to a trusted root. If publishers are
+// given, the signing certificate's subject common name must also match one
+// of them, compared case-insensitively, such as "Contoso Ltd". The whole
+// certificate chain is checked for revocation. Failures are returned as
+// errors wrapping ErrUntrustedExecutable.
+func VerifySignature(path string, publishers ...string) error {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	data := &windows.WinTrustData{
+		Size:             uint32(unsafe.Sizeof(windows.WinTrustData{})),
+		UIChoice:         windows.WTD_UI_NONE,
+		RevocationChecks: windows.WTD_REVOKE_WHOLECHAIN,
+		UnionChoice:      windows.WTD_CHOICE_FILE,
+		StateAction:      windows.WTD_STATEACTION_VERIFY,
+		FileOrCatalogOrBlobOrSgnrOrCert: unsafe.Pointer(&windows.WinTrustFileInfo{
+			Size:     uint32(unsafe.Sizeof(windows.WinTrustFileInfo{})),
+			FilePath: p,
+		}),
+	}
+	err = windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+	data.StateAction = windows.WTD_STATEACTION_CLOSE
+	windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+	if err != nil {
+		return &sentinelError{
+			sentinel: ErrUntrustedExecutable,
+			err:      fmt.Errorf("signature of %s did not verify: %w", path, err),
+		}
+	}
+
+	if len(publishers) == 0 {
+		return nil
+	}
+	signer, err := signerName(p)
+	if err != nil {
+		return fmt.Errorf("failed to read signer of %s: %w", path, err)
+	}
+	for _, publisher := range publishers {
+		if strings.EqualFold(signer, publisher) {
+			return nil
+		}
+	}
+	return newError(ErrUntrustedExecutable, "%s is signed by %q, not by an allowed publisher", path, signer)
+}
+
+// signerName returns the subject common name of the certificate that signed
+// the file at path.
+func signerName(path *uint16) (string, error) {
+	var encoding, contentType, formatType uint32
+	var store, msg windows.Handle
+	err := windows.CryptQueryObject(windows.CERT_QUERY_OBJECT_FILE, unsafe.Pointer(path),
+		windows.CERT_QUERY_CONTENT_FLAG_PKCS7_SIGNED_EMBED, windows.CERT_QUERY_FORMAT_FLAG_BINARY, 0,
+		&encoding, &contentType, &formatType, &store, &msg, nil)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CertCloseStore(store, 0)
+	defer cryptMsgClose(msg)
+
+	info, buf, err := cryptMsgSignerInfo(msg)
+	if err != nil {
+		return "", err
+	}
+	certInfo := windows.CertInfo{Issuer: info.Issuer, SerialNumber: info.SerialNumber}
+	ctx, err := windows.CertFindCertificateInStore(store, windows.X509_ASN_ENCODING|windows.PKCS_7_ASN_ENCODING, 0,
+		windows.CERT_FIND_SUBJECT_CERT, unsafe.Pointer(&certInfo), nil)
+	runtime.KeepAlive(buf)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CertFreeCertificateContext(ctx)
+
+	cert, err := x509.ParseCertificate(unsafe.Slice(ctx.EncodedCert, ctx.Length))
+	if err != nil {
+		return "", err
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// VerifyServiceSignature checks the Authenticode signature of a Windows
+// service's executable like VerifySignature, for example before starting a
+// service that someone else installed.
+func VerifyServiceSignature(name string, publishers ...string) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.VerifySignature(name, publishers...)
+	})
+}
+
+// VerifySignature checks the signature of the named service's executable,
+// like VerifyServiceSignature.
+func (m *Manager) VerifySignature(name string, publishers ...string) error {
+	config, err := m.Config(name)
+	if err != nil {
+		return err
+	}
+	exe, _ := splitImagePath(config.BinaryPath)
+	return m.verifyExecutable(exe, publishers)
+}
+
+// verifyExecutable verifies the signature of the executable at path on the
+// machine m is connected to.
+func (m *Manager) verifyExecutable(path string, publishers []string) error {
+	if expanded, err := registry.ExpandString(path); err == nil {
+		path = expanded
+	}
+	target, err := m.adminSharePath(path)
+	if err != nil {
+		return err
+	}
+	return VerifySignature(target, publishers...)
+}
@@ -0,0 +1,150 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// MarshalJSON encodes Err as its message.
+func (r BatchResult) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Name   string        `json:"name"`
+		Status ServiceStatus `json:"status"`
+		Error  string        `json:"error,omitempty"`
+	}{Name: r.Name, Status: r.Status}
+	if r.Err != nil {
+		v.Error = r.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// BatchStart starts the named services and waits for each to run, working
+// on up to concurrency services at a time over a single service control
+// manager connection. Results are returned in the order of names.
+func BatchStart(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return batchWithManager(ctx, names, func(m *Manager) []BatchResult {
+		return m.BatchStart(ctx, names, concurrency)
+	})
+}
+
+// BatchStart starts the named services over m's connection, like the
+// package-level BatchStart.
+func (m *Manager) BatchStart(ctx context.Context, names []string, concurrency int) []BatchResult {
+	results := m.runBatch(ctx, names, concurrency, windows.SERVICE_START|windows.SERVICE_QUERY_STATUS, func(s *mgr.Service) error {
+		err := s.Start()
+		if err != nil && !errors.Is(err, windows.ERROR_SERVICE_ALREADY_RUNNING) {
+			return fmt.Errorf("could not start service: %w", scmError(err))
+		}
+		return m.waitRunning(ctx, s)
+	})
+	m.auditResults("start", StateRunning, results)
+	return results
+}
+
+// BatchStop stops the named services and waits for each to stop, working
+// on up to concurrency services at a time over a single service control
+// manager connection. Results are returned in the order of names.
+func BatchStop(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return batchWithManager(ctx, names, func(m *Manager) []BatchResult {
+		return m.BatchStop(ctx, names, concurrency)
+	})
+}
+
+// BatchStop stops the named services over m's connection, like the
+// package-level BatchStop.
+func (m *Manager) BatchStop(ctx context.Context, names []string, concurrency int) []BatchResult {
+	results := m.runBatch(ctx, names, concurrency, windows.SERVICE_QUERY_STATUS, func(s *mgr.Service) error {
+		return m.stopAndWait(ctx, s.Name)
+	})
+	m.auditResults("stop", StateStopped, results)
+	return results
+}
+
+// BatchQuery queries the named services, up to concurrency at a time over a
+// single service control manager connection. Results are returned in the
+// order of names.
+func BatchQuery(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return batchWithManager(ctx, names, func(m *Manager) []BatchResult {
+		return m.BatchQuery(ctx, names, concurrency)
+	})
+}
+
+// BatchQuery queries the named services over m's connection, like the
+// package-level BatchQuery.
+func (m *Manager) BatchQuery(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return m.runBatch(ctx, names, concurrency, windows.SERVICE_QUERY_STATUS, func(s *mgr.Service) error {
+		return nil
+	})
+}
+
+// batchWithManager runs fn on a new connection, reporting a connection
+// failure as the result for every service.
+func batchWithManager(ctx context.Context, names []string, fn func(*Manager) []BatchResult) []BatchResult {
+	m, err := ConnectCtx(ctx)
+	if err != nil {
+		results := make([]BatchResult, len(names))
+		for i, name := range names {
+			results[i] = BatchResult{Name: name, Err: err}
+		}
+		return results
+	}
+	defer m.Disconnect()
+	return fn(m)
+}
+
+func (m *Manager) runBatch(ctx context.Context, names []string, concurrency int, access uint32, op func(*mgr.Service) error) []BatchResult {
+	results := make([]BatchResult, len(names))
+	for i, name := range names {
+		results[i].Name = name
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			results[i].Status, results[i].Err = m.runOp(results[i].Name, access, op)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runOp opens the named service with access, which must include
+// SERVICE_QUERY_STATUS, runs op on it, and returns the service's status
+// afterwards.
+func (m *Manager) runOp(name string, access uint32, op func(*mgr.Service) error) (ServiceStatus, error) {
+	s, err := m.openService(name, access)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+	defer s.Close()
+
+	opErr := op(s)
+	status, err := queryStatus(s)
+	if err != nil && opErr == nil {
+		opErr = fmt.Errorf("could not query service status: %w", err)
+	}
+	return status, opErr
+}
@@ -0,0 +1,95 @@
+//go:build windows
+
+package winsvc
+
+import "golang.org/x/sys/windows"
+
+// CurrentOSVersion returns the version of Windows running, as reported by
+// RtlGetVersion, which unlike GetVersionEx does not depend on the
+// compatibility section of the program's manifest.
+func CurrentOSVersion() OSVersion {
+	info := windows.RtlGetVersion()
+	return OSVersion{Major: info.MajorVersion, Minor: info.MinorVersion, Build: info.BuildNumber}
+}
+
+// capability is a service feature introduced by a release of Windows.
+type capability struct {
+	feature             string
+	release             string
+	major, minor, build uint32
+}
+
+var (
+	capDelayedAutoStart = capability{"delayed automatic start", "Windows Vista", 6, 0, 0}
+	capSIDType          = capability{"service SID types", "Windows Vista", 6, 0, 0}
+	capPrivileges       = capability{"required privileges", "Windows Vista", 6, 0, 0}
+	capNonCrashFailures = capability{"failure actions on non-crash failures", "Windows Vista", 6, 0, 0}
+	capTriggers         = capability{"service triggers", "Windows 7", 6, 1, 0}
+	capProtected        = capability{"protected services", "Windows 8.1", 6, 3, 0}
+	capPerUser          = capability{"per-user services", "Windows 10 version 1607", 10, 0, 14393}
+)
+
+func (c capability) supported() bool {
+	return CurrentOSVersion().AtLeast(c.major, c.minor, c.build)
+}
+
+// check returns an *UnsupportedError if the running Windows lacks c.
+func (c capability) check() error {
+	if v := CurrentOSVersion(); !v.AtLeast(c.major, c.minor, c.build) {
+		return &UnsupportedError{Feature: c.feature, Required: c.release, Version: v}
+	}
+	return nil
+}
+
+// SupportsDelayedAutoStart reports whether services can be started
+// automatically after the others, with AutoDelayStart.
+func SupportsDelayedAutoStart() bool {
+	return capDelayedAutoStart.supported()
+}
+
+// SupportsTriggers reports whether services can be started and stopped by
+// triggers, such as a device arriving or a network address appearing.
+func SupportsTriggers() bool {
+	return capTriggers.supported()
+}
+
+// SupportsProtectedServices reports whether services can be launched as
+// protected processes, with SetProtectionLevel.
+func SupportsProtectedServices() bool {
+	return capProtected.supported()
+}
+
+// SupportsPerUserServices reports whether the service control manager
+// runs per-user services, a copy of a template service started for each
+// user who signs in.
+func SupportsPerUserServices() bool {
+	return capPerUser.supported()
+}
+
+// checkCapabilities returns an *UnsupportedError if installing or
+// reconfiguring a service with config needs a feature the running Windows
+// lacks.
+func checkCapabilities(config *serviceConfig) error {
+	var needed []capability
+	if config.DelayedAutoStart {
+		needed = append(needed, capDelayedAutoStart)
+	}
+	if config.SidType != windows.SERVICE_SID_TYPE_NONE {
+		needed = append(needed, capSIDType)
+	}
+	if config.requiredPrivileges != nil {
+		needed = append(needed, capPrivileges)
+	}
+	if config.recovery != nil && config.recovery.OnNonCrashFailures {
+		needed = append(needed, capNonCrashFailures)
+	}
+	if config.triggers != nil && len(*config.triggers) > 0 {
+		needed = append(needed, capTriggers)
+	}
+	for _, c := range needed {
+		if err := c.check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
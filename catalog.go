@@ -0,0 +1,91 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// WatchServiceCatalog streams an event every time a service is created or
+// deleted on the local machine, until ctx is done. The channel is closed
+// when the watch ends; if it ends for another reason, the last event has
+// Err set.
+func WatchServiceCatalog(ctx context.Context) (<-chan CatalogEvent, error) {
+	events := make(chan CatalogEvent)
+	ready := make(chan error, 1)
+	go watchCatalog(ctx, events, ready)
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func watchCatalog(ctx context.Context, events chan<- CatalogEvent, ready chan<- error) {
+	defer close(events)
+
+	// Notifications are delivered as APCs to the registering thread.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT|windows.SC_MANAGER_ENUMERATE_SERVICE)
+	if err != nil {
+		ready <- fmt.Errorf("failed to connect to service manager: %w", scmError(err))
+		return
+	}
+	ready <- nil
+
+	const mask = windows.SERVICE_NOTIFY_CREATED | windows.SERVICE_NOTIFY_DELETED
+	err = notifyLoop(ctx, scm, mask, func(n *windows.SERVICE_NOTIFY) uint32 {
+		now := time.Now()
+		names := takeNotifyNames(n)
+		for _, name := range names {
+			e := CatalogEvent{Time: now, Type: ServiceCreated, Name: name[1:]}
+			if name[0] == '\\' {
+				e.Type = ServiceDeleted
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return 0
+			}
+		}
+		return mask
+	})
+	if err != nil && ctx.Err() == nil {
+		select {
+		case events <- CatalogEvent{Time: time.Now(), Err: err}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// takeNotifyNames parses and frees the ServiceNames multi-string of a
+// SERVICE_NOTIFY. Each name is prefixed with '/' for a created service or
+// '\' for a deleted one.
+func takeNotifyNames(n *windows.SERVICE_NOTIFY) []string {
+	if n.ServiceNames == nil {
+		return nil
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(n.ServiceNames)))
+
+	var names []string
+	p := unsafe.Pointer(n.ServiceNames)
+	for {
+		name := windows.UTF16PtrToString((*uint16)(p))
+		if name == "" {
+			break
+		}
+		if len(name) > 1 {
+			names = append(names, name)
+		}
+		p = unsafe.Add(p, (len(utf16.Encode([]rune(name)))+1)*2)
+	}
+	return names
+}
@@ -0,0 +1,226 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var levelNames = map[EventLevel]string{
+	LevelCritical:      "win:Critical",
+	LevelError:         "win:Error",
+	LevelWarning:       "win:Warning",
+	LevelInformational: "win:Informational",
+	LevelVerbose:       "win:Verbose",
+}
+
+// channelValue is the channel number of a provider's operational channel;
+// numbers below 16 are reserved for Windows.
+const channelValue = 16
+
+func (p ChannelProvider) channel() string {
+	if p.Channel != "" {
+		return p.Channel
+	}
+	return p.Name + "/Operational"
+}
+
+var manifestTemplate = template.Must(template.New("manifest").Funcs(template.FuncMap{
+	"xml":   xmlText,
+	"level": func(l EventLevel) string { return levelNames[l] },
+	"join":  strings.Join,
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<instrumentationManifest xmlns="http://schemas.microsoft.com/win/2004/08/events" xmlns:win="http://manifests.microsoft.com/win/2004/08/windows/events" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <instrumentation>
+    <events>
+      <provider name="{{xml .P.Name}}" guid="{{.GUID}}" symbol="PROVIDER" resourceFileName="{{xml .File}}" messageFileName="{{xml .File}}">
+        <channels>
+          <channel name="{{xml .Channel}}" chid="operational" type="Operational" enabled="true" value="{{.Value}}"/>
+        </channels>
+{{- if .P.Keywords}}
+        <keywords>
+{{- range .P.Keywords}}
+          <keyword name="{{xml .Name}}" mask="0x{{printf "%x" .Mask}}"/>
+{{- end}}
+        </keywords>
+{{- end}}
+        <templates>
+          <template tid="message">
+            <data name="Message" inType="win:UnicodeString"/>
+          </template>
+        </templates>
+        <events>
+{{- range .P.Events}}
+          <event value="{{.ID}}" level="{{level .Level}}" channel="operational" template="message"{{if .Keywords}} keywords="{{xml (join .Keywords " ")}}"{{end}} message="$(string.event.{{.ID}})"/>
+{{- end}}
+        </events>
+      </provider>
+    </events>
+  </instrumentation>
+  <localization>
+    <resources culture="en-US">
+      <stringTable>
+{{- range .P.Events}}
+        <string id="event.{{.ID}}" value="%1"/>
+{{- end}}
+      </stringTable>
+    </resources>
+  </localization>
+</instrumentationManifest>
+`))
+
+// xmlText escapes s for use in XML text and attribute values.
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Manifest returns the instrumentation manifest of p, naming file as the
+// binary that holds its compiled resources. To let Event Viewer render the
+// events, compile the manifest with mc.exe and embed the result in that
+// binary, usually the service executable.
+func (p ChannelProvider) Manifest(file string) ([]byte, error) {
+	for _, e := range p.Events {
+		if _, ok := levelNames[e.Level]; !ok {
+			return nil, fmt.Errorf("event %d has invalid level %d", e.ID, e.Level)
+		}
+	}
+	var b bytes.Buffer
+	err := manifestTemplate.Execute(&b, struct {
+		P       ChannelProvider
+		GUID    string
+		Channel string
+		File    string
+		Value   int
+	}{p, p.GUID.String(), p.channel(), file, channelValue})
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// InstallChannelProvider registers p and creates its channel with wevtutil,
+// naming file as the binary that holds its compiled resources (see
+// ChannelProvider.Manifest). It requires administrator rights.
+func InstallChannelProvider(p ChannelProvider, file string) error {
+	manifest, err := p.Manifest(file)
+	if err != nil {
+		return err
+	}
+	path, err := writeTempManifest(manifest)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	out, err := exec.Command("wevtutil", "install-manifest", path, "/rf:"+file, "/mf:"+file).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to install provider %s: %w: %s", p.Name, err, out)
+	}
+	return nil
+}
+
+// UninstallChannelProvider unregisters p and deletes its channel.
+func UninstallChannelProvider(p ChannelProvider) error {
+	manifest, err := p.Manifest("")
+	if err != nil {
+		return err
+	}
+	path, err := writeTempManifest(manifest)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	out, err := exec.Command("wevtutil", "uninstall-manifest", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to uninstall provider %s: %w: %s", p.Name, err, out)
+	}
+	return nil
+}
+
+func writeTempManifest(manifest []byte) (string, error) {
+	f, err := os.CreateTemp("", "winsvc-*.man")
+	if err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(manifest); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return filepath.Clean(f.Name()), nil
+}
+
+// ChannelWriter writes the events of a ChannelProvider through ETW.
+type ChannelWriter struct {
+	handle      uint64
+	descriptors map[uint16]eventDescriptor
+}
+
+// OpenChannelProvider registers the calling process as p, so that it can
+// write p's events. p should be installed with InstallChannelProvider;
+// events written before are discarded.
+func OpenChannelProvider(p ChannelProvider) (*ChannelWriter, error) {
+	masks := make(map[string]uint64, len(p.Keywords))
+	for _, k := range p.Keywords {
+		masks[k.Name] = k.Mask
+	}
+	descriptors := make(map[uint16]eventDescriptor, len(p.Events))
+	for _, e := range p.Events {
+		d := eventDescriptor{ID: e.ID, Channel: channelValue, Level: uint8(e.Level)}
+		for _, k := range e.Keywords {
+			mask, ok := masks[k]
+			if !ok {
+				return nil, fmt.Errorf("event %d uses undefined keyword %s", e.ID, k)
+			}
+			d.Keyword |= mask
+		}
+		descriptors[e.ID] = d
+	}
+
+	h, err := eventRegister(&p.GUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register provider %s: %w", p.Name, err)
+	}
+	return &ChannelWriter{handle: h, descriptors: descriptors}, nil
+}
+
+// Write writes the event with the given ID and message to the channel.
+func (w *ChannelWriter) Write(id uint16, message string) error {
+	d, ok := w.descriptors[id]
+	if !ok {
+		return fmt.Errorf("event %d is not defined by the provider", id)
+	}
+	s, err := windows.UTF16FromString(message)
+	if err != nil {
+		return err
+	}
+	data := []eventDataDescriptor{{
+		Ptr:  uint64(uintptr(unsafe.Pointer(&s[0]))),
+		Size: uint32(2 * len(s)),
+	}}
+	err = eventWrite(w.handle, &d, data)
+	runtime.KeepAlive(s)
+	if err != nil {
+		return fmt.Errorf("failed to write event %d: %w", id, err)
+	}
+	return nil
+}
+
+// Close unregisters the provider.
+func (w *ChannelWriter) Close() error {
+	return eventUnregister(w.handle)
+}
@@ -0,0 +1,280 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lib-x/winsvc"
+)
+
+func (c *cli) list(args []string) error {
+	fs := c.flags("list")
+	states := fs.String("state", "", "comma-separated states to list, such as running,stopped")
+	startTypes := fs.String("start", "", "comma-separated start types to list, such as automatic,manual")
+	var filter winsvc.ServiceFilter
+	fs.StringVar(&filter.NamePattern, "name", "", "pattern the service name must match, such as MyProduct*")
+	fs.StringVar(&filter.BinaryPathContains, "path", "", "substring the binary path must contain")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("list takes no arguments")
+	}
+	for _, s := range splitList(*states) {
+		var state winsvc.State
+		if err := state.UnmarshalText([]byte(s)); err != nil {
+			return err
+		}
+		filter.States = append(filter.States, state)
+	}
+	for _, s := range splitList(*startTypes) {
+		var t winsvc.StartType
+		if err := t.UnmarshalText([]byte(s)); err != nil {
+			return err
+		}
+		filter.StartTypes = append(filter.StartTypes, t)
+	}
+
+	services, err := c.m.List(filter)
+	if err != nil {
+		return err
+	}
+	return c.print(services, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSTATE\tPID\tDISPLAY NAME")
+		for _, s := range services {
+			pid := ""
+			if s.Status.ProcessID != 0 {
+				pid = fmt.Sprint(s.Status.ProcessID)
+			}
+			fmt.Fprintf(tw, "%s\t%v\t%s\t%s\n", s.Name, s.Status.State, pid, s.DisplayName)
+		}
+		tw.Flush()
+	})
+}
+
+// serviceStatus is the output of status for one service.
+type serviceStatus struct {
+	Name   string               `json:"name"`
+	Config winsvc.ServiceConfig `json:"config"`
+	Status winsvc.ServiceStatus `json:"status"`
+}
+
+func (c *cli) status(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: winsvcctl status <name>...")
+	}
+	var result []serviceStatus
+	for _, name := range args {
+		config, err := c.m.Config(name)
+		if err != nil {
+			return err
+		}
+		status, err := c.m.Query(name)
+		if err != nil {
+			return err
+		}
+		result = append(result, serviceStatus{Name: name, Config: config, Status: status})
+	}
+
+	return c.print(result, func(w io.Writer) {
+		for i, s := range result {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s (%s)\n", s.Name, s.Config.DisplayName)
+			state := s.Status.State.String()
+			if s.Status.ProcessID != 0 {
+				state += fmt.Sprintf(", pid %d", s.Status.ProcessID)
+			}
+			code := winsvc.ExitCode{Win32ExitCode: s.Status.Win32ExitCode, ServiceSpecificExitCode: s.Status.ServiceSpecificExitCode}
+			if err := code.Err(); err != nil && s.Status.State == winsvc.StateStopped {
+				state += fmt.Sprintf(", last exit: %s", winsvc.DescribeError(err))
+			}
+			start := s.Config.StartType.String()
+			if s.Config.DelayedStart {
+				start += " (delayed)"
+			}
+			fmt.Fprintf(w, "  state:   %s\n", state)
+			fmt.Fprintf(w, "  start:   %s\n", start)
+			fmt.Fprintf(w, "  binary:  %s\n", s.Config.BinaryPath)
+			fmt.Fprintf(w, "  account: %s\n", s.Config.Account)
+			if len(s.Config.Dependencies) > 0 {
+				fmt.Fprintf(w, "  depends: %s\n", strings.Join(s.Config.Dependencies, ", "))
+			}
+			if s.Config.Description != "" {
+				fmt.Fprintf(w, "  %s\n", s.Config.Description)
+			}
+		}
+	})
+}
+
+func (c *cli) install(args []string) error {
+	fs := c.flags("install")
+	var s manifestService
+	displayName := fs.String("display-name", "", "display name of the service")
+	description := fs.String("description", "", "description of the service")
+	account := fs.String("account", "", `account to run as, such as NT AUTHORITY\LocalService, NT SERVICE\<name> or DOMAIN\user; LocalSystem by default`)
+	fs.StringVar(&s.Credential, "credential", "", "Credential Manager target holding the password of a user account")
+	startType := fs.String("start", "automatic", "start type: automatic, delayed, manual or disabled")
+	depends := fs.String("depends", "", "comma-separated services to start first")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	var start winsvc.StartType
+	delayed := strings.EqualFold(*startType, "delayed")
+	if delayed {
+		start = winsvc.StartTypeAutomatic
+	} else if err := start.UnmarshalText([]byte(*startType)); err != nil {
+		return err
+	}
+	s.Name = fs.Arg(0)
+	s.StartType, s.DelayedStart = &start, &delayed
+	if *displayName != "" {
+		s.DisplayName = displayName
+	}
+	if *description != "" {
+		s.Description = description
+	}
+	if *account != "" {
+		s.Account = account
+	}
+	if deps := splitList(*depends); len(deps) > 0 {
+		s.Dependencies = &deps
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+	if err := c.m.Install(ctx, fs.Arg(1), s.Name, fs.Args()[2:], s.options()...); err != nil {
+		return err
+	}
+	return c.done(s.Name, "installed")
+}
+
+func (c *cli) remove(args []string) error {
+	fs := c.flags("remove")
+	stop := fs.Bool("stop", false, "stop the service first")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	name := fs.Arg(0)
+
+	ctx, cancel := c.context()
+	defer cancel()
+	if *stop {
+		state, err := c.m.QueryState(name)
+		if err != nil {
+			return err
+		}
+		if state != winsvc.StateStopped {
+			if err := c.m.Stop(ctx, name); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.m.Remove(ctx, name); err != nil {
+		return err
+	}
+	return c.done(name, "removed")
+}
+
+func (c *cli) start(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: winsvcctl start <name> [args...]")
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	latency, err := c.m.StartWait(ctx, args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+	return c.print(map[string]any{"name": args[0], "result": "running", "latencyMs": latency.Milliseconds()}, func(w io.Writer) {
+		fmt.Fprintf(w, "%s running after %v\n", args[0], latency.Round(time.Millisecond))
+	})
+}
+
+func (c *cli) stop(args []string) error {
+	return c.control(args, "stop", "stopped", c.m.Stop)
+}
+
+func (c *cli) restart(args []string) error {
+	return c.control(args, "restart", "restarted", c.m.Restart)
+}
+
+// control runs a command that takes a service name and only controls it.
+func (c *cli) control(args []string, command, result string, op func(context.Context, string) error) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: winsvcctl %s <name>", command)
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	if err := op(ctx, args[0]); err != nil {
+		return err
+	}
+	return c.done(args[0], result)
+}
+
+func (c *cli) watch(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: winsvcctl watch <name>")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	events, err := c.m.Watch(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.out)
+	for e := range events {
+		if c.json {
+			// One event per line, so the output can be processed as it comes.
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+			continue
+		}
+		line := fmt.Sprintf("%s  %v", e.Time.Format("15:04:05.000"), e.Status.State)
+		if e.Status.ProcessID != 0 {
+			line += fmt.Sprintf("  pid %d", e.Status.ProcessID)
+		}
+		if e.Deleted {
+			line += "  deleted"
+		}
+		fmt.Fprintln(c.out, line)
+	}
+	if err := ctx.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,30 @@
+// Winsvcctl manages Windows services from the command line, like sc.exe,
+// with optional JSON output and manifests that describe services as code.
+//
+// Usage:
+//
+//	winsvcctl [-json] [-host name] [-timeout d] <command> [flags] [args]
+//
+// The commands are:
+//
+//	list      list the services, optionally filtered
+//	status    print the configuration and status of services
+//	install   install a service
+//	remove    remove a service
+//	start     start a service and wait for it to run
+//	stop      stop a service and wait for it to stop
+//	restart   stop and start a service
+//	watch     print the status of a service whenever it changes
+//	export    write a manifest describing services
+//	apply     install or reconfigure services to match a manifest
+//
+// A manifest is a JSON file with a "services" array. Each entry holds the
+// fields of winsvc.ServiceConfig, as printed by "winsvcctl -json status",
+// plus optional "recovery" actions, "labels" and, for services running as a
+// user, the Windows Credential Manager "credential" target holding its
+// password. Running export on one machine and apply on another reproduces
+// the services there; apply leaves services that already match alone, and
+// the settings an entry leaves out as they are.
+//
+// Winsvcctl only runs on Windows.
+package main
@@ -0,0 +1,139 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lib-x/winsvc"
+)
+
+// command is a winsvcctl command.
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(c *cli, args []string) error
+}
+
+// commands is set in init, as the usage of each command refers back to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"list", "[-state s] [-start t] [-name pattern] [-path substr]", "list the services, optionally filtered", (*cli).list},
+		{"status", "<name>...", "print the configuration and status of services", (*cli).status},
+		{"install", "[flags] <name> <exe> [args...]", "install a service", (*cli).install},
+		{"remove", "[-stop] <name>", "remove a service", (*cli).remove},
+		{"start", "<name> [args...]", "start a service and wait for it to run", (*cli).start},
+		{"stop", "<name>", "stop a service and wait for it to stop", (*cli).stop},
+		{"restart", "<name>", "stop and start a service", (*cli).restart},
+		{"watch", "<name>", "print the status of a service whenever it changes", (*cli).watch},
+		{"export", "[-label l] [-o file] [name...]", "write a manifest describing services", (*cli).export},
+		{"apply", "[-dry-run] <file>", "install or reconfigure services to match a manifest", (*cli).apply},
+	}
+}
+
+// cli holds the global flags and the connection the commands use.
+type cli struct {
+	m       *winsvc.Manager
+	host    string
+	json    bool
+	timeout time.Duration
+	out     io.Writer
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "winsvcctl: %s\n", winsvc.DescribeError(err))
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	c := &cli{out: out}
+	fs := flag.NewFlagSet("winsvcctl", flag.ContinueOnError)
+	fs.BoolVar(&c.json, "json", false, "write JSON instead of text")
+	fs.StringVar(&c.host, "host", "", "manage the services of another machine")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second, "how long to wait for a service to change state")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		usage(fs)
+		return flag.ErrHelp
+	}
+
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		m, err := winsvc.ConnectRemote(c.host)
+		if err != nil {
+			return err
+		}
+		defer m.Disconnect()
+		c.m = m
+		return cmd.run(c, fs.Args()[1:])
+	}
+	usage(fs)
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintf(w, "usage: winsvcctl [flags] <command> [flags] [args]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(w, "\nflags:\n")
+	fs.PrintDefaults()
+}
+
+// flags returns the flag set of the named command.
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("winsvcctl "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		for _, cmd := range commands {
+			if cmd.name == name {
+				fmt.Fprintf(fs.Output(), "usage: winsvcctl %s %s\n", name, cmd.args)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// context returns a context bounded by the -timeout flag.
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// print writes v as indented JSON in JSON mode, and calls text otherwise.
+func (c *cli) print(v any, text func(w io.Writer)) error {
+	if !c.json {
+		text(c.out)
+		return nil
+	}
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// done reports that an operation on the named service succeeded.
+func (c *cli) done(name, what string) error {
+	return c.print(map[string]string{"name": name, "result": what}, func(w io.Writer) {
+		fmt.Fprintf(w, "%s %s\n", name, what)
+	})
+}
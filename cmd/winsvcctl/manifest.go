@@ -0,0 +1,479 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lib-x/winsvc"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// manifest is the document export writes and apply reads.
+type manifest struct {
+	Services []manifestService `json:"services"`
+}
+
+// manifestService describes one service of a manifest. The fields of
+// winsvc.ServiceConfig are pointers so that apply only changes the
+// settings a manifest has.
+type manifestService struct {
+	Name           string                   `json:"name"`
+	DisplayName    *string                  `json:"displayName,omitempty"`
+	Description    *string                  `json:"description,omitempty"`
+	BinaryPath     *string                  `json:"binaryPath,omitempty"`
+	ServiceType    *uint32                  `json:"serviceType,omitempty"`
+	StartType      *winsvc.StartType        `json:"startType,omitempty"`
+	DelayedStart   *bool                    `json:"delayedStart,omitempty"`
+	ErrorControl   *uint32                  `json:"errorControl,omitempty"`
+	Account        *string                  `json:"account,omitempty"`
+	Dependencies   *[]string                `json:"dependencies,omitempty"`
+	LoadOrderGroup *string                  `json:"loadOrderGroup,omitempty"`
+	SidType        *uint32                  `json:"sidType,omitempty"`
+	Triggers       *[]winsvc.ServiceTrigger `json:"triggers,omitempty"`
+	// Credential is the Credential Manager target holding the password of
+	// a user account, used when the service is installed or its account
+	// changed.
+	Credential string                 `json:"credential,omitempty"`
+	Recovery   *winsvc.RecoveryConfig `json:"recovery,omitempty"`
+	Labels     []string               `json:"labels,omitempty"`
+}
+
+func (c *cli) export(args []string) error {
+	fs := c.flags("export")
+	label := fs.String("label", "", "export the services with this label")
+	output := fs.String("o", "", "write the manifest to this file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	names := fs.Args()
+	if *label != "" {
+		labeled, err := c.m.ServicesWithLabel(*label)
+		if err != nil {
+			return err
+		}
+		names = append(names, labeled...)
+	}
+	if len(names) == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	var doc manifest
+	for _, name := range names {
+		s, err := c.describe(name)
+		if err != nil {
+			return err
+		}
+		doc.Services = append(doc.Services, s)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, err = c.out.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
+
+// describe returns the manifest entry of an installed service, with every
+// setting present.
+func (c *cli) describe(name string) (manifestService, error) {
+	config, err := c.m.Config(name)
+	if err != nil {
+		return manifestService{}, err
+	}
+	s := manifestService{
+		Name:           name,
+		DisplayName:    &config.DisplayName,
+		Description:    &config.Description,
+		BinaryPath:     &config.BinaryPath,
+		ServiceType:    &config.ServiceType,
+		StartType:      &config.StartType,
+		DelayedStart:   &config.DelayedStart,
+		ErrorControl:   &config.ErrorControl,
+		Account:        &config.Account,
+		Dependencies:   nonNil(config.Dependencies),
+		LoadOrderGroup: &config.LoadOrderGroup,
+		SidType:        &config.SidType,
+		Triggers:       nonNil(config.Triggers),
+	}
+	recovery, err := c.m.RecoveryConfig(name)
+	if err != nil {
+		return manifestService{}, err
+	}
+	if len(recovery.Actions) > 0 || recovery.Command != "" || recovery.RebootMessage != "" || recovery.OnNonCrashFailures {
+		s.Recovery = &recovery
+	}
+	if s.Labels, err = c.m.Labels(name); err != nil {
+		return manifestService{}, err
+	}
+	return s, nil
+}
+
+// nonNil returns a pointer to list, or to an empty list if it is nil, so
+// that the manifest records that there are none.
+func nonNil[T any](list []T) *[]T {
+	if list == nil {
+		list = []T{}
+	}
+	return &list
+}
+
+func (c *cli) apply(args []string) error {
+	fs := c.flags("apply")
+	dryRun := fs.Bool("dry-run", false, "print what would change without changing anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var doc manifest
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid manifest %s: %w", fs.Arg(0), err)
+	}
+
+	type result struct {
+		Name    string   `json:"name"`
+		Result  string   `json:"result"`
+		Changes []string `json:"changes,omitempty"`
+	}
+	var results []result
+	for _, s := range doc.Services {
+		if s.Name == "" {
+			return fmt.Errorf("manifest service without a name")
+		}
+		r := result{Name: s.Name}
+		exists, err := c.m.Exists(s.Name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			r.Result = "installed"
+			if !*dryRun {
+				err = c.installManifest(s)
+			}
+		} else {
+			r.Changes, err = c.reconcile(s, *dryRun)
+			r.Result = "unchanged"
+			if len(r.Changes) > 0 {
+				r.Result = "updated"
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if *dryRun && r.Result != "unchanged" {
+			r.Result = "would be " + r.Result
+		}
+		results = append(results, r)
+	}
+
+	return c.print(results, func(w io.Writer) {
+		for _, r := range results {
+			fmt.Fprintf(w, "%s %s\n", r.Name, r.Result)
+			for _, change := range r.Changes {
+				fmt.Fprintf(w, "  %s\n", change)
+			}
+		}
+	})
+}
+
+// installManifest installs a service of a manifest that does not exist yet.
+func (c *cli) installManifest(s manifestService) error {
+	if s.BinaryPath == nil || *s.BinaryPath == "" {
+		return fmt.Errorf("service %s has no binary path", s.Name)
+	}
+	argv, err := windows.DecomposeCommandLine(*s.BinaryPath)
+	if err != nil || len(argv) == 0 {
+		return fmt.Errorf("invalid binary path of service %s: %q", s.Name, *s.BinaryPath)
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	if err := c.m.Install(ctx, argv[0], s.Name, argv[1:], s.options()...); err != nil {
+		return err
+	}
+	if len(s.Labels) > 0 {
+		return c.m.SetLabels(s.Name, s.Labels...)
+	}
+	return nil
+}
+
+// options returns the options setting the settings s has, for installing
+// or reconfiguring the service. The binary path of an installed service is
+// set too; Install takes it as an argument instead.
+func (s *manifestService) options() []winsvc.ServiceOption {
+	var options []winsvc.ServiceOption
+	config := func(f func(*mgr.Config)) {
+		options = append(options, winsvc.MgrConfig(f))
+	}
+	if s.DisplayName != nil {
+		options = append(options, winsvc.DisplayName(*s.DisplayName))
+	}
+	if s.Description != nil {
+		options = append(options, winsvc.Description(*s.Description))
+	}
+	if s.BinaryPath != nil {
+		config(func(c *mgr.Config) { c.BinaryPathName = *s.BinaryPath })
+	}
+	if s.ServiceType != nil {
+		config(func(c *mgr.Config) { c.ServiceType = *s.ServiceType })
+	}
+	if s.StartType != nil {
+		config(func(c *mgr.Config) { c.StartType = uint32(*s.StartType) })
+	}
+	if s.DelayedStart != nil {
+		config(func(c *mgr.Config) { c.DelayedAutoStart = *s.DelayedStart })
+	}
+	if s.ErrorControl != nil {
+		config(func(c *mgr.Config) { c.ErrorControl = *s.ErrorControl })
+	}
+	if s.Dependencies != nil {
+		// Dependencies adds to the current dependencies; the manifest
+		// replaces them.
+		config(func(c *mgr.Config) { c.Dependencies = *s.Dependencies })
+	}
+	if s.LoadOrderGroup != nil {
+		config(func(c *mgr.Config) { c.LoadOrderGroup = *s.LoadOrderGroup })
+	}
+	if s.SidType != nil {
+		config(func(c *mgr.Config) { c.SidType = *s.SidType })
+	}
+	if s.Account != nil {
+		options = append(options, s.accountOption())
+	}
+	if s.Triggers != nil {
+		options = append(options, winsvc.Triggers(*s.Triggers...))
+	}
+	if s.Recovery != nil {
+		options = append(options, winsvc.Recovery(*s.Recovery))
+	}
+	return options
+}
+
+// accountOption returns the option running the service as the account of
+// s, LocalSystem if it is empty.
+func (s *manifestService) accountOption() winsvc.ServiceOption {
+	account := *s.Account
+	if account == "" {
+		account = "LocalSystem"
+	}
+	switch {
+	case strings.EqualFold(account, `NT SERVICE\`+s.Name):
+		return winsvc.VirtualAccount()
+	case passwordless(account):
+		return winsvc.MgrConfig(func(c *mgr.Config) { c.ServiceStartName = account })
+	case s.Credential != "":
+		return winsvc.RunAsUserFromCredential(account, s.Credential)
+	default:
+		return winsvc.RunAsUser(account, nil)
+	}
+}
+
+// reconcile updates an installed service to match s, returning the
+// changes. Settings s does not have are left alone. With dryRun, the
+// changes are only computed.
+func (c *cli) reconcile(s manifestService, dryRun bool) ([]string, error) {
+	have, err := c.describe(s.Name)
+	if err != nil {
+		return nil, err
+	}
+	var changes []string
+	change := func(field string, from, to any) {
+		changes = append(changes, fmt.Sprintf("%s: %v -> %v", field, from, to))
+	}
+	// update holds the settings that differ from s.
+	update := manifestService{Name: s.Name, Credential: s.Credential}
+	if changed(s.DisplayName, have.DisplayName) {
+		change("displayName", *have.DisplayName, *s.DisplayName)
+		update.DisplayName = s.DisplayName
+	}
+	if changed(s.Description, have.Description) {
+		change("description", *have.Description, *s.Description)
+		update.Description = s.Description
+	}
+	if s.BinaryPath != nil && !strings.EqualFold(*s.BinaryPath, *have.BinaryPath) {
+		change("binaryPath", *have.BinaryPath, *s.BinaryPath)
+		update.BinaryPath = s.BinaryPath
+	}
+	if changed(s.ServiceType, have.ServiceType) {
+		change("serviceType", *have.ServiceType, *s.ServiceType)
+		update.ServiceType = s.ServiceType
+	}
+	if changed(s.StartType, have.StartType) {
+		change("startType", *have.StartType, *s.StartType)
+		update.StartType = s.StartType
+	}
+	if changed(s.DelayedStart, have.DelayedStart) {
+		change("delayedStart", *have.DelayedStart, *s.DelayedStart)
+		update.DelayedStart = s.DelayedStart
+	}
+	if changed(s.ErrorControl, have.ErrorControl) {
+		change("errorControl", *have.ErrorControl, *s.ErrorControl)
+		update.ErrorControl = s.ErrorControl
+	}
+	if s.Dependencies != nil && !sameNames(*s.Dependencies, *have.Dependencies) {
+		change("dependencies", *have.Dependencies, *s.Dependencies)
+		update.Dependencies = s.Dependencies
+	}
+	if s.LoadOrderGroup != nil && !strings.EqualFold(*s.LoadOrderGroup, *have.LoadOrderGroup) {
+		change("loadOrderGroup", *have.LoadOrderGroup, *s.LoadOrderGroup)
+		update.LoadOrderGroup = s.LoadOrderGroup
+	}
+	if changed(s.SidType, have.SidType) {
+		change("sidType", *have.SidType, *s.SidType)
+		update.SidType = s.SidType
+	}
+	if s.Account != nil {
+		account := *s.Account
+		if account == "" {
+			account = "LocalSystem"
+		}
+		if !strings.EqualFold(account, *have.Account) {
+			if !passwordless(account) && s.Credential == "" {
+				return nil, fmt.Errorf("changing the account of service %s to %s needs its password; add the credential holding it to the manifest", s.Name, account)
+			}
+			change("account", *have.Account, account)
+			update.Account = &account
+		}
+	}
+	if s.Triggers != nil && !sameTriggers(*s.Triggers, *have.Triggers) {
+		change("triggers", describeTriggers(*have.Triggers), describeTriggers(*s.Triggers))
+		update.Triggers = s.Triggers
+	}
+	if s.Recovery != nil {
+		var recovery winsvc.RecoveryConfig
+		if have.Recovery != nil {
+			recovery = *have.Recovery
+		}
+		if recoveryChanges := diffRecovery(recovery, *s.Recovery); len(recoveryChanges) > 0 {
+			changes = append(changes, recoveryChanges...)
+			update.Recovery = s.Recovery
+		}
+	}
+	if len(changes) > 0 && !dryRun {
+		if err := c.m.Reconfigure(s.Name, update.options()...); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Labels != nil && !sameNames(have.Labels, s.Labels) {
+		change("labels", have.Labels, s.Labels)
+		if !dryRun {
+			if err := c.m.SetLabels(s.Name, s.Labels...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// changed reports whether a manifest has the setting want and it differs
+// from have.
+func changed[T comparable](want, have *T) bool {
+	return want != nil && *want != *have
+}
+
+// diffRecovery returns the changes turning the failure actions have into
+// want.
+func diffRecovery(have, want winsvc.RecoveryConfig) []string {
+	var changes []string
+	if !slices.Equal(have.Actions, want.Actions) || have.ResetPeriod != want.ResetPeriod {
+		changes = append(changes, fmt.Sprintf("recovery: %s -> %s", describeActions(have), describeActions(want)))
+	}
+	if have.RebootMessage != want.RebootMessage {
+		changes = append(changes, fmt.Sprintf("rebootMessage: %q -> %q", have.RebootMessage, want.RebootMessage))
+	}
+	if have.Command != want.Command {
+		changes = append(changes, fmt.Sprintf("recoveryCommand: %q -> %q", have.Command, want.Command))
+	}
+	if have.OnNonCrashFailures != want.OnNonCrashFailures {
+		changes = append(changes, fmt.Sprintf("onNonCrashFailures: %v -> %v", have.OnNonCrashFailures, want.OnNonCrashFailures))
+	}
+	return changes
+}
+
+// sameTriggers reports whether a and b hold the same triggers in the same
+// order. Subtypes are compared case-insensitively, with or without braces.
+func sameTriggers(a, b []winsvc.ServiceTrigger) bool {
+	return slices.EqualFunc(a, b, func(x, y winsvc.ServiceTrigger) bool {
+		return x.Type == y.Type && x.Action == y.Action &&
+			strings.EqualFold(strings.Trim(x.Subtype, "{}"), strings.Trim(y.Subtype, "{}")) &&
+			slices.EqualFunc(x.Data, y.Data, func(d, e winsvc.TriggerData) bool {
+				return d.Type == e.Type && bytes.Equal(d.Data, e.Data)
+			})
+	})
+}
+
+// describeTriggers formats triggers for the changes apply reports.
+func describeTriggers(triggers []winsvc.ServiceTrigger) string {
+	if len(triggers) == 0 {
+		return "none"
+	}
+	var list []string
+	for _, t := range triggers {
+		action := "start"
+		if t.Action == 2 { // SERVICE_TRIGGER_ACTION_SERVICE_STOP
+			action = "stop"
+		}
+		list = append(list, fmt.Sprintf("%s on type %d %s", action, t.Type, t.Subtype))
+	}
+	return strings.Join(list, ", ")
+}
+
+// describeActions formats failure actions for the changes apply reports.
+func describeActions(c winsvc.RecoveryConfig) string {
+	if len(c.Actions) == 0 {
+		return "none"
+	}
+	var actions []string
+	for _, a := range c.Actions {
+		actions = append(actions, fmt.Sprintf("%v after %v", a.Type, a.Delay))
+	}
+	return fmt.Sprintf("%s, reset after %v", strings.Join(actions, ", "), c.ResetPeriod)
+}
+
+// passwordless reports whether account runs a service without a password:
+// LocalSystem, the NT AUTHORITY accounts and virtual accounts.
+func passwordless(account string) bool {
+	upper := strings.ToUpper(account)
+	return upper == "LOCALSYSTEM" || strings.HasPrefix(upper, `NT AUTHORITY\`) || strings.HasPrefix(upper, `NT SERVICE\`)
+}
+
+// sameNames reports whether a and b hold the same names in any order,
+// compared case-insensitively like service names.
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = lowerSorted(a), lowerSorted(b)
+	return slices.Equal(a, b)
+}
+
+func lowerSorted(names []string) []string {
+	lower := make([]string, len(names))
+	for i, name := range names {
+		lower[i] = strings.ToLower(name)
+	}
+	sort.Strings(lower)
+	return lower
+}
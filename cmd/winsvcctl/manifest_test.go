@@ -0,0 +1,59 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lib-x/winsvc"
+)
+
+func TestManifestServiceOmitted(t *testing.T) {
+	var s manifestService
+	if err := json.Unmarshal([]byte(`{"name":"svc","description":"","dependencies":[]}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Description == nil || *s.Description != "" {
+		t.Errorf("Description = %v, want empty", s.Description)
+	}
+	if s.Dependencies == nil || len(*s.Dependencies) != 0 {
+		t.Errorf("Dependencies = %v, want empty", s.Dependencies)
+	}
+	if s.DisplayName != nil || s.StartType != nil || s.Account != nil || s.Triggers != nil {
+		t.Errorf("omitted settings are present: %+v", s)
+	}
+	if n := len(s.options()); n != 2 {
+		t.Errorf("len(options()) = %d, want 2", n)
+	}
+}
+
+func TestSameTriggers(t *testing.T) {
+	trigger := winsvc.ServiceTrigger{
+		Type:    1,
+		Action:  1,
+		Subtype: "{4D36E967-E325-11CE-BFC1-08002BE10318}",
+		Data:    []winsvc.TriggerData{{Type: 2, Data: []byte("USB\x00")}},
+	}
+	same := trigger
+	same.Subtype = "4d36e967-e325-11ce-bfc1-08002be10318"
+	stop := trigger
+	stop.Action = 2
+	other := trigger
+	other.Data = []winsvc.TriggerData{{Type: 2, Data: []byte("PCI\x00")}}
+
+	for _, tt := range []struct {
+		a, b []winsvc.ServiceTrigger
+		want bool
+	}{
+		{nil, []winsvc.ServiceTrigger{}, true},
+		{[]winsvc.ServiceTrigger{trigger}, []winsvc.ServiceTrigger{same}, true},
+		{[]winsvc.ServiceTrigger{trigger}, []winsvc.ServiceTrigger{stop}, false},
+		{[]winsvc.ServiceTrigger{trigger}, []winsvc.ServiceTrigger{other}, false},
+		{[]winsvc.ServiceTrigger{trigger}, nil, false},
+	} {
+		if got := sameTriggers(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameTriggers(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
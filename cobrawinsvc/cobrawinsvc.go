@@ -0,0 +1,96 @@
+// Package cobrawinsvc adds the service management commands of winsvc.Manage
+// to a cobra command tree, so programs with an established cobra command
+// line manage their service like the others:
+//
+//	root.AddCommand(cobrawinsvc.Command("MyService", svc,
+//		winsvc.ManageServiceOptions(winsvc.Hardened())))
+//
+// The program then has "service install", "service uninstall", "service
+// start", "service stop", "service restart", "service status" and "service
+// run" commands. The install command takes the install flags of Manage,
+// and the installed service runs the program with "service run" followed
+// by the arguments given to install after "--".
+package cobrawinsvc
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/lib-x/winsvc"
+	"github.com/spf13/cobra"
+)
+
+// Command returns a "service" command whose subcommands manage the named
+// service, which runs s, with winsvc.Manage and options.
+func Command(name string, s winsvc.Service, options ...winsvc.ManageOption) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Manage the " + name + " service",
+		Args:  cobra.NoArgs,
+	}
+
+	manage := func(command string) func(*cobra.Command, []string) error {
+		return func(_ *cobra.Command, args []string) error {
+			return winsvc.Manage(name, s, append([]string{command}, args...), options...)
+		}
+	}
+
+	var flags winsvc.InstallFlags
+	install := &cobra.Command{
+		Use:   "install [flags] [-- args]",
+		Short: "Install the program as the " + name + " service, which runs with args",
+		RunE: func(c *cobra.Command, args []string) error {
+			// The service runs the program's service run command, which is
+			// the path of this command without the program name.
+			runArgs := strings.Fields(c.Parent().CommandPath())[1:]
+			runArgs = append(append(runArgs, "run"), args...)
+			return flags.Install(name, runArgs, options...)
+		},
+	}
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	flags.Register(fs, "")
+	install.Flags().AddGoFlagSet(fs)
+
+	cmd.AddCommand(
+		install,
+		&cobra.Command{
+			Use:     "uninstall",
+			Aliases: []string{"remove"},
+			Short:   "Stop and remove the " + name + " service",
+			Args:    cobra.NoArgs,
+			RunE:    manage("uninstall"),
+		},
+		&cobra.Command{
+			Use:   "start [args]",
+			Short: "Start the " + name + " service and wait for it to run",
+			// The arguments are the service's, not flags of the command.
+			DisableFlagParsing: true,
+			RunE:               manage("start"),
+		},
+		&cobra.Command{
+			Use:   "stop",
+			Short: "Stop the " + name + " service",
+			Args:  cobra.NoArgs,
+			RunE:  manage("stop"),
+		},
+		&cobra.Command{
+			Use:   "restart",
+			Short: "Restart the " + name + " service",
+			Args:  cobra.NoArgs,
+			RunE:  manage("restart"),
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Print the state of the " + name + " service",
+			Args:  cobra.NoArgs,
+			RunE:  manage("status"),
+		},
+		&cobra.Command{
+			Use:                "run",
+			Short:              "Run the " + name + " service, in debug mode on a console",
+			DisableFlagParsing: true,
+			RunE:               manage("run"),
+		},
+	)
+	return cmd
+}
@@ -0,0 +1,14 @@
+module github.com/lib-x/winsvc/cobrawinsvc
+
+go 1.22
+
+require (
+	github.com/lib-x/winsvc v0.0.0-20261014070136-7ca79a31f462
+	github.com/spf13/cobra v1.8.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+)
@@ -0,0 +1,176 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// GetServiceConfig returns the configuration of a Windows service.
+func GetServiceConfig(name string) (ServiceConfig, error) {
+	return GetServiceConfigCtx(context.Background(), name)
+}
+
+// GetServiceConfigCtx is like GetServiceConfig but honors ctx cancellation.
+func GetServiceConfigCtx(ctx context.Context, name string) (ServiceConfig, error) {
+	var config ServiceConfig
+	err := withManager(ctx, func(m *Manager) error {
+		var err error
+		config, err = m.Config(name)
+		return err
+	})
+	return config, err
+}
+
+// Config returns the configuration of the named service, like
+// GetServiceConfig.
+func (m *Manager) Config(name string) (ServiceConfig, error) {
+	s, err := m.openService(name, windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return ServiceConfig{}, err
+	}
+	defer s.Close()
+
+	c, err := s.Config()
+	if err != nil {
+		return ServiceConfig{}, fmt.Errorf("could not query service config: %w", err)
+	}
+	triggers, err := triggers(s, m.host == "")
+	if err != nil {
+		return ServiceConfig{}, err
+	}
+
+	return ServiceConfig{
+		Name:           name,
+		DisplayName:    c.DisplayName,
+		Description:    c.Description,
+		BinaryPath:     c.BinaryPathName,
+		ServiceType:    c.ServiceType,
+		StartType:      StartType(c.StartType),
+		DelayedStart:   c.DelayedAutoStart,
+		ErrorControl:   c.ErrorControl,
+		Account:        c.ServiceStartName,
+		Dependencies:   c.Dependencies,
+		LoadOrderGroup: c.LoadOrderGroup,
+		SidType:        c.SidType,
+		Triggers:       triggers,
+	}, nil
+}
+
+// ReconfigureService changes the configuration of an installed Windows
+// service with the options InstallServiceWithOption takes. Settings no
+// option touches keep their current values, Dependencies adds to the
+// current dependencies, and Recovery and Triggers replace the failure
+// actions and triggers. WithFirewallRule, WithPerfCounters and
+// RequireSignature only apply to installing a service and are rejected.
+func ReconfigureService(name string, options ...ServiceOption) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.Reconfigure(name, options...)
+	})
+}
+
+// Reconfigure changes the configuration of the named service, like
+// ReconfigureService.
+func (m *Manager) Reconfigure(name string, options ...ServiceOption) (err error) {
+	var config serviceConfig
+	defer func() {
+		m.audit("reconfigure", name, map[string]string{
+			"startType": strconv.FormatUint(uint64(config.StartType), 10),
+			"account":   config.ServiceStartName,
+		}, err)
+	}()
+
+	s, err := m.openService(name, windows.SERVICE_QUERY_CONFIG|windows.SERVICE_CHANGE_CONFIG)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	current, err := s.Config()
+	if err != nil {
+		return fmt.Errorf("could not query service config: %w", err)
+	}
+	config.mgrConfig = mgrConfig(current)
+	for _, option := range options {
+		option(&config)
+	}
+	if len(config.firewallRules) > 0 || len(config.counterSets) > 0 || config.verifySignature {
+		return fmt.Errorf("firewall rules, performance counters and signature checks only apply to installing a service")
+	}
+	if m.host == "" {
+		if err := checkCapabilities(&config); err != nil {
+			return err
+		}
+	}
+	if config.virtualAccount {
+		config.ServiceStartName = virtualAccountName(name)
+	}
+
+	// UpdateConfig leaves empty settings unchanged, so the account, which
+	// needs a password argument, and the settings options emptied are
+	// changed separately.
+	update := mgr.Config(config.mgrConfig)
+	update.ServiceStartName = ""
+	update.Password = ""
+	if err := s.UpdateConfig(update); err != nil {
+		return fmt.Errorf("failed to update service config: %w", scmError(err))
+	}
+	var group, deps, account, password *uint16
+	if config.LoadOrderGroup == "" && current.LoadOrderGroup != "" {
+		group = windows.StringToUTF16Ptr("")
+	}
+	if len(config.Dependencies) == 0 && len(current.Dependencies) > 0 {
+		deps = &[]uint16{0, 0}[0]
+	}
+	if config.ServiceStartName != "" && !strings.EqualFold(config.ServiceStartName, current.ServiceStartName) {
+		if account, err = windows.UTF16PtrFromString(config.ServiceStartName); err != nil {
+			return err
+		}
+		// Accounts without a password take an empty one; the password of
+		// a user account is set below.
+		password = windows.StringToUTF16Ptr("")
+	}
+	if group != nil || deps != nil || account != nil {
+		err = windows.ChangeServiceConfig(s.Handle, windows.SERVICE_NO_CHANGE, windows.SERVICE_NO_CHANGE,
+			windows.SERVICE_NO_CHANGE, nil, group, nil, deps, account, password, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update service config: %w", scmError(err))
+		}
+	}
+	if config.Description == "" && current.Description != "" {
+		info := windows.SERVICE_DESCRIPTION{Description: windows.StringToUTF16Ptr("")}
+		err = windows.ChangeServiceConfig2(s.Handle, windows.SERVICE_CONFIG_DESCRIPTION, (*byte)(unsafe.Pointer(&info)))
+		if err != nil {
+			return fmt.Errorf("failed to clear service description: %w", scmError(err))
+		}
+	}
+
+	if config.password != nil {
+		if err := setServicePassword(s.Handle, config.password); err != nil {
+			return err
+		}
+	}
+	if config.requiredPrivileges != nil {
+		if err := setRequiredPrivileges(s, config.requiredPrivileges); err != nil {
+			return err
+		}
+	}
+	if config.recovery != nil {
+		if err := setRecovery(s, *config.recovery); err != nil {
+			return err
+		}
+	}
+	if config.triggers != nil {
+		if err := setTriggers(s, *config.triggers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"sync"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// InContainer reports whether the process runs in a Windows container,
+// isolated by process or by Hyper-V, which Windows marks with the
+// ContainerType value under HKLM\SYSTEM\CurrentControlSet\Control.
+func InContainer() bool {
+	return inContainer()
+}
+
+var inContainer = sync.OnceValue(func() bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	_, _, err = k.GetIntegerValue("ContainerType")
+	return err == nil
+})
+
+// IsNanoServer reports whether the system is Nano Server, which lacks
+// much of what full Windows offers services, such as EventCreate.exe, the
+// message file of the event sources Install registers.
+func IsNanoServer() bool {
+	return isNanoServer()
+}
+
+var isNanoServer = sync.OnceValue(func() bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion\Server\ServerLevels`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	nano, _, err := k.GetIntegerValue("NanoServer")
+	return err == nil && nano == 1
+})
+
+// DegradedMode reports whether the package works around a reduced
+// system, because InContainer or IsNanoServer. RunAsService then logs to
+// a file in ServiceLogDir instead of the event log, which no one reads in
+// a container; Install registers no event source, which RunAsService does
+// not use; and, in a container, whose networking the host configures,
+// Install skips firewall rules.
+func DegradedMode() bool {
+	return InContainer() || IsNanoServer()
+}
@@ -0,0 +1,198 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// debugPipeSDDL lets only SYSTEM, administrators and the pipe's owner, the
+// account the service runs as, read the debug pipe. Without the owner, a
+// service running as a non-administrative account could not create further
+// instances of its own pipe.
+const debugPipeSDDL = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GA;;;OW)"
+
+// DebugPipePath returns the path of the named pipe on which a service run
+// with the DebugPipe option serves its expvar variables.
+func DebugPipePath(name string) string {
+	return `\\.\pipe\winsvc.` + name + `.debug`
+}
+
+// DebugPipe makes RunAsService serve the process's expvar variables, the
+// ones the expvar package publishes itself and any the service registers
+// with expvar.Publish or PublishGauge, on the pipe DebugPipePath returns.
+// Each connection receives a JSON object in the format of expvar's HTTP
+// handler, so production services can be inspected with DumpDebugVars
+// without opening a network port. Only SYSTEM, administrators and the
+// account the service runs as can connect.
+func DebugPipe() RunOption {
+	return func(c *runConfig) {
+		c.debugPipe = true
+	}
+}
+
+// PublishGauge publishes a variable whose value is the result of calling
+// fn whenever the variables are read, for example through the debug pipe.
+// Like expvar.Publish, it panics if name is already in use.
+func PublishGauge(name string, fn func() float64) {
+	expvar.Publish(name, expvar.Func(func() any { return fn() }))
+}
+
+// DumpDebugVars connects to the debug pipe of the named service, which
+// must run on the local machine with the DebugPipe option, and returns its
+// variables by name.
+func DumpDebugVars(name string) (map[string]json.RawMessage, error) {
+	var f *os.File
+	var err error
+	for i := 0; ; i++ {
+		f, err = os.OpenFile(DebugPipePath(name), os.O_RDONLY, 0)
+		if !errors.Is(err, windows.ERROR_PIPE_BUSY) || i == 50 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			return nil, newError(ErrServiceNotActive, "service %s does not serve a debug pipe", name)
+		}
+		return nil, fmt.Errorf("could not connect to debug pipe: %w", scmError(err))
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil && !errors.Is(err, windows.ERROR_BROKEN_PIPE) {
+		return nil, fmt.Errorf("could not read debug pipe: %w", err)
+	}
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("invalid debug variables: %w", err)
+	}
+	return vars, nil
+}
+
+// debugPipeServer serves expvar variables on a named pipe, one client at
+// a time.
+type debugPipeServer struct {
+	path *uint16
+	sa   *windows.SecurityAttributes
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// serveDebugPipe starts serving the debug pipe of the named service. It
+// fails if another process already serves it.
+func serveDebugPipe(name string) (*debugPipeServer, error) {
+	path, err := windows.UTF16PtrFromString(DebugPipePath(name))
+	if err != nil {
+		return nil, err
+	}
+	sd, err := windows.SecurityDescriptorFromString(debugPipeSDDL)
+	if err != nil {
+		return nil, err
+	}
+	s := &debugPipeServer{
+		path: path,
+		sa:   &windows.SecurityAttributes{Length: uint32(unsafe.Sizeof(windows.SecurityAttributes{})), SecurityDescriptor: sd},
+		done: make(chan struct{}),
+	}
+
+	h, err := s.create(windows.FILE_FLAG_FIRST_PIPE_INSTANCE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create debug pipe: %w", err)
+	}
+	go s.serve(h)
+	return s, nil
+}
+
+func (s *debugPipeServer) create(flags uint32) (windows.Handle, error) {
+	return windows.CreateNamedPipe(s.path, windows.PIPE_ACCESS_OUTBOUND|flags,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_REJECT_REMOTE_CLIENTS, windows.PIPE_UNLIMITED_INSTANCES,
+		64*1024, 0, 0, s.sa)
+}
+
+func (s *debugPipeServer) serve(h windows.Handle) {
+	defer close(s.done)
+	for {
+		err := windows.ConnectNamedPipe(h, nil)
+		if s.isClosed() {
+			windows.CloseHandle(h)
+			return
+		}
+		// Create the next instance before answering, so that clients
+		// arriving meanwhile find the pipe busy rather than missing.
+		next, nextErr := s.create(0)
+		if err == nil || errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+			writeAll(h, debugVarsJSON())
+			windows.FlushFileBuffers(h)
+			windows.DisconnectNamedPipe(h)
+		}
+		windows.CloseHandle(h)
+		if nextErr != nil {
+			return
+		}
+		h = next
+	}
+}
+
+func (s *debugPipeServer) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Close stops serving the pipe and waits for the current client to be
+// answered.
+func (s *debugPipeServer) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	// ConnectNamedPipe blocks until a client arrives, so connect to wake
+	// it up, again if the pipe was busy with another client.
+	for {
+		if h, err := windows.CreateFile(s.path, windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, 0, 0); err == nil {
+			windows.CloseHandle(h)
+		}
+		select {
+		case <-s.done:
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+// debugVarsJSON encodes all expvar variables the way expvar's HTTP handler
+// does.
+func debugVarsJSON() []byte {
+	vars := make(map[string]json.RawMessage)
+	expvar.Do(func(kv expvar.KeyValue) {
+		vars[kv.Key] = json.RawMessage(kv.Value.String())
+	})
+	data, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return []byte("{}")
+	}
+	return data
+}
+
+func writeAll(h windows.Handle, data []byte) {
+	for len(data) > 0 {
+		var n uint32
+		if err := windows.WriteFile(h, data, &n, nil); err != nil {
+			return
+		}
+		data = data[n:]
+	}
+}
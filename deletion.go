@@ -0,0 +1,130 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// handleHolders are executables that commonly keep service handles open.
+var handleHolders = []string{"mmc.exe", "taskmgr.exe", "procexp.exe", "procexp64.exe"}
+
+// CheckMarkedForDeletion returns a *MarkedForDeletionError if the named
+// service is marked for deletion, and nil if it is not.
+func CheckMarkedForDeletion(name string) error {
+	marked, err := isMarkedForDeletion(registry.LOCAL_MACHINE, name)
+	if err != nil || !marked {
+		return err
+	}
+	return newMarkedForDeletionError(name)
+}
+
+// isMarkedForDeletion checks the DeleteFlag value the service control
+// manager sets on the service's registry key under root when it is deleted.
+func isMarkedForDeletion(root registry.Key, name string) (bool, error) {
+	k, err := registry.OpenKey(root, servicesKeyPath+`\`+name, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open service key: %w", err)
+	}
+	defer k.Close()
+
+	flag, _, err := k.GetIntegerValue("DeleteFlag")
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read DeleteFlag: %w", err)
+	}
+	return flag != 0, nil
+}
+
+// isMarkedForDeletion is like the package-level isMarkedForDeletion for
+// the machine m is connected to.
+func (m *Manager) isMarkedForDeletion(name string) (bool, error) {
+	var marked bool
+	err := m.withRegistry(func(root registry.Key) error {
+		var err error
+		marked, err = isMarkedForDeletion(root, name)
+		return err
+	})
+	return marked, err
+}
+
+// markedForDeletionError returns the error for the named service being
+// marked for deletion on the machine m is connected to. Handle holders are
+// only looked for on the local machine.
+func (m *Manager) markedForDeletionError(name string) *MarkedForDeletionError {
+	if m.host != "" {
+		return &MarkedForDeletionError{Name: name}
+	}
+	return newMarkedForDeletionError(name)
+}
+
+func newMarkedForDeletionError(name string) *MarkedForDeletionError {
+	e := &MarkedForDeletionError{Name: name}
+
+	if status, err := QueryServiceEx(name); err == nil && status.ProcessID != 0 {
+		e.Holders = append(e.Holders, ProcessInfo{PID: status.ProcessID, Name: processImageName(status.ProcessID)})
+	}
+
+	procs, err := listProcesses()
+	if err != nil {
+		return e
+	}
+	for _, p := range procs {
+		for _, holder := range handleHolders {
+			if strings.EqualFold(p.Name, holder) {
+				e.Holders = append(e.Holders, p)
+			}
+		}
+	}
+	return e
+}
+
+// processImageName returns the file name of the executable the process
+// with the given ID runs, such as "svchost.exe", or "" if it cannot be
+// queried.
+func processImageName(pid uint32) string {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return filepath.Base(windows.UTF16ToString(buf[:size]))
+}
+
+func listProcesses() ([]ProcessInfo, error) {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snap)
+
+	var procs []ProcessInfo
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snap, &entry); err == nil; err = windows.Process32Next(snap, &entry) {
+		procs = append(procs, ProcessInfo{
+			PID:  entry.ProcessID,
+			Name: windows.UTF16ToString(entry.ExeFile[:]),
+		})
+	}
+	if !errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return nil, err
+	}
+	return procs, nil
+}
@@ -0,0 +1,165 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+type deployConfig struct {
+	destDir   string
+	args      []string
+	options   []ServiceOption
+	overwrite bool
+	start     bool
+	startArgs []string
+}
+
+// DeployDestination sets the directory on the target machine the executable
+// is copied to, as a local path there such as `D:\Services\MyService`. It
+// defaults to `C:\Program Files\<name>`.
+func DeployDestination(dir string) DeployOption {
+	return func(c *deployConfig) {
+		c.destDir = dir
+	}
+}
+
+// DeployServiceArgs sets the arguments the service control manager passes
+// to the deployed executable.
+func DeployServiceArgs(args ...string) DeployOption {
+	return func(c *deployConfig) {
+		c.args = args
+	}
+}
+
+// DeployServiceOptions sets the options the service is installed with.
+func DeployServiceOptions(options ...ServiceOption) DeployOption {
+	return func(c *deployConfig) {
+		c.options = append(c.options, options...)
+	}
+}
+
+// DeployOverwrite replaces an executable already present at the
+// destination instead of failing.
+func DeployOverwrite() DeployOption {
+	return func(c *deployConfig) {
+		c.overwrite = true
+	}
+}
+
+// DeployStart starts the service once it is installed and waits for it to
+// run, passing args to its Execute method.
+func DeployStart(args ...string) DeployOption {
+	return func(c *deployConfig) {
+		c.start = true
+		c.startArgs = args
+	}
+}
+
+// DeployService copies the executable at exePath to host, installs it there
+// as the named service and optionally starts it. See Manager.Deploy.
+func DeployService(ctx context.Context, host, exePath, name string, options ...DeployOption) error {
+	m, err := ConnectRemoteCtx(ctx, host)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.Deploy(ctx, exePath, name, options...)
+}
+
+// Deploy copies the executable at exePath to the machine m is connected to,
+// installs it as the named service and, with DeployStart, starts it. On a
+// remote machine the file is copied through its administrative share
+// (\\host\C$), so the caller needs administrative rights there. If
+// installing fails, a copied executable is removed again.
+func (m *Manager) Deploy(ctx context.Context, exePath, name string, options ...DeployOption) (err error) {
+	cfg := deployConfig{destDir: filepath.Join(`C:\Program Files`, name)}
+	for _, option := range options {
+		option(&cfg)
+	}
+	dest := filepath.Join(cfg.destDir, filepath.Base(exePath))
+	defer func() { m.audit("deploy", name, map[string]string{"source": exePath, "destination": dest}, err) }()
+
+	// Fail before copying anything if the install itself would be refused.
+	if m.readOnly {
+		return &ReadOnlyError{Access: windows.SC_MANAGER_CREATE_SERVICE}
+	}
+
+	target, err := m.adminSharePath(dest)
+	if err != nil {
+		return err
+	}
+
+	if err := copyExecutable(exePath, target, cfg.overwrite); err != nil {
+		return err
+	}
+
+	err = m.Install(ctx, dest, name, cfg.args, cfg.options...)
+	if err != nil {
+		if !cfg.overwrite {
+			os.Remove(target)
+		}
+		return err
+	}
+
+	if cfg.start {
+		_, err = m.StartWait(ctx, name, cfg.startArgs...)
+		return err
+	}
+	return nil
+}
+
+// adminSharePath returns the path through which path on the machine m is
+// connected to can be reached from this one.
+func (m *Manager) adminSharePath(path string) (string, error) {
+	if m.host == "" {
+		return path, nil
+	}
+	volume := filepath.VolumeName(path)
+	if len(volume) != 2 || volume[1] != ':' {
+		return "", fmt.Errorf("destination %s must be an absolute path with a drive letter", path)
+	}
+	return `\\` + m.host + `\` + strings.ToUpper(volume[:1]) + `$` + path[2:], nil
+}
+
+func copyExecutable(src, dst string, overwrite bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open executable: %w", err)
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	out, err := os.OpenFile(dst, flags, 0o755)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("destination %s already exists: %w", dst, err)
+		}
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to copy executable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+// Package winsvc provides utilities for creating and managing Windows services.
+//
+// The package is only functional on Windows. On other platforms it builds
+// with the same API, so cross-platform programs can import it
+// unconditionally, but every operation fails with ErrUnsupportedPlatform
+// and InServiceMode reports false.
+package winsvc
+
+import "errors"
+
+// ErrUnsupportedPlatform is returned by the functions and methods of this
+// package on platforms other than Windows.
+var ErrUnsupportedPlatform = errors.New("winsvc: only supported on windows")
@@ -0,0 +1,103 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// tokenElevationTypeLimited is the elevation type of the UAC-filtered token
+// an administrator runs with until they elevate.
+const tokenElevationTypeLimited = 3
+
+// IsElevated reports whether the current process runs with an elevated
+// token, as it must to install, remove or reconfigure services. With UAC
+// enabled, administrators only get one when they accept a UAC prompt.
+func IsElevated() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// RelaunchElevated starts the current executable again with args, asking
+// for elevation through a UAC prompt, and returns once it has started; the
+// caller usually exits then. It returns an error wrapping
+// windows.ERROR_CANCELLED if the user declines the prompt.
+//
+//	if !winsvc.IsElevated() {
+//		if err := winsvc.RelaunchElevated(os.Args[1:]...); err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+func RelaunchElevated(args ...string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	verb, err := windows.UTF16PtrFromString("runas")
+	if err != nil {
+		return err
+	}
+	file, err := windows.UTF16PtrFromString(exe)
+	if err != nil {
+		return err
+	}
+	params, err := windows.UTF16PtrFromString(windows.ComposeCommandLine(args))
+	if err != nil {
+		return err
+	}
+	dir, err := windows.UTF16PtrFromString(cwd)
+	if err != nil {
+		return err
+	}
+
+	err = windows.ShellExecute(0, verb, file, params, dir, windows.SW_NORMAL)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_CANCELLED) {
+			return fmt.Errorf("elevation was declined: %w", err)
+		}
+		return fmt.Errorf("failed to relaunch elevated: %w", err)
+	}
+	return nil
+}
+
+// checkElevation returns an error wrapping ErrNotElevated if the process
+// runs with the filtered token of an administrator, so that what, which
+// needs administrator rights, would fail with a bare "Access is denied".
+// Operations on remote hosts do not use the local token and are not
+// checked.
+func (m *Manager) checkElevation(what string) error {
+	if m.host != "" {
+		return nil
+	}
+	var elevationType uint32
+	var n uint32
+	err := windows.GetTokenInformation(windows.GetCurrentProcessToken(), windows.TokenElevationType,
+		(*byte)(unsafe.Pointer(&elevationType)), uint32(unsafe.Sizeof(elevationType)), &n)
+	if err != nil || elevationType != tokenElevationTypeLimited {
+		return nil
+	}
+	return newError(ErrNotElevated, "%s requires administrator rights; run from an elevated prompt or call RelaunchElevated", what)
+}
+
+// elevationError adds ErrNotElevated to an access denied error from the
+// local service control manager if the process is not elevated, since
+// elevating is then the likely fix.
+func (m *Manager) elevationError(err error, what string) error {
+	if m.host != "" || !errors.Is(err, ErrAccessDenied) || IsElevated() {
+		return err
+	}
+	return &sentinelError{
+		sentinel: ErrNotElevated,
+		err:      fmt.Errorf("%s requires administrator rights, run from an elevated prompt: %w", what, err),
+	}
+}
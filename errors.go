@@ -0,0 +1,215 @@
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors for conditions callers commonly need to tell apart.
+// Errors returned by this package wrap them where applicable, so they can
+// be tested with errors.Is. The underlying Windows error, if any, stays
+// available through errors.Is and errors.As as well.
+var (
+	ErrServiceExists         = errors.New("service already exists")
+	ErrServiceNotFound       = errors.New("service does not exist")
+	ErrAccessDenied          = errors.New("access denied")
+	ErrTimeout               = errors.New("timeout")
+	ErrMarkedForDeletion     = errors.New("service is marked for deletion")
+	ErrServiceDisabled       = errors.New("service is disabled")
+	ErrServiceNotActive      = errors.New("service is not active")
+	ErrServiceAlreadyRunning = errors.New("service is already running")
+	ErrDependencyFailed      = errors.New("dependency service failed to start")
+	ErrReadOnly              = errors.New("manager is read-only")
+	ErrSharedProcess         = errors.New("service process is shared")
+	ErrNotElevated           = errors.New("process is not elevated")
+	ErrUntrustedExecutable   = errors.New("executable signature is not trusted")
+)
+
+// ErrUnsupportedOnThisOS is wrapped by the errors of operations needing a
+// service feature that the running version of Windows lacks. They are
+// *UnsupportedError values.
+var ErrUnsupportedOnThisOS = errors.New("not supported on this version of Windows")
+
+// Win32 error codes commonly returned by the service control manager.
+const (
+	errorFileNotFound                   errno = 2
+	errorAccessDenied                   errno = 5
+	errorInvalidParameter               errno = 87
+	errorBadExeFormat                   errno = 193
+	errorDependentServicesRunning       errno = 1051
+	errorInvalidServiceControl          errno = 1052
+	errorServiceRequestTimeout          errno = 1053
+	errorServiceNoThread                errno = 1054
+	errorServiceDatabaseLocked          errno = 1055
+	errorServiceAlreadyRunning          errno = 1056
+	errorInvalidServiceAccount          errno = 1057
+	errorServiceDisabled                errno = 1058
+	errorCircularDependency             errno = 1059
+	errorServiceDoesNotExist            errno = 1060
+	errorServiceCannotAcceptCtrl        errno = 1061
+	errorServiceNotActive               errno = 1062
+	errorFailedServiceControllerConnect errno = 1063
+	errorExceptionInService             errno = 1064
+	errorServiceSpecificError           errno = 1066
+	errorProcessAborted                 errno = 1067
+	errorServiceDependencyFail          errno = 1068
+	errorServiceLogonFailed             errno = 1069
+	errorServiceStartHang               errno = 1070
+	errorInvalidServiceLock             errno = 1071
+	errorServiceMarkedForDelete         errno = 1072
+	errorServiceExists                  errno = 1073
+	errorServiceDependencyDeleted       errno = 1075
+	errorServiceNeverStarted            errno = 1077
+	errorDuplicateServiceName           errno = 1078
+	errorDifferentServiceAccount        errno = 1079
+	errorShutdownInProgress             errno = 1115
+	errorLogonTypeNotGranted            errno = 1385
+)
+
+var sentinelErrors = map[errno]error{
+	errorServiceExists:          ErrServiceExists,
+	errorDuplicateServiceName:   ErrServiceExists,
+	errorServiceDoesNotExist:    ErrServiceNotFound,
+	errorAccessDenied:           ErrAccessDenied,
+	errorServiceRequestTimeout:  ErrTimeout,
+	errorServiceMarkedForDelete: ErrMarkedForDeletion,
+	errorServiceDisabled:        ErrServiceDisabled,
+	errorServiceNotActive:       ErrServiceNotActive,
+	errorServiceAlreadyRunning:  ErrServiceAlreadyRunning,
+	errorServiceDependencyFail:  ErrDependencyFailed,
+}
+
+// sentinelError attaches a sentinel error to an underlying error without
+// changing its message.
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+func (e *sentinelError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sentinelError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
+
+// scmError wraps err with the sentinel error matching its Windows error
+// code, if there is one.
+func scmError(err error) error {
+	var code errno
+	if !errors.As(err, &code) {
+		return err
+	}
+	if sentinel, ok := sentinelErrors[code]; ok {
+		return &sentinelError{sentinel: sentinel, err: err}
+	}
+	return err
+}
+
+// newError returns an error with the formatted message that wraps sentinel.
+func newError(sentinel error, format string, args ...any) error {
+	return &sentinelError{sentinel: sentinel, err: fmt.Errorf(format, args...)}
+}
+
+// timeoutError wraps a context error with ErrTimeout if it is a deadline.
+func timeoutError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &sentinelError{sentinel: ErrTimeout, err: err}
+	}
+	return err
+}
+
+// UnsupportedError reports that a service feature needs a newer version of
+// Windows than the one running. It is returned before the service control
+// manager is asked, instead of the opaque error it would report.
+type UnsupportedError struct {
+	// Feature names the feature, such as "delayed automatic start".
+	Feature string
+	// Required is the first release with the feature, such as "Windows 8.1".
+	Required string
+	// Version is the version of Windows running.
+	Version OSVersion
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s requires %s or later, not Windows %v", e.Feature, e.Required, e.Version)
+}
+
+func (e *UnsupportedError) Unwrap() error {
+	return ErrUnsupportedOnThisOS
+}
+
+// ProcessInfo identifies a running process.
+type ProcessInfo struct {
+	PID  uint32 `json:"pid"`
+	Name string `json:"name"`
+}
+
+// MarkedForDeletionError reports that a service has been deleted but still
+// exists because handles to it are open. The service disappears once every
+// handle is closed, or at the next reboot.
+type MarkedForDeletionError struct {
+	Name string
+	// Holders lists processes that are likely keeping a handle to the
+	// service open, such as the service itself or the Services console.
+	// It is a best-effort guess and may be incomplete.
+	Holders []ProcessInfo
+}
+
+func (e *MarkedForDeletionError) Error() string {
+	msg := fmt.Sprintf("service %s is marked for deletion", e.Name)
+	if len(e.Holders) > 0 {
+		var names []string
+		for _, p := range e.Holders {
+			if p.Name == "" {
+				names = append(names, fmt.Sprintf("process %d", p.PID))
+				continue
+			}
+			names = append(names, fmt.Sprintf("%s (%d)", p.Name, p.PID))
+		}
+		msg += "; close " + strings.Join(names, ", ") + " or reboot"
+	}
+	return msg
+}
+
+func (e *MarkedForDeletionError) Unwrap() []error {
+	return []error{ErrMarkedForDeletion, errorServiceMarkedForDelete}
+}
+
+// ReadOnlyError is returned when an operation that changes a service is
+// attempted through a ReadOnlyManager.
+type ReadOnlyError struct {
+	// Name is the service the operation targeted, or empty if it targeted
+	// the service control manager itself, as installing does.
+	Name string
+	// Access is the access mask the operation needed.
+	Access uint32
+}
+
+func (e *ReadOnlyError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("read-only manager cannot request service manager access %#x", e.Access)
+	}
+	return fmt.Sprintf("read-only manager cannot request access %#x to service %s", e.Access, e.Name)
+}
+
+func (e *ReadOnlyError) Unwrap() error {
+	return ErrReadOnly
+}
+
+// DisabledServiceError is returned by StartServiceTree when the target or
+// one of its dependencies cannot be started because it is disabled.
+type DisabledServiceError struct {
+	Name string
+}
+
+func (e *DisabledServiceError) Error() string {
+	return fmt.Sprintf("service %s is disabled", e.Name)
+}
+
+func (e *DisabledServiceError) Unwrap() error {
+	return ErrServiceDisabled
+}
@@ -0,0 +1,183 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+// ETWProviderID returns the GUID of the ETW provider, derived from
+// ETWProviderName the way TraceLogging tools derive it.
+func ETWProviderID() GUID {
+	return tracingProviderID(ETWProviderName)
+}
+
+// tracingProviderID hashes name into a provider GUID as EventSource and
+// TraceLogging do: SHA-1 of a fixed namespace and the upper-cased UTF-16BE
+// name, with the version nibble set to 5.
+func tracingProviderID(name string) GUID {
+	namespace := []byte{0x48, 0x2C, 0x2D, 0xB2, 0xC3, 0x90, 0x47, 0xC8, 0x87, 0xF8, 0x1A, 0x15, 0xBF, 0xC1, 0x30, 0xFB}
+	h := sha1.New()
+	h.Write(namespace)
+	for _, u := range utf16.Encode([]rune(strings.ToUpper(name))) {
+		h.Write([]byte{byte(u >> 8), byte(u)})
+	}
+	sum := h.Sum(nil)
+	sum[7] = sum[7]&0x0f | 0x50
+
+	var id GUID
+	id.Data1 = binary.LittleEndian.Uint32(sum[0:4])
+	id.Data2 = binary.LittleEndian.Uint16(sum[4:6])
+	id.Data3 = binary.LittleEndian.Uint16(sum[6:8])
+	copy(id.Data4[:], sum[8:16])
+	return id
+}
+
+const (
+	// tlgChannel is the channel TraceLogging events are written to.
+	tlgChannel = 11
+
+	tlgInUnicodeString = 1
+	tlgInUInt32        = 8
+	tlgInInt64         = 9
+
+	eventDataTypeEventMetadata    = 1
+	eventDataTypeProviderMetadata = 2
+
+	eventProviderSetTraits = 2
+)
+
+type tracingProvider struct {
+	handle uint64
+	traits []byte
+}
+
+// etwProvider registers the package's TraceLogging provider on first use.
+// It is nil if registration fails, which disables tracing.
+var etwProvider = sync.OnceValue(func() *tracingProvider {
+	id := ETWProviderID()
+	h, err := eventRegister(&id)
+	if err != nil {
+		return nil
+	}
+	traits := make([]byte, 2, 3+len(ETWProviderName))
+	traits = append(traits, ETWProviderName...)
+	traits = append(traits, 0)
+	binary.LittleEndian.PutUint16(traits, uint16(len(traits)))
+	eventSetInformation(h, eventProviderSetTraits, traits)
+	return &tracingProvider{handle: h, traits: traits}
+})
+
+// traceField is a field of a TraceLogging event.
+type traceField struct {
+	name  string
+	str   string
+	u32   uint32
+	i64   int64
+	inTyp byte
+}
+
+func traceString(name, v string) traceField {
+	return traceField{name: name, str: v, inTyp: tlgInUnicodeString}
+}
+func traceUint32(name string, v uint32) traceField {
+	return traceField{name: name, u32: v, inTyp: tlgInUInt32}
+}
+func traceInt64(name string, v int64) traceField {
+	return traceField{name: name, i64: v, inTyp: tlgInInt64}
+}
+
+// traceEvent writes a TraceLogging event with the given name and fields.
+// Nothing is written if no trace session listens.
+func traceEvent(name string, level EventLevel, fields ...traceField) {
+	p := etwProvider()
+	if p == nil {
+		return
+	}
+
+	meta := make([]byte, 3, 64)
+	meta = append(meta, name...)
+	meta = append(meta, 0)
+	for _, f := range fields {
+		meta = append(meta, f.name...)
+		meta = append(meta, 0, f.inTyp)
+	}
+	binary.LittleEndian.PutUint16(meta, uint16(len(meta)))
+
+	data := []eventDataDescriptor{
+		{Ptr: uint64(uintptr(unsafe.Pointer(&p.traits[0]))), Size: uint32(len(p.traits)), Reserved: eventDataTypeProviderMetadata},
+		{Ptr: uint64(uintptr(unsafe.Pointer(&meta[0]))), Size: uint32(len(meta)), Reserved: eventDataTypeEventMetadata},
+	}
+	var keep [][]uint16
+	for i := range fields {
+		f := &fields[i]
+		switch f.inTyp {
+		case tlgInUnicodeString:
+			s, err := windows.UTF16FromString(f.str)
+			if err != nil {
+				s = []uint16{0}
+			}
+			keep = append(keep, s)
+			data = append(data, eventDataDescriptor{Ptr: uint64(uintptr(unsafe.Pointer(&s[0]))), Size: uint32(2 * len(s))})
+		case tlgInUInt32:
+			data = append(data, eventDataDescriptor{Ptr: uint64(uintptr(unsafe.Pointer(&f.u32))), Size: 4})
+		case tlgInInt64:
+			data = append(data, eventDataDescriptor{Ptr: uint64(uintptr(unsafe.Pointer(&f.i64))), Size: 8})
+		}
+	}
+
+	desc := eventDescriptor{Channel: tlgChannel, Level: uint8(level)}
+	eventWriteTransfer(p.handle, &desc, data)
+	runtime.KeepAlive(meta)
+	runtime.KeepAlive(fields)
+	runtime.KeepAlive(keep)
+}
+
+// traceOperation emits an event for a management operation.
+func traceOperation(op, host, name string, err error) {
+	level := LevelInformational
+	result := "success"
+	var code uint32
+	if err != nil {
+		level = LevelWarning
+		result = err.Error()
+		var errno windows.Errno
+		if errors.As(err, &errno) {
+			code = uint32(errno)
+		}
+	}
+	traceEvent("Operation", level,
+		traceString("Operation", op),
+		traceString("Host", host),
+		traceString("Service", name),
+		traceString("Result", result),
+		traceUint32("ErrorCode", code))
+}
+
+// traceControl emits an event for a control request the running service
+// received.
+func traceControl(name string, c svc.Cmd) {
+	traceEvent("ControlReceived", LevelInformational, traceString("Service", name), traceUint32("Control", uint32(c)), traceString("ControlName", ControlName(uint32(c))))
+}
+
+// traceState emits an event for a state the running service reported.
+func traceState(name string, state svc.State) {
+	traceEvent("StateChange", LevelInformational, traceString("Service", name), traceString("State", State(state).String()))
+}
+
+// traceStopDuration emits an event with the time the running service's
+// stop function took.
+func traceStopDuration(name string, d time.Duration) {
+	traceEvent("StopDuration", LevelInformational, traceString("Service", name), traceInt64("DurationMs", d.Milliseconds()))
+}
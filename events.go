@@ -0,0 +1,52 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// String returns the name and ID of e, such as "DatabaseDown(201)".
+func (e Event) String() string {
+	return fmt.Sprintf("%s(%d)", e.Name, e.ID)
+}
+
+// Log writes msg to l as an event of kind e. The category is recorded if l
+// is an *eventlog.Log; other loggers receive the ID and severity.
+func (e Event) Log(l Logger, msg string) error {
+	if el, ok := l.(*eventlog.Log); ok {
+		return reportEvent(el, e, msg)
+	}
+	return logAt(l, e.Severity, e.ID, msg)
+}
+
+// LogEvent writes msg as an event of kind e to the logger of the service
+// running in RunAsService, which is its event log source unless WithLogger
+// was given. It fails if no service is running.
+func LogEvent(e Event, msg string) error {
+	if elog == nil {
+		return errors.New("no service is running")
+	}
+	return e.Log(elog, msg)
+}
+
+func reportEvent(l *eventlog.Log, e Event, msg string) error {
+	var etype uint16
+	switch e.Severity {
+	case SeverityError:
+		etype = windows.EVENTLOG_ERROR_TYPE
+	case SeverityWarning:
+		etype = windows.EVENTLOG_WARNING_TYPE
+	default:
+		etype = windows.EVENTLOG_INFORMATION_TYPE
+	}
+	s, err := windows.UTF16PtrFromString(msg)
+	if err != nil {
+		return err
+	}
+	return windows.ReportEvent(l.Handle, etype, e.Category, e.ID, 0, 1, 0, &s, nil)
+}
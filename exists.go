@@ -0,0 +1,52 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// ServiceExists reports whether a Windows service with the given name is
+// installed. It needs no administrative rights: a service that exists but
+// cannot be opened by the caller is still reported as existing, while
+// failing to reach the service control manager at all is an error.
+func ServiceExists(name string) (bool, error) {
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to service manager: %w", scmError(err))
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	return serviceExists(scm, name)
+}
+
+// Exists reports whether the named service is installed, like ServiceExists.
+func (m *Manager) Exists(name string) (bool, error) {
+	return serviceExists(m.m.Handle, name)
+}
+
+// serviceExists opens the named service on scm with the least access
+// possible to find out whether it exists.
+func serviceExists(scm windows.Handle, name string) (bool, error) {
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return false, err
+	}
+	h, err := windows.OpenService(scm, n, windows.SERVICE_QUERY_STATUS)
+	switch {
+	case err == nil:
+		windows.CloseServiceHandle(h)
+		return true, nil
+	case errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST):
+		return false, nil
+	case errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		// The service control manager looks the name up before checking
+		// access, so a denied open means the service exists.
+		return true, nil
+	default:
+		return false, fmt.Errorf("could not access service: %w", scmError(err))
+	}
+}
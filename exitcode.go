@@ -0,0 +1,175 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// Err returns the exit code as an error, or nil if the service exited cleanly.
+func (c ExitCode) Err() error {
+	switch c.Win32ExitCode {
+	case 0:
+		return nil
+	case uint32(windows.ERROR_SERVICE_SPECIFIC_ERROR):
+		return fmt.Errorf("service-specific error %d", c.ServiceSpecificExitCode)
+	default:
+		return windows.Errno(c.Win32ExitCode)
+	}
+}
+
+// GetLastExitCode returns the exit codes from the latest status of a Windows service.
+func GetLastExitCode(name string) (ExitCode, error) {
+	status, err := QueryServiceEx(name)
+	if err != nil {
+		return ExitCode{}, err
+	}
+	return ExitCode{
+		Win32ExitCode:           status.Win32ExitCode,
+		ServiceSpecificExitCode: status.ServiceSpecificExitCode,
+	}, nil
+}
+
+// GetFailureReason returns the last exit code of a Windows service together
+// with the most recent Service Control Manager 7031 or 7034 event for it,
+// to help answer why the service stopped.
+func GetFailureReason(name string) (FailureReason, error) {
+	var reason FailureReason
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		reason, err = m.FailureReason(name)
+		return err
+	})
+	return reason, err
+}
+
+// FailureReason returns the last exit code and failure event of the named
+// service, like GetFailureReason. On a remote host, the event log is read
+// with the caller's credentials, even if m was connected with ConnectAs,
+// whose password is not kept.
+func (m *Manager) FailureReason(name string) (FailureReason, error) {
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS|windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return FailureReason{}, err
+	}
+	defer s.Close()
+
+	status, err := queryStatus(s)
+	if err != nil {
+		return FailureReason{}, fmt.Errorf("could not query service status: %w", err)
+	}
+	config, err := s.Config()
+	if err != nil {
+		return FailureReason{}, fmt.Errorf("could not query service config: %w", err)
+	}
+
+	var session windows.Handle
+	if m.host != "" {
+		session, err = evtOpenSession(m.host)
+		if err != nil {
+			return FailureReason{}, fmt.Errorf("failed to open event log session on %s: %w", m.host, err)
+		}
+		defer evtClose(session)
+	}
+
+	event, err := lastFailureEvent(session, config.DisplayName)
+	if err != nil {
+		return FailureReason{}, err
+	}
+
+	return FailureReason{
+		ExitCode: ExitCode{
+			Win32ExitCode:           status.Win32ExitCode,
+			ServiceSpecificExitCode: status.ServiceSpecificExitCode,
+		},
+		Event: event,
+	}, nil
+}
+
+const scmFailureQuery = `*[System[Provider[@Name='Service Control Manager'] and (EventID=7031 or EventID=7034)]]`
+
+// scmEventLimit bounds how many failure events are examined when looking
+// for one that names a particular service.
+const scmEventLimit = 500
+
+type eventXML struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		} `xml:"Provider"`
+		EventID     uint32 `xml:"EventID"`
+		Level       uint8  `xml:"Level"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+		EventRecordID uint64 `xml:"EventRecordID"`
+		Computer      string `xml:"Computer"`
+	} `xml:"System"`
+	Data []string `xml:"EventData>Data"`
+}
+
+// lastFailureEvent returns the newest 7031/7034 event in the System log of
+// session whose first insertion string is displayName, or nil if there is
+// none.
+func lastFailureEvent(session windows.Handle, displayName string) (*FailureEvent, error) {
+	rs, err := evtQuery(session, "System", scmFailureQuery, evtQueryChannelPath|evtQueryReverseDirection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system event log: %w", err)
+	}
+	defer evtClose(rs)
+
+	events := make([]windows.Handle, 16)
+	for seen := 0; seen < scmEventLimit; {
+		n, err := evtNext(rs, events, windows.INFINITE)
+		if err != nil {
+			if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to read system event log: %w", err)
+		}
+		seen += n
+
+		var found *FailureEvent
+		var parseErr error
+		for _, h := range events[:n] {
+			if found == nil && parseErr == nil {
+				found, parseErr = parseFailureEvent(h, displayName)
+			}
+			evtClose(h)
+		}
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func parseFailureEvent(h windows.Handle, displayName string) (*FailureEvent, error) {
+	text, err := evtRenderXML(h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render event: %w", err)
+	}
+	var e eventXML
+	if err := xml.Unmarshal([]byte(text), &e); err != nil {
+		return nil, fmt.Errorf("failed to parse event: %w", err)
+	}
+	if len(e.Data) == 0 || e.Data[0] != displayName {
+		return nil, nil
+	}
+	t, _ := time.Parse(time.RFC3339Nano, e.System.TimeCreated.SystemTime)
+	return &FailureEvent{
+		EventID: e.System.EventID,
+		Time:    t,
+		Data:    e.Data,
+	}, nil
+}
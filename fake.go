@@ -0,0 +1,619 @@
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Values of the service control manager API used by FakeSCM.
+const (
+	controlStop     = 1 // SERVICE_CONTROL_STOP
+	controlPause    = 2 // SERVICE_CONTROL_PAUSE
+	controlContinue = 3 // SERVICE_CONTROL_CONTINUE
+
+	serviceOwnProcess  = 0x10 // SERVICE_WIN32_OWN_PROCESS
+	serviceErrorNormal = 1    // SERVICE_ERROR_NORMAL
+)
+
+// FakeSCM is an in-memory SCM for tests. It keeps a table of services that
+// Install and Remove add to and remove from, and moves them between states
+// the way the service control manager does, returning the same errors for
+// invalid requests: starting a running or disabled service, stopping a
+// stopped one or one with running dependents, installing over a service
+// that is marked for deletion, and so on. Starting a service starts its
+// dependencies first. Services never run any code; use SetStatus to
+// simulate a service stopping or failing on its own.
+//
+// The options passed to Install are applied to the service's
+// configuration; those that act outside the service control manager, such
+// as firewall rules, performance counters and passwords, are ignored, as
+// are the options passed to Remove. A FakeSCM is safe for concurrent use.
+type FakeSCM struct {
+	// PendingTime is how long services stay in a pending state, such as
+	// StartPending, before reaching the state they are moving to. If zero,
+	// they reach it immediately.
+	PendingTime time.Duration
+	// OnOperation, if set, is called before each operation with its name,
+	// as in Operation.Name, and the service it targets. A non-nil error
+	// fails the operation, to simulate access denied errors or timeouts.
+	OnOperation func(op, name string) error
+
+	mu         sync.Mutex
+	services   map[string]*fakeService
+	operations []Operation
+	changed    chan struct{}
+	nextPID    uint32
+}
+
+type fakeService struct {
+	config ServiceConfig
+	status ServiceStatus
+	// args are the arguments of the most recent start.
+	args []string
+	// deleted is set when the service is removed while it is not stopped.
+	// It disappears once it stops.
+	deleted bool
+	// generation counts state changes, so that a pending state that was
+	// replaced does not complete.
+	generation int
+}
+
+// NewFakeSCM returns a FakeSCM without services.
+func NewFakeSCM() *FakeSCM {
+	return &FakeSCM{
+		services: make(map[string]*fakeService),
+		changed:  make(chan struct{}),
+		nextPID:  1000,
+	}
+}
+
+// Operations returns the operations performed on f so far, oldest first,
+// including those that failed.
+func (f *FakeSCM) Operations() []Operation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Operation(nil), f.operations...)
+}
+
+// StartArgs returns the arguments the named service was last started with.
+func (f *FakeSCM) StartArgs(name string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), s.args...), nil
+}
+
+// SetStatus replaces the status of the named service, as if the service
+// had reported it, for example StateStopped with an exit code to simulate
+// a crash. ProcessID is cleared when the service stops.
+func (f *FakeSCM) SetStatus(name string, status ServiceStatus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	status.ServiceType = s.config.ServiceType
+	s.status = status
+	f.setState(s, status.State)
+	return nil
+}
+
+// SetState moves the named service to state, like SetStatus with only the
+// state changed.
+func (f *FakeSCM) SetState(name string, state State) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	f.setState(s, state)
+	return nil
+}
+
+// begin records an operation and calls OnOperation.
+func (f *FakeSCM) begin(op, name string, params map[string]string) error {
+	f.mu.Lock()
+	f.operations = append(f.operations, Operation{Name: op, Service: name, Parameters: params})
+	hook := f.OnOperation
+	f.mu.Unlock()
+	if hook != nil {
+		return hook(op, name)
+	}
+	return nil
+}
+
+// lookup returns the named service. The caller must hold f.mu.
+func (f *FakeSCM) lookup(name string) (*fakeService, error) {
+	s, ok := f.services[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("could not access service %s: %w", name, scmError(errorServiceDoesNotExist))
+	}
+	return s, nil
+}
+
+// setState moves s to state and wakes up the waiters. A service that was
+// removed while running disappears once it stops. The caller must hold
+// f.mu.
+func (f *FakeSCM) setState(s *fakeService, state State) {
+	s.status.State = state
+	s.generation++
+	switch state {
+	case StateStopped:
+		s.status.ProcessID = 0
+		s.status.ControlsAccepted = 0
+		if s.deleted {
+			delete(f.services, strings.ToLower(s.config.Name))
+		}
+	case StateRunning, StatePaused:
+		s.status.ControlsAccepted = AcceptStop | AcceptShutdown | AcceptPauseAndContinue
+	}
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// transition moves s to pending, then after PendingTime to final. The
+// caller must hold f.mu.
+func (f *FakeSCM) transition(s *fakeService, pending, final State) {
+	if f.PendingTime <= 0 {
+		f.setState(s, final)
+		return
+	}
+	f.setState(s, pending)
+	generation := s.generation
+	time.AfterFunc(f.PendingTime, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if s.generation == generation {
+			f.setState(s, final)
+		}
+	})
+}
+
+// Install adds a service to f, like Manager.Install.
+func (f *FakeSCM) Install(ctx context.Context, appPath, name string, serviceArgs []string, options ...ServiceOption) error {
+	config := serviceConfig{mgrConfig: mgrConfig{
+		StartType: uint32(StartTypeAutomatic),
+	}}
+	for _, option := range options {
+		option(&config)
+	}
+	if err := f.begin("install", name, map[string]string{
+		"binaryPath": appPath,
+		"args":       strings.Join(serviceArgs, " "),
+	}); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, err := f.lookup(name); err == nil {
+		if s.deleted {
+			return &MarkedForDeletionError{Name: name}
+		}
+		return newError(ErrServiceExists, "service %s already exists", name)
+	}
+
+	c := config.mgrConfig
+	if c.ServiceType == 0 {
+		c.ServiceType = serviceOwnProcess
+	}
+	if c.ErrorControl == 0 {
+		c.ErrorControl = serviceErrorNormal
+	}
+	if c.DisplayName == "" {
+		c.DisplayName = name
+	}
+	if config.virtualAccount {
+		c.ServiceStartName = virtualAccountName(name)
+	}
+	if c.ServiceStartName == "" {
+		c.ServiceStartName = "LocalSystem"
+	}
+	binaryPath := escapeArg(appPath)
+	for _, arg := range serviceArgs {
+		binaryPath += " " + escapeArg(arg)
+	}
+
+	f.services[strings.ToLower(name)] = &fakeService{
+		config: ServiceConfig{
+			Name:           name,
+			DisplayName:    c.DisplayName,
+			Description:    c.Description,
+			BinaryPath:     binaryPath,
+			ServiceType:    c.ServiceType,
+			StartType:      StartType(c.StartType),
+			DelayedStart:   c.DelayedAutoStart,
+			ErrorControl:   c.ErrorControl,
+			Account:        c.ServiceStartName,
+			Dependencies:   append([]string(nil), c.Dependencies...),
+			LoadOrderGroup: c.LoadOrderGroup,
+			SidType:        c.SidType,
+		},
+		status: ServiceStatus{State: StateStopped, ServiceType: c.ServiceType},
+	}
+	return nil
+}
+
+// escapeArg quotes s for a command line the way windows.EscapeArg does
+// when mgr.Mgr.CreateService builds the binary path of a service.
+func escapeArg(s string) string {
+	if s == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(s, " \t\"\\") {
+		return s
+	}
+	quote := strings.ContainsAny(s, " \t")
+	var b strings.Builder
+	if quote {
+		b.WriteByte('"')
+	}
+	slashes := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			slashes++
+		case '"':
+			// Double the backslashes before the quote and escape it.
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		b.WriteByte(s[i])
+	}
+	if quote {
+		b.WriteString(strings.Repeat(`\`, slashes))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// Remove removes a service from f, like Manager.Remove. A service that is
+// not stopped is marked for deletion and disappears once it stops.
+func (f *FakeSCM) Remove(ctx context.Context, name string, options ...RemoveOption) error {
+	if err := f.begin("remove", name, nil); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.lookup(name)
+	if err != nil {
+		return newError(ErrServiceNotFound, "service %s is not installed", name)
+	}
+	if s.deleted {
+		return fmt.Errorf("failed to delete service: %w", scmError(errorServiceMarkedForDelete))
+	}
+	if s.status.State != StateStopped {
+		s.deleted = true
+		return nil
+	}
+	delete(f.services, strings.ToLower(name))
+	return nil
+}
+
+// Exists reports whether the named service is in f, like Manager.Exists.
+func (f *FakeSCM) Exists(name string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.lookup(name)
+	return err == nil, nil
+}
+
+// Config returns the configuration of the named service, like
+// Manager.Config.
+func (f *FakeSCM) Config(name string) (ServiceConfig, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.lookup(name)
+	if err != nil {
+		return ServiceConfig{}, err
+	}
+	config := s.config
+	config.Dependencies = append([]string(nil), config.Dependencies...)
+	return config, nil
+}
+
+// Query returns the status of the named service, like Manager.Query.
+func (f *FakeSCM) Query(name string) (ServiceStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.lookup(name)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+	return s.status, nil
+}
+
+// QueryState returns the state of the named service, like
+// Manager.QueryState.
+func (f *FakeSCM) QueryState(name string) (State, error) {
+	status, err := f.Query(name)
+	return status.State, err
+}
+
+// List returns the services in f that match filter, sorted by name, like
+// Manager.List.
+func (f *FakeSCM) List(filter ServiceFilter) ([]ServiceInfo, error) {
+	pattern := strings.ToLower(filter.NamePattern)
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", filter.NamePattern, err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []ServiceInfo
+	for key, s := range f.services {
+		if len(filter.States) > 0 && !containsState(filter.States, s.status.State) {
+			continue
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, key); !ok {
+				continue
+			}
+		}
+		if len(filter.StartTypes) > 0 && !containsStartType(filter.StartTypes, s.config.StartType) {
+			continue
+		}
+		if filter.BinaryPathContains != "" && !strings.Contains(strings.ToLower(s.config.BinaryPath), strings.ToLower(filter.BinaryPathContains)) {
+			continue
+		}
+		result = append(result, ServiceInfo{Name: s.config.Name, DisplayName: s.config.DisplayName, Status: s.status})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func containsState(states []State, state State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStartType(types []StartType, t StartType) bool {
+	for _, st := range types {
+		if st == t {
+			return true
+		}
+	}
+	return false
+}
+
+// argsParams returns the audit parameters of a start request.
+func argsParams(args []string) map[string]string {
+	if len(args) == 0 {
+		return nil
+	}
+	return map[string]string{"args": strings.Join(args, " ")}
+}
+
+// Start starts the named service and its dependencies without waiting
+// for it to run, like Manager.Start.
+func (f *FakeSCM) Start(ctx context.Context, name string, args ...string) error {
+	if err := f.begin("start", name, argsParams(args)); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	return f.start(s, args, map[*fakeService]bool{})
+}
+
+// start starts s after its dependencies. path holds the services whose
+// dependencies are being started, to detect cycles. The caller must hold
+// f.mu.
+func (f *FakeSCM) start(s *fakeService, args []string, path map[*fakeService]bool) error {
+	switch {
+	case s.deleted:
+		return fmt.Errorf("could not start service: %w", scmError(errorServiceMarkedForDelete))
+	case s.status.State != StateStopped:
+		return fmt.Errorf("could not start service: %w", scmError(errorServiceAlreadyRunning))
+	case s.config.StartType == StartTypeDisabled:
+		return fmt.Errorf("could not start service: %w", scmError(errorServiceDisabled))
+	}
+	path[s] = true
+	defer delete(path, s)
+
+	for _, depName := range s.config.Dependencies {
+		dep, err := f.lookup(depName)
+		if err != nil || dep.deleted {
+			return fmt.Errorf("could not start service: %w", scmError(errorServiceDependencyDeleted))
+		}
+		if path[dep] {
+			return fmt.Errorf("could not start service: %w", scmError(errorCircularDependency))
+		}
+		if dep.status.State != StateStopped {
+			continue
+		}
+		if err := f.start(dep, nil, path); err != nil {
+			return fmt.Errorf("could not start service: %w", scmError(errorServiceDependencyFail))
+		}
+	}
+
+	s.args = append([]string(nil), args...)
+	s.status.Win32ExitCode = 0
+	s.status.ServiceSpecificExitCode = 0
+	f.nextPID += 4
+	s.status.ProcessID = f.nextPID
+	f.transition(s, StateStartPending, StateRunning)
+	return nil
+}
+
+// Stop stops the named service and waits until it is stopped or ctx is
+// done, like Manager.Stop. It fails if services depending on it are
+// running.
+func (f *FakeSCM) Stop(ctx context.Context, name string) error {
+	if err := f.begin("stop", name, nil); err != nil {
+		return err
+	}
+	return f.control(ctx, name, controlStop)
+}
+
+// Pause pauses the named service and waits until it is paused or ctx is
+// done, like Manager.Pause.
+func (f *FakeSCM) Pause(ctx context.Context, name string) error {
+	if err := f.begin("pause", name, nil); err != nil {
+		return err
+	}
+	return f.control(ctx, name, controlPause)
+}
+
+// Continue resumes the named service and waits until it is running or ctx
+// is done, like Manager.Continue.
+func (f *FakeSCM) Continue(ctx context.Context, name string) error {
+	if err := f.begin("continue", name, nil); err != nil {
+		return err
+	}
+	return f.control(ctx, name, controlContinue)
+}
+
+// Restart stops the named service unless it is stopped, starts it again
+// and waits until it is running or ctx is done, like Manager.Restart.
+func (f *FakeSCM) Restart(ctx context.Context, name string) error {
+	if err := f.begin("restart", name, nil); err != nil {
+		return err
+	}
+	state, err := f.QueryState(name)
+	if err != nil {
+		return err
+	}
+	if state != StateStopped && state != StateStopPending {
+		if err := f.control(ctx, name, controlStop); err != nil {
+			return err
+		}
+	}
+	if err := f.WaitForState(ctx, name, StateStopped); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	s, err := f.lookup(name)
+	if err == nil {
+		err = f.start(s, nil, map[*fakeService]bool{})
+	}
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return f.WaitForState(ctx, name, StateRunning)
+}
+
+// control sends c to the named service and waits for the state it leads
+// to.
+func (f *FakeSCM) control(ctx context.Context, name string, c uint32) error {
+	f.mu.Lock()
+	s, err := f.lookup(name)
+	if err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	to, err := f.sendControl(s, c)
+	f.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("could not send control=%d: %w", c, scmError(err))
+	}
+	return f.WaitForState(ctx, name, to)
+}
+
+// sendControl applies c to s and returns the state s is moving to. The
+// caller must hold f.mu.
+func (f *FakeSCM) sendControl(s *fakeService, c uint32) (State, error) {
+	state := s.status.State
+	if state == StateStopped || state == StateStopPending {
+		return 0, errorServiceNotActive
+	}
+	if state == StateStartPending {
+		return 0, errorServiceCannotAcceptCtrl
+	}
+
+	switch c {
+	case controlStop:
+		for _, other := range f.services {
+			if other.status.State != StateStopped && containsName(other.config.Dependencies, s.config.Name) {
+				return 0, errorDependentServicesRunning
+			}
+		}
+		f.transition(s, StateStopPending, StateStopped)
+		return StateStopped, nil
+	case controlPause:
+		if state != StatePaused {
+			f.transition(s, StatePausePending, StatePaused)
+		}
+		return StatePaused, nil
+	case controlContinue:
+		if state != StateRunning {
+			f.transition(s, StateContinuePending, StateRunning)
+		}
+		return StateRunning, nil
+	}
+	return 0, errorInvalidServiceControl
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitForState blocks until the named service reaches state or ctx is
+// done, like Manager.WaitForState. A service that disappears because it
+// was removed counts as stopped.
+func (f *FakeSCM) WaitForState(ctx context.Context, name string, state State) error {
+	for {
+		f.mu.Lock()
+		s, err := f.lookup(name)
+		var current State
+		if err == nil {
+			current = s.status.State
+		}
+		changed := f.changed
+		f.mu.Unlock()
+		if err != nil {
+			if state == StateStopped {
+				return nil
+			}
+			return err
+		}
+		if current == state {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for service to go to state=%d: %w", state, timeoutError(ctx.Err()))
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFakeSCMLifecycle(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeSCM()
+
+	err := f.Install(ctx, `C:\Program Files\App\app.exe`, "App", []string{"run", "-v"}, DisplayName("My App"), OnDemandStart())
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	config, err := f.Config("app")
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	want := ServiceConfig{
+		Name:         "App",
+		DisplayName:  "My App",
+		BinaryPath:   `"C:\Program Files\App\app.exe" run -v`,
+		ServiceType:  serviceOwnProcess,
+		StartType:    StartTypeManual,
+		ErrorControl: serviceErrorNormal,
+		Account:      "LocalSystem",
+	}
+	if !configEqual(config, want) {
+		t.Errorf("Config = %+v, want %+v", config, want)
+	}
+	if state, _ := f.QueryState("App"); state != StateStopped {
+		t.Errorf("state after Install = %v, want Stopped", state)
+	}
+
+	if err := f.Start(ctx, "App", "-once"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := f.WaitForState(ctx, "App", StateRunning); err != nil {
+		t.Fatalf("WaitForState(Running): %v", err)
+	}
+	status, _ := f.Query("App")
+	if status.ProcessID == 0 || status.ControlsAccepted == 0 {
+		t.Errorf("status of running service = %+v, want a process ID and accepted controls", status)
+	}
+	if args, _ := f.StartArgs("App"); !slices.Equal(args, []string{"-once"}) {
+		t.Errorf("StartArgs = %q, want [-once]", args)
+	}
+
+	if err := f.Stop(ctx, "App"); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	status, _ = f.Query("App")
+	if status.State != StateStopped || status.ProcessID != 0 {
+		t.Errorf("status after Stop = %+v, want Stopped without a process ID", status)
+	}
+
+	if err := f.Remove(ctx, "App"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if exists, _ := f.Exists("App"); exists {
+		t.Error("service exists after Remove")
+	}
+
+	var ops []string
+	for _, op := range f.Operations() {
+		ops = append(ops, op.Name)
+	}
+	if want := []string{"install", "start", "stop", "remove"}; !slices.Equal(ops, want) {
+		t.Errorf("Operations = %q, want %q", ops, want)
+	}
+}
+
+// configEqual reports whether the scalar fields of a and b are equal.
+func configEqual(a, b ServiceConfig) bool {
+	return a.Name == b.Name && a.DisplayName == b.DisplayName && a.Description == b.Description &&
+		a.BinaryPath == b.BinaryPath && a.ServiceType == b.ServiceType && a.StartType == b.StartType &&
+		a.DelayedStart == b.DelayedStart && a.ErrorControl == b.ErrorControl && a.Account == b.Account &&
+		a.LoadOrderGroup == b.LoadOrderGroup && a.SidType == b.SidType
+}
+
+func TestFakeSCMErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		// run performs the operations of the test on f, which has a
+		// stopped service "a" and a running service "b" depending on it,
+		// and returns the error of the last one.
+		run  func(ctx context.Context, f *FakeSCM) error
+		want error
+	}{
+		{
+			name: "install existing",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				return f.Install(ctx, "a.exe", "A", nil)
+			},
+			want: ErrServiceExists,
+		},
+		{
+			name: "start running",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				return f.Start(ctx, "b")
+			},
+			want: ErrServiceAlreadyRunning,
+		},
+		{
+			name: "stop with running dependents",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				return f.Stop(ctx, "a")
+			},
+			want: errorDependentServicesRunning,
+		},
+		{
+			name: "stop stopped",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				if err := f.Stop(ctx, "b"); err != nil {
+					return err
+				}
+				if err := f.Stop(ctx, "a"); err != nil {
+					return err
+				}
+				return f.Stop(ctx, "a")
+			},
+			want: ErrServiceNotActive,
+		},
+		{
+			name: "start disabled",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				if err := f.Install(ctx, "c.exe", "c", nil, DisabledStart()); err != nil {
+					return err
+				}
+				return f.Start(ctx, "c")
+			},
+			want: ErrServiceDisabled,
+		},
+		{
+			name: "missing dependency",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				if err := f.Install(ctx, "c.exe", "c", nil, Dependencies("missing")); err != nil {
+					return err
+				}
+				return f.Start(ctx, "c")
+			},
+			want: errorServiceDependencyDeleted,
+		},
+		{
+			name: "circular dependency",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				f.Install(ctx, "c.exe", "c", nil, Dependencies("d"))
+				f.Install(ctx, "d.exe", "d", nil, Dependencies("c"))
+				return f.Start(ctx, "c")
+			},
+			want: errorServiceDependencyFail,
+		},
+		{
+			name: "query missing",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				_, err := f.Query("missing")
+				return err
+			},
+			want: ErrServiceNotFound,
+		},
+		{
+			name: "remove missing",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				return f.Remove(ctx, "missing")
+			},
+			want: ErrServiceNotFound,
+		},
+		{
+			name: "install over marked for deletion",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				if err := f.Remove(ctx, "b"); err != nil {
+					return err
+				}
+				return f.Install(ctx, "b.exe", "b", nil)
+			},
+			want: ErrMarkedForDeletion,
+		},
+		{
+			name: "operation hook",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				f.OnOperation = func(op, name string) error {
+					if op == "start" {
+						return ErrAccessDenied
+					}
+					return nil
+				}
+				return f.Start(ctx, "a")
+			},
+			want: ErrAccessDenied,
+		},
+		{
+			name: "canceled",
+			run: func(ctx context.Context, f *FakeSCM) error {
+				ctx, cancel := context.WithCancel(ctx)
+				cancel()
+				return f.Start(ctx, "a")
+			},
+			want: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := NewFakeSCM()
+			if err := f.Install(ctx, "a.exe", "a", nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Install(ctx, "b.exe", "b", nil, Dependencies("a")); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Start(ctx, "b"); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.run(ctx, f); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeSCMStartsDependencies(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeSCM()
+	f.Install(ctx, "db.exe", "db", nil)
+	f.Install(ctx, "cache.exe", "cache", nil, Dependencies("db"))
+	f.Install(ctx, "app.exe", "app", nil, Dependencies("cache", "db"))
+
+	if err := f.Start(ctx, "app"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	for _, name := range []string{"db", "cache", "app"} {
+		if state, _ := f.QueryState(name); state != StateRunning {
+			t.Errorf("state of %s = %v, want Running", name, state)
+		}
+	}
+}
+
+func TestFakeSCMRemoveRunning(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeSCM()
+	f.Install(ctx, "app.exe", "app", nil)
+	f.Start(ctx, "app")
+
+	if err := f.Remove(ctx, "app"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if exists, _ := f.Exists("app"); !exists {
+		t.Fatal("running service disappeared when it was removed")
+	}
+	if err := f.Stop(ctx, "app"); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if exists, _ := f.Exists("app"); exists {
+		t.Error("removed service exists after it stopped")
+	}
+}
+
+func TestFakeSCMPendingTime(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	f := NewFakeSCM()
+	f.PendingTime = 20 * time.Millisecond
+	f.Install(ctx, "app.exe", "app", nil)
+
+	if err := f.Start(ctx, "app"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if state, _ := f.QueryState("app"); state != StateStartPending {
+		t.Errorf("state right after Start = %v, want StartPending", state)
+	}
+	if err := f.WaitForState(ctx, "app", StateRunning); err != nil {
+		t.Fatalf("WaitForState(Running): %v", err)
+	}
+	if err := f.Pause(ctx, "app"); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if err := f.Restart(ctx, "app"); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	if state, _ := f.QueryState("app"); state != StateRunning {
+		t.Errorf("state after Restart = %v, want Running", state)
+	}
+}
+
+func TestFakeSCMList(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeSCM()
+	f.Install(ctx, `C:\a\one.exe`, "ProductOne", nil)
+	f.Install(ctx, `C:\b\two.exe`, "ProductTwo", nil, DisabledStart())
+	f.Install(ctx, `C:\a\other.exe`, "Other", nil)
+	f.Start(ctx, "ProductOne")
+
+	tests := []struct {
+		name   string
+		filter ServiceFilter
+		want   []string
+	}{
+		{"all", ServiceFilter{}, []string{"Other", "ProductOne", "ProductTwo"}},
+		{"pattern", ServiceFilter{NamePattern: "product*"}, []string{"ProductOne", "ProductTwo"}},
+		{"state", ServiceFilter{States: []State{StateRunning}}, []string{"ProductOne"}},
+		{"start type", ServiceFilter{StartTypes: []StartType{StartTypeDisabled}}, []string{"ProductTwo"}},
+		{"binary path", ServiceFilter{BinaryPathContains: `c:\A\`}, []string{"Other", "ProductOne"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, err := f.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []string
+			for _, s := range services {
+				got = append(got, s.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("List = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeArg(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", `""`},
+		{"plain", "plain"},
+		{`C:\dir\app.exe`, `C:\dir\app.exe`},
+		{`C:\Program Files\app.exe`, `"C:\Program Files\app.exe"`},
+		{"a\tb", "\"a\tb\""},
+		{`say "hi"`, `"say \"hi\""`},
+		{`a\"b`, `a\\\"b`},
+		{`C:\My Dir\`, `"C:\My Dir\\"`},
+	}
+	for _, tt := range tests {
+		if got := escapeArg(tt.in); got != tt.want {
+			t.Errorf("escapeArg(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
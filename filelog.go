@@ -0,0 +1,253 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// WithFileLogRotation sets the rotation of the file log RunAsService falls
+// back to when the event log cannot be opened.
+func WithFileLogRotation(r FileLogRotation) RunOption {
+	return func(c *runConfig) {
+		c.fileLog = r
+	}
+}
+
+// ServiceLogDir returns %ProgramData%\<name>\logs, the directory of the
+// file log RunAsService falls back to for the named service.
+func ServiceLogDir(name string) (string, error) {
+	root, err := windows.KnownFolderPath(windows.FOLDERID_ProgramData, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate ProgramData: %w", err)
+	}
+	return filepath.Join(root, name, "logs"), nil
+}
+
+// openFallbackLog opens the file log of the named service in ServiceLogDir.
+func openFallbackLog(name string, rotation FileLogRotation) (*FileLogger, error) {
+	dir, err := ServiceLogDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewFileLogger(dir, name, rotation)
+}
+
+// RotatingFile is an io.Writer appending to <dir>\<name>.log, rotating the
+// file as configured. Rotated files are named <name>-<time>.log, or
+// <name>-<time>.log.gz if compressed. It is safe for concurrent use.
+type RotatingFile struct {
+	dir      string
+	name     string
+	rotation FileLogRotation
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	created time.Time
+	// pending tracks the rotated files being compressed.
+	pending sync.WaitGroup
+}
+
+// NewRotatingFile opens a RotatingFile in dir, creating the directory if
+// necessary.
+func NewRotatingFile(dir, name string, rotation FileLogRotation) (*RotatingFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	r := &RotatingFile{dir: dir, name: name, rotation: rotation.withDefaults()}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.prune()
+	return r, nil
+}
+
+// Path returns the path of the current file.
+func (r *RotatingFile) Path() string {
+	return filepath.Join(r.dir, r.name+".log")
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.Path(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	r.f = f
+	r.size = info.Size()
+	r.created = time.Now()
+	if d, ok := info.Sys().(*syscall.Win32FileAttributeData); ok && r.size > 0 {
+		r.created = time.Unix(0, d.CreationTime.Nanoseconds())
+	}
+	return nil
+}
+
+// rotate renames the current file, starts a new one and compresses the
+// renamed file in the background if configured.
+func (r *RotatingFile) rotate() error {
+	r.f.Close()
+	r.f = nil
+	rotated := filepath.Join(r.dir, r.name+"-"+time.Now().UTC().Format("20060102T150405.000")+".log")
+	if err := os.Rename(r.Path(), rotated); err != nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if r.rotation.Compress {
+		r.pending.Add(1)
+		go func() {
+			defer r.pending.Done()
+			if err := compressFile(rotated); err == nil {
+				os.Remove(rotated)
+			}
+			r.prune()
+		}()
+		return nil
+	}
+	r.prune()
+	return nil
+}
+
+// compressFile writes path to path.gz.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+	}
+	return err
+}
+
+// prune deletes the rotated files beyond MaxBackups or older than
+// Retention.
+func (r *RotatingFile) prune() {
+	matches, err := filepath.Glob(filepath.Join(r.dir, r.name+"-*.log*"))
+	if err != nil {
+		return
+	}
+	// The timestamp in the name sorts chronologically.
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	cutoff := time.Now().Add(-r.rotation.Retention)
+	for i, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if i >= r.rotation.MaxBackups || info.ModTime().Before(cutoff) {
+			os.Remove(path)
+		}
+	}
+}
+
+// Write implements io.Writer. A write that would take the file past
+// MaxSize, or one after it reached MaxAge, rotates it first.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && (r.size+int64(len(p)) > r.rotation.MaxSize || time.Since(r.created) > r.rotation.MaxAge) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the file and waits for pending compressions.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.f != nil {
+		err = r.f.Close()
+		r.f = nil
+	}
+	r.mu.Unlock()
+	r.pending.Wait()
+	return err
+}
+
+// FileLogger is a Logger writing to a RotatingFile in the format of
+// NewWriterLogger. It is safe for concurrent use.
+type FileLogger struct {
+	w *RotatingFile
+}
+
+// NewFileLogger opens a FileLogger writing to <dir>\<name>.log, creating
+// the directory if necessary.
+func NewFileLogger(dir, name string, rotation FileLogRotation) (*FileLogger, error) {
+	w, err := NewRotatingFile(dir, name, rotation)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLogger{w: w}, nil
+}
+
+// Path returns the path of the current log file.
+func (l *FileLogger) Path() string {
+	return l.w.Path()
+}
+
+func (l *FileLogger) write(severity string, eid uint32, msg string) error {
+	line := fmt.Sprintf("%s %s [%d] %s\n", time.Now().UTC().Format(time.RFC3339), severity, eid, strings.TrimRight(msg, "\r\n"))
+	_, err := l.w.Write([]byte(line))
+	return err
+}
+
+// Info implements Logger.
+func (l *FileLogger) Info(eid uint32, msg string) error {
+	return l.write("INFO", eid, msg)
+}
+
+// Warning implements Logger.
+func (l *FileLogger) Warning(eid uint32, msg string) error {
+	return l.write("WARNING", eid, msg)
+}
+
+// Error implements Logger.
+func (l *FileLogger) Error(eid uint32, msg string) error {
+	return l.write("ERROR", eid, msg)
+}
+
+// Close closes the log file.
+func (l *FileLogger) Close() error {
+	return l.w.Close()
+}
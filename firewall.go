@@ -0,0 +1,42 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// addFirewallRule creates rule for the named service running from program.
+func addFirewallRule(rule FirewallRule, name, program string) error {
+	dir := "in"
+	if rule.Outbound {
+		dir = "out"
+	}
+	args := []string{"advfirewall", "firewall", "add", "rule", "name=" + rule.Name, "dir=" + dir, "action=allow"}
+	switch rule.Scope {
+	case FirewallScopeService:
+		args = append(args, "service="+name)
+	case FirewallScopeProgram:
+		args = append(args, "program="+program)
+	case FirewallScopeServiceAndProgram:
+		args = append(args, "service="+name, "program="+program)
+	default:
+		return fmt.Errorf("invalid firewall rule scope %d", rule.Scope)
+	}
+	if rule.Protocol != "" {
+		args = append(args, "protocol="+rule.Protocol)
+	}
+	if rule.LocalPorts != "" {
+		args = append(args, "localport="+rule.LocalPorts)
+	}
+	if rule.RemoteAddresses != "" {
+		args = append(args, "remoteip="+rule.RemoteAddresses)
+	}
+
+	out, err := exec.Command("netsh", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to add firewall rule %s: %w: %s", rule.Name, err, out)
+	}
+	return nil
+}
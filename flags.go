@@ -0,0 +1,93 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// InstallFlags are the flags of the install command of Manage, for programs
+// that define their own service commands, such as with cobra. The zero
+// value is ready to be registered.
+type InstallFlags struct {
+	flags installFlags
+}
+
+// Register defines the install flags on fs, each named with prefix
+// followed by the name Manage uses, such as -display-name or, with prefix
+// "service-", -service-display-name.
+func (f *InstallFlags) Register(fs *flag.FlagSet, prefix string) {
+	if prefix == "" {
+		f.flags.register(fs)
+		return
+	}
+	// Register on a scratch set, then copy the flags over renamed, so
+	// that the names and usage stay those of Manage.
+	scratch := flag.NewFlagSet("", flag.ContinueOnError)
+	f.flags.register(scratch)
+	scratch.VisitAll(func(fl *flag.Flag) {
+		fs.Var(fl.Value, prefix+fl.Name, fl.Usage)
+	})
+}
+
+// Install installs the running executable as the named service, configured
+// by the parsed flags and by the ManageServiceOptions in options, which
+// take precedence. The
+// service runs the executable with runArgs, which must lead back to the
+// program's run command, such as []string{"service", "run"}.
+func (f *InstallFlags) Install(name string, runArgs []string, options ...ManageOption) error {
+	cfg := newManageConfig(options)
+	return installManaged(name, &f.flags, runArgs, &cfg)
+}
+
+// ServiceFlags are the flags RegisterServiceFlags adds to a program's
+// command line.
+type ServiceFlags struct {
+	command string
+	install InstallFlags
+}
+
+// RegisterServiceFlags adds service management to a program whose command
+// line is a flag.FlagSet: a -service flag naming a command of Manage,
+// install, uninstall, start, stop, restart, status or run, and the install
+// flags of Manage prefixed with "service-", such as -service-account. A
+// program using flag.CommandLine reduces to:
+//
+//	sf := winsvc.RegisterServiceFlags(flag.CommandLine)
+//	flag.Parse()
+//	if sf.Requested() {
+//		if err := sf.Run("MyService", svc, flag.Args()); err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+//
+// The installed service runs the program with -service run, followed by
+// the arguments given to install.
+func RegisterServiceFlags(fs *flag.FlagSet) *ServiceFlags {
+	f := &ServiceFlags{}
+	fs.StringVar(&f.command, "service", "", "service command: install, uninstall, start, stop, restart, status or run")
+	f.install.Register(fs, "service-")
+	return f
+}
+
+// Requested reports whether the parsed command line has a -service
+// command, to be run with Run instead of the program's normal work.
+func (f *ServiceFlags) Requested() bool {
+	return f.command != ""
+}
+
+// Run runs the -service command for the named service, which runs s, like
+// Manage. args are the remaining arguments of the command line: those of
+// the service on install and start, and none otherwise.
+func (f *ServiceFlags) Run(name string, s Service, args []string, options ...ManageOption) error {
+	switch command := strings.ToLower(f.command); command {
+	case "install":
+		return f.install.Install(name, append([]string{"-service", "run"}, args...), options...)
+	case "run", "start", "uninstall", "remove", "stop", "restart", "status":
+		return Manage(name, s, append([]string{command}, args...), options...)
+	}
+	return fmt.Errorf("unknown service command %q", f.command)
+}
@@ -0,0 +1,383 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+type forwardConfig struct {
+	log        string
+	interval   time.Duration
+	bufferSize int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// ForwardFromLog tails the named event log instead of the Application log.
+func ForwardFromLog(name string) ForwardOption {
+	return func(c *forwardConfig) {
+		c.log = name
+	}
+}
+
+// ForwardPollInterval sets how often the event log is checked for new
+// entries, 2 seconds by default.
+func ForwardPollInterval(d time.Duration) ForwardOption {
+	return func(c *forwardConfig) {
+		c.interval = d
+	}
+}
+
+// ForwardBufferSize sets how many entries are kept while the endpoint is
+// unreachable, 1000 by default. When the buffer is full, the oldest
+// entries are dropped.
+func ForwardBufferSize(n int) ForwardOption {
+	return func(c *forwardConfig) {
+		c.bufferSize = n
+	}
+}
+
+// ForwardBackoff sets the delays between attempts to reach a failing
+// endpoint, which double from min up to max; 1 second and 1 minute by
+// default.
+func ForwardBackoff(min, max time.Duration) ForwardOption {
+	return func(c *forwardConfig) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+// Forwarder tails the entries an event source writes to the event log of
+// the local machine and ships them to a remote collector, buffering them
+// while the collector is unreachable.
+type Forwarder struct {
+	source  string
+	cfg     forwardConfig
+	sink    forwardSink
+	dropped atomic.Uint64
+}
+
+// NewForwarder returns a Forwarder shipping the entries of source to
+// endpoint, which is one of:
+//
+//   - syslog://host[:port] for RFC 5424 syslog over UDP, port 514 by default
+//   - syslog+tcp://host[:port] for RFC 5424 syslog over TCP with octet
+//     counting framing, port 514 by default
+//   - tcp://host:port for one JSON-encoded ForwardedEvent per line
+//   - http://... or https://... for POST requests with a JSON array of
+//     ForwardedEvent values
+func NewForwarder(source, endpoint string, options ...ForwardOption) (*Forwarder, error) {
+	if strings.ContainsAny(source, `'"`) {
+		return nil, fmt.Errorf("invalid event source %q", source)
+	}
+	cfg := forwardConfig{
+		log:        "Application",
+		interval:   2 * time.Second,
+		bufferSize: 1000,
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	if cfg.bufferSize < 1 {
+		cfg.bufferSize = 1
+	}
+
+	sink, err := newForwardSink(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Forwarder{source: source, cfg: cfg, sink: sink}, nil
+}
+
+// Dropped returns the number of entries dropped because the buffer was
+// full.
+func (f *Forwarder) Dropped() uint64 {
+	return f.dropped.Load()
+}
+
+// Run forwards entries written from now on until ctx is done, then
+// returns ctx's error. Entries still buffered at that point are lost.
+func (f *Forwarder) Run(ctx context.Context) error {
+	defer f.sink.close()
+
+	last, err := f.lastRecordID()
+	if err != nil {
+		return err
+	}
+
+	var buf []ForwardedEvent
+	var retryAt time.Time
+	backoff := f.cfg.minBackoff
+	ticker := time.NewTicker(f.cfg.interval)
+	defer ticker.Stop()
+	for {
+		events, err := f.readSince(last)
+		if err == nil && len(events) > 0 {
+			last = events[len(events)-1].RecordID
+			buf = append(buf, events...)
+			if over := len(buf) - f.cfg.bufferSize; over > 0 {
+				f.dropped.Add(uint64(over))
+				buf = append(buf[:0], buf[over:]...)
+			}
+		}
+
+		if len(buf) > 0 && !time.Now().Before(retryAt) {
+			if err := f.sink.send(ctx, buf); err != nil {
+				retryAt = time.Now().Add(backoff)
+				backoff = min(2*backoff, f.cfg.maxBackoff)
+			} else {
+				buf = buf[:0]
+				backoff = f.cfg.minBackoff
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Forwarder) query(after uint64) string {
+	if after == 0 {
+		return fmt.Sprintf(`*[System[Provider[@Name='%s']]]`, f.source)
+	}
+	return fmt.Sprintf(`*[System[Provider[@Name='%s'] and (EventRecordID > %d)]]`, f.source, after)
+}
+
+// lastRecordID returns the record ID of the newest entry of the source, or
+// zero if there is none.
+func (f *Forwarder) lastRecordID() (uint64, error) {
+	rs, err := evtQuery(0, f.cfg.log, f.query(0), evtQueryChannelPath|evtQueryReverseDirection)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s event log: %w", f.cfg.log, err)
+	}
+	defer evtClose(rs)
+
+	events := make([]windows.Handle, 1)
+	n, err := evtNext(rs, events, windows.INFINITE)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read %s event log: %w", f.cfg.log, err)
+	}
+	defer evtClose(events[0])
+	if n == 0 {
+		return 0, nil
+	}
+	e, err := renderForwardedEvent(events[0])
+	if err != nil {
+		return 0, err
+	}
+	return e.RecordID, nil
+}
+
+// readSince returns the entries of the source after the given record ID,
+// oldest first.
+func (f *Forwarder) readSince(after uint64) ([]ForwardedEvent, error) {
+	rs, err := evtQuery(0, f.cfg.log, f.query(after), evtQueryChannelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s event log: %w", f.cfg.log, err)
+	}
+	defer evtClose(rs)
+
+	var result []ForwardedEvent
+	events := make([]windows.Handle, 16)
+	for {
+		n, err := evtNext(rs, events, windows.INFINITE)
+		if err != nil {
+			if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+				return result, nil
+			}
+			return result, fmt.Errorf("failed to read %s event log: %w", f.cfg.log, err)
+		}
+		for _, h := range events[:n] {
+			if e, err := renderForwardedEvent(h); err == nil {
+				result = append(result, e)
+			}
+			evtClose(h)
+		}
+	}
+}
+
+func renderForwardedEvent(h windows.Handle) (ForwardedEvent, error) {
+	text, err := evtRenderXML(h)
+	if err != nil {
+		return ForwardedEvent{}, err
+	}
+	var x eventXML
+	if err := xml.Unmarshal([]byte(text), &x); err != nil {
+		return ForwardedEvent{}, fmt.Errorf("failed to parse event: %w", err)
+	}
+	t, _ := time.Parse(time.RFC3339Nano, x.System.TimeCreated.SystemTime)
+	severity := SeverityInfo
+	switch x.System.Level {
+	case 1, 2:
+		severity = SeverityError
+	case 3:
+		severity = SeverityWarning
+	}
+	return ForwardedEvent{
+		RecordID: x.System.EventRecordID,
+		Time:     t,
+		Host:     x.System.Computer,
+		Source:   x.System.Provider.Name,
+		EventID:  x.System.EventID,
+		Severity: severity,
+		Message:  strings.Join(x.Data, " "),
+	}, nil
+}
+
+// forwardSink delivers entries to a remote endpoint.
+type forwardSink interface {
+	send(ctx context.Context, events []ForwardedEvent) error
+	close()
+}
+
+func newForwardSink(endpoint string) (forwardSink, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	switch u.Scheme {
+	case "syslog":
+		return &streamSink{network: "udp", addr: hostPort(u.Host, "514"), encode: encodeSyslog}, nil
+	case "syslog+tcp":
+		return &streamSink{network: "tcp", addr: hostPort(u.Host, "514"), encode: encodeSyslogFramed}, nil
+	case "tcp":
+		if u.Port() == "" {
+			return nil, fmt.Errorf("endpoint %q has no port", endpoint)
+		}
+		return &streamSink{network: "tcp", addr: u.Host, encode: encodeJSONLine}, nil
+	case "http", "https":
+		return &httpSink{url: endpoint, client: &http.Client{Timeout: 30 * time.Second}}, nil
+	}
+	return nil, fmt.Errorf("unsupported endpoint scheme %q", u.Scheme)
+}
+
+func hostPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// streamSink writes each entry to a UDP or TCP connection, redialing after
+// a failure.
+type streamSink struct {
+	network string
+	addr    string
+	encode  func(ForwardedEvent) []byte
+	conn    net.Conn
+}
+
+func (s *streamSink) send(ctx context.Context, events []ForwardedEvent) error {
+	if s.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, s.network, s.addr)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	for _, e := range events {
+		if _, err := s.conn.Write(s.encode(e)); err != nil {
+			s.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *streamSink) close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// syslogSeverity maps severities to RFC 5424 severities.
+var syslogSeverity = map[Severity]int{
+	SeverityError:   3,
+	SeverityWarning: 4,
+	SeverityInfo:    6,
+}
+
+// syslogFacilityDaemon is the RFC 5424 facility of system daemons.
+const syslogFacilityDaemon = 3
+
+// syslogTimeFormat is the RFC 5424 timestamp format, which allows at most
+// six digits of fractional seconds.
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+func encodeSyslog(e ForwardedEvent) []byte {
+	host := e.Host
+	if host == "" {
+		host = "-"
+	}
+	app := strings.ReplaceAll(e.Source, " ", "_")
+	if app == "" {
+		app = "-"
+	}
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s - %d - %s",
+		syslogFacilityDaemon*8+syslogSeverity[e.Severity], e.Time.UTC().Format(syslogTimeFormat),
+		host, app, e.EventID, e.Message))
+}
+
+func encodeSyslogFramed(e ForwardedEvent) []byte {
+	msg := encodeSyslog(e)
+	return append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+}
+
+func encodeJSONLine(e ForwardedEvent) []byte {
+	data, _ := json.Marshal(e)
+	return append(data, '\n')
+}
+
+// httpSink posts entries as a JSON array.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSink) send(ctx context.Context, events []ForwardedEvent) error {
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSink) close() {}
@@ -0,0 +1,63 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodeSyslog(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		name string
+		e    ForwardedEvent
+		want string
+	}{
+		{
+			name: "info",
+			e:    ForwardedEvent{Time: at, Host: "web1", Source: "MyService", EventID: 1, Severity: SeverityInfo, Message: "started"},
+			want: "<30>1 2024-05-01T10:00:00.123456Z web1 MyService - 1 - started",
+		},
+		{
+			name: "error",
+			e:    ForwardedEvent{Time: at, Host: "web1", Source: "MyService", EventID: 7, Severity: SeverityError, Message: "failed"},
+			want: "<27>1 2024-05-01T10:00:00.123456Z web1 MyService - 7 - failed",
+		},
+		{
+			name: "warning",
+			e:    ForwardedEvent{Time: at, Host: "web1", Source: "MyService", EventID: 3, Severity: SeverityWarning, Message: "slow"},
+			want: "<28>1 2024-05-01T10:00:00.123456Z web1 MyService - 3 - slow",
+		},
+		{
+			name: "whole second",
+			e:    ForwardedEvent{Time: at.Truncate(time.Second), Host: "web1", Source: "MyService", EventID: 1, Severity: SeverityInfo, Message: "started"},
+			want: "<30>1 2024-05-01T10:00:00.000000Z web1 MyService - 1 - started",
+		},
+		{
+			name: "nil values",
+			e:    ForwardedEvent{Time: at, EventID: 1, Severity: SeverityInfo, Message: "started"},
+			want: "<30>1 2024-05-01T10:00:00.123456Z - - - 1 - started",
+		},
+		{
+			name: "source with spaces",
+			e:    ForwardedEvent{Time: at, Host: "web1", Source: "My Service", EventID: 1, Severity: SeverityInfo, Message: "started"},
+			want: "<30>1 2024-05-01T10:00:00.123456Z web1 My_Service - 1 - started",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(encodeSyslog(tt.e)); got != tt.want {
+				t.Errorf("encodeSyslog() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeSyslogFramed(t *testing.T) {
+	e := ForwardedEvent{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Host: "web1", Source: "MyService", EventID: 1, Severity: SeverityInfo, Message: "started"}
+	const msg = "<30>1 2024-05-01T10:00:00.000000Z web1 MyService - 1 - started"
+	if got, want := string(encodeSyslogFramed(e)), "62 "+msg; got != want {
+		t.Errorf("encodeSyslogFramed() = %q, want %q", got, want)
+	}
+}
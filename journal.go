@@ -0,0 +1,96 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// WithJournal makes RunAsService append every lifecycle transition of the
+// service to the file at path, one JSON-encoded JournalEntry per line.
+// Each entry is flushed to disk before the service continues, so the file
+// gives an ordered account of what happened up to a crash, independently
+// of the event log. Read it back with ReadJournal.
+func WithJournal(path string) RunOption {
+	return func(c *runConfig) {
+		c.journal = path
+	}
+}
+
+// journal appends entries to a journal file. A nil *journal records
+// nothing.
+type journal struct {
+	mu      sync.Mutex
+	f       *os.File
+	service string
+}
+
+func openJournal(path, service string) (*journal, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	return &journal{f: f, service: service}, nil
+}
+
+func (j *journal) record(e JournalEntry) {
+	if j == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Service = j.service
+	e.PID = os.Getpid()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.f.Write(append(data, '\n'))
+	j.f.Sync()
+}
+
+func (j *journal) control(c svc.Cmd) {
+	j.record(JournalEntry{Event: JournalControl, Control: uint32(c)})
+}
+
+func (j *journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.f.Close()
+}
+
+// ReadJournal returns the entries of the journal file at path, oldest
+// first. Lines that cannot be decoded, such as one cut short by a crash,
+// are skipped, so the entries of later runs appended after it are still
+// returned.
+func ReadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return entries, nil
+}
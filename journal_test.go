@@ -0,0 +1,84 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadJournal(t *testing.T) {
+	const (
+		start = `{"time":"2026-01-02T03:04:05Z","service":"svc","pid":10,"event":"start"}`
+		ready = `{"time":"2026-01-02T03:04:06Z","service":"svc","pid":10,"event":"ready"}`
+		exit  = `{"time":"2026-01-02T03:04:07Z","service":"svc","pid":10,"event":"exit","exitCode":1067,"error":"boom"}`
+	)
+	tests := []struct {
+		name  string
+		lines []string
+		want  []JournalEvent
+	}{
+		{"empty", nil, nil},
+		{"complete run", []string{start, ready, exit}, []JournalEvent{JournalStart, JournalReady, JournalExit}},
+		{"cut short by a crash", []string{start, ready[:20]}, []JournalEvent{JournalStart}},
+		{"run after a crash", []string{start, ready[:20], start, exit}, []JournalEvent{JournalStart, JournalStart, JournalExit}},
+		{"blank and garbage lines", []string{"", start, "not json", `[1,2]`, exit}, []JournalEvent{JournalStart, JournalExit}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "journal.jsonl")
+			content := strings.Join(tt.lines, "\n")
+			if content != "" {
+				content += "\n"
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := ReadJournal(path)
+			if err != nil {
+				t.Fatalf("ReadJournal: %v", err)
+			}
+			var got []JournalEvent
+			for _, e := range entries {
+				got = append(got, e.Event)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ReadJournal events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadJournalFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	content := `{"time":"2026-01-02T03:04:05Z","service":"svc","pid":10,"event":"stop-end","durationMs":250}` + "\n" +
+		`{"time":"2026-01-02T03:04:06Z","service":"svc","pid":10,"event":"exit","exitCode":1067,"error":"boom"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadJournal(path)
+	if err != nil {
+		t.Fatalf("ReadJournal: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("ReadJournal returned %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Service != "svc" || e.PID != 10 || e.DurationMs != 250 || e.Time.Second() != 5 {
+		t.Errorf("stop-end entry = %+v", e)
+	}
+	if e := entries[1]; e.ExitCode != 1067 || e.Error != "boom" {
+		t.Errorf("exit entry = %+v", e)
+	}
+}
+
+func TestReadJournalMissing(t *testing.T) {
+	_, err := ReadJournal(filepath.Join(t.TempDir(), "missing.jsonl"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadJournal of a missing file = %v, want fs.ErrNotExist", err)
+	}
+}
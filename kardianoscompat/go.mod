@@ -0,0 +1,9 @@
+module github.com/lib-x/winsvc/kardianoscompat
+
+go 1.22
+
+require (
+	github.com/kardianos/service v1.2.2
+	github.com/lib-x/winsvc v0.0.0-20261014070121-05be6850cc92
+	golang.org/x/sys v0.26.0
+)
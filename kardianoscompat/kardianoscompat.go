@@ -0,0 +1,50 @@
+// Package kardianoscompat implements the github.com/kardianos/service
+// Service on top of winsvc, so programs written against that API can move
+// to winsvc by changing the call creating their service:
+//
+//	s, err := kardianoscompat.New(prg, &service.Config{Name: "MyService"},
+//		kardianoscompat.ServiceOptions(winsvc.Hardened(), winsvc.Recovery(recovery)))
+//	...
+//	err = s.Run()
+//
+// The returned Service runs prg with winsvc.RunAsService and installs it
+// with winsvc.InstallServiceWithOption, translating the Config and the
+// Windows options of kardianos/service, StartType, DelayedAutoStart,
+// Password and OnFailure, into winsvc options. ServiceOptions adds the
+// features kardianos/service lacks, such as recovery action sequences,
+// triggers and SID types, and RunOptions configures RunAsService.
+//
+// Stop is also called for system shutdown, as RunAsService does not tell
+// them apart; the Shutdown method of a service.Shutdowner is not used.
+//
+// On other platforms New returns the Service of kardianos/service itself,
+// so cross-platform programs keep working unchanged.
+package kardianoscompat
+
+import "github.com/lib-x/winsvc"
+
+// Option configures the Service returned by New. Options only apply on
+// Windows.
+type Option func(*config)
+
+type config struct {
+	serviceOptions []winsvc.ServiceOption
+	runOptions     []winsvc.RunOption
+}
+
+// ServiceOptions sets options Install installs the service with, applied
+// after the ones translated from the service.Config, which they override.
+func ServiceOptions(options ...winsvc.ServiceOption) Option {
+	return func(c *config) {
+		c.serviceOptions = append(c.serviceOptions, options...)
+	}
+}
+
+// RunOptions sets options Run passes to winsvc.RunAsService. Run uses
+// winsvc.ExitWhen itself to stop the service when Start fails, so an
+// ExitWhen given here is replaced.
+func RunOptions(options ...winsvc.RunOption) Option {
+	return func(c *config) {
+		c.runOptions = append(c.runOptions, options...)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package kardianoscompat
+
+import "github.com/kardianos/service"
+
+// New returns the Service of kardianos/service for the current platform;
+// options are ignored.
+func New(i service.Interface, c *service.Config, options ...Option) (service.Service, error) {
+	return service.New(i, c)
+}
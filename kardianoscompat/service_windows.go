@@ -0,0 +1,320 @@
+//go:build windows
+
+package kardianoscompat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kardianos/service"
+	"github.com/lib-x/winsvc"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// The Windows options of kardianos/service, read from Config.Option.
+const (
+	optionStartType        = "StartType"
+	optionDelayedAutoStart = "DelayedAutoStart"
+	optionPassword         = "Password"
+	optionOnFailure        = "OnFailure"
+	optionOnFailureDelay   = "OnFailureDelayDuration"
+	optionOnFailureReset   = "OnFailureResetPeriod"
+)
+
+// New returns a service.Service running and managing i as the Windows
+// service described by c, with winsvc.
+func New(i service.Interface, c *service.Config, options ...Option) (service.Service, error) {
+	if c.Name == "" {
+		return nil, service.ErrNameFieldRequired
+	}
+	s := &winService{i: i, c: c}
+	for _, option := range options {
+		option(&s.cfg)
+	}
+	return s, nil
+}
+
+type winService struct {
+	i   service.Interface
+	c   *service.Config
+	cfg config
+
+	mu      sync.Mutex
+	started bool
+	err     error
+}
+
+func (s *winService) Run() error {
+	s.mu.Lock()
+	s.err = nil
+	s.mu.Unlock()
+
+	exit := make(chan error, 1)
+	start := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.i.Start(s); err != nil {
+			s.err = err
+			exit <- err
+			return
+		}
+		s.started = true
+	}
+	stop := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.started {
+			return
+		}
+		s.started = false
+		if err := s.i.Stop(s); err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+
+	options := append(append([]winsvc.RunOption(nil), s.cfg.runOptions...), winsvc.ExitWhen(exit))
+	err := winsvc.RunAsService(s.c.Name, start, stop, !winsvc.InServiceMode(), options...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
+
+func (s *winService) Start() error {
+	return winsvc.StartService(s.c.Name)
+}
+
+func (s *winService) Stop() error {
+	return winsvc.StopService(s.c.Name)
+}
+
+func (s *winService) Restart() error {
+	// Like StopService, wait as long as DefaultWaitPolicy allows.
+	m, err := winsvc.Connect(winsvc.WithWaitPolicy(winsvc.DefaultWaitPolicy()))
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.Restart(context.Background(), s.c.Name)
+}
+
+func (s *winService) Install() error {
+	exe := s.c.Executable
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return fmt.Errorf("failed to get executable path: %w", err)
+		}
+	}
+	options, err := s.installOptions()
+	if err != nil {
+		return err
+	}
+	if err := winsvc.InstallServiceWithOption(exe, s.c.Name, s.c.Arguments, options...); err != nil {
+		return err
+	}
+	if err := s.setEnvironment(); err != nil {
+		winsvc.RemoveService(s.c.Name)
+		return err
+	}
+	return nil
+}
+
+// installOptions translates the Config into winsvc options, followed by
+// the ones given to ServiceOptions.
+func (s *winService) installOptions() ([]winsvc.ServiceOption, error) {
+	var options []winsvc.ServiceOption
+	if s.c.DisplayName != "" {
+		options = append(options, winsvc.DisplayName(s.c.DisplayName))
+	}
+	if s.c.Description != "" {
+		options = append(options, winsvc.Description(s.c.Description))
+	}
+	if len(s.c.Dependencies) > 0 {
+		options = append(options, winsvc.Dependencies(s.c.Dependencies...))
+	}
+	if s.c.UserName != "" {
+		var password []byte
+		if p := stringOption(s.c.Option, optionPassword, ""); p != "" {
+			password = []byte(p)
+		}
+		options = append(options, winsvc.RunAsUser(s.c.UserName, password))
+	}
+
+	switch startType := stringOption(s.c.Option, optionStartType, service.ServiceStartAutomatic); startType {
+	case service.ServiceStartAutomatic:
+		if boolOption(s.c.Option, optionDelayedAutoStart, false) {
+			options = append(options, winsvc.AutoDelayStart())
+		} else {
+			options = append(options, winsvc.AutoStart())
+		}
+	case service.ServiceStartManual:
+		options = append(options, winsvc.OnDemandStart())
+	case service.ServiceStartDisabled:
+		options = append(options, winsvc.DisabledStart())
+	default:
+		return nil, fmt.Errorf("unknown start type %q", startType)
+	}
+
+	if onFailure := stringOption(s.c.Option, optionOnFailure, ""); onFailure != "" {
+		action := winsvc.RecoveryAction{Type: winsvc.RecoveryRestart, Delay: time.Second}
+		switch onFailure {
+		case service.OnFailureReboot:
+			action.Type = winsvc.RecoveryReboot
+		case service.OnFailureNoAction:
+			action.Type = winsvc.RecoveryNone
+		}
+		if d, err := time.ParseDuration(stringOption(s.c.Option, optionOnFailureDelay, "1s")); err == nil {
+			action.Delay = d
+		}
+		options = append(options, winsvc.Recovery(winsvc.RecoveryConfig{
+			Actions:     []winsvc.RecoveryAction{action},
+			ResetPeriod: time.Duration(intOption(s.c.Option, optionOnFailureReset, 10)) * time.Second,
+		}))
+	}
+	return append(options, s.cfg.serviceOptions...), nil
+}
+
+// setEnvironment sets the Environment registry value of the service,
+// which the service control manager adds to its environment.
+func (s *winService) setEnvironment() error {
+	if len(s.c.EnvVars) == 0 {
+		return nil
+	}
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Services\`+s.c.Name, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open the registry key of service %s: %w", s.c.Name, err)
+	}
+	defer k.Close()
+	env := make([]string, 0, len(s.c.EnvVars))
+	for name, value := range s.c.EnvVars {
+		env = append(env, name+"="+value)
+	}
+	if err := k.SetStringsValue("Environment", env); err != nil {
+		return fmt.Errorf("failed to set the environment of service %s: %w", s.c.Name, err)
+	}
+	return nil
+}
+
+func (s *winService) Uninstall() error {
+	err := winsvc.RemoveService(s.c.Name)
+	if errors.Is(err, winsvc.ErrServiceNotFound) {
+		return fmt.Errorf("service %s is not installed", s.c.Name)
+	}
+	return err
+}
+
+func (s *winService) Logger(errs chan<- error) (service.Logger, error) {
+	if !winsvc.InServiceMode() {
+		return service.ConsoleLogger, nil
+	}
+	return s.SystemLogger(errs)
+}
+
+func (s *winService) SystemLogger(errs chan<- error) (service.Logger, error) {
+	l, err := eventlog.Open(s.c.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogger{log: l, errs: errs}, nil
+}
+
+func (s *winService) String() string {
+	if s.c.DisplayName != "" {
+		return s.c.DisplayName
+	}
+	return s.c.Name
+}
+
+func (s *winService) Platform() string {
+	return service.Platform()
+}
+
+// Status reports pending and paused services as stopped, like
+// kardianos/service, except that a service still starting is running.
+func (s *winService) Status() (service.Status, error) {
+	state, err := winsvc.QueryServiceState(s.c.Name)
+	if errors.Is(err, winsvc.ErrServiceNotFound) {
+		return service.StatusUnknown, service.ErrNotInstalled
+	}
+	if err != nil {
+		return service.StatusUnknown, err
+	}
+	switch state {
+	case winsvc.StateRunning, winsvc.StateStartPending:
+		return service.StatusRunning, nil
+	case winsvc.StateStopped, winsvc.StateStopPending, winsvc.StatePaused,
+		winsvc.StatePausePending, winsvc.StateContinuePending:
+		return service.StatusStopped, nil
+	}
+	return service.StatusUnknown, fmt.Errorf("unknown state %v", state)
+}
+
+// eventLogger is a service.Logger writing to the event log source of the
+// service, with the event IDs kardianos/service uses.
+type eventLogger struct {
+	log  *eventlog.Log
+	errs chan<- error
+}
+
+func (l *eventLogger) send(err error) error {
+	if err != nil && l.errs != nil {
+		l.errs <- err
+	}
+	return err
+}
+
+func (l *eventLogger) Error(v ...any) error {
+	return l.send(l.log.Error(3, fmt.Sprint(v...)))
+}
+
+func (l *eventLogger) Warning(v ...any) error {
+	return l.send(l.log.Warning(2, fmt.Sprint(v...)))
+}
+
+func (l *eventLogger) Info(v ...any) error {
+	return l.send(l.log.Info(1, fmt.Sprint(v...)))
+}
+
+func (l *eventLogger) Errorf(format string, a ...any) error {
+	return l.send(l.log.Error(3, fmt.Sprintf(format, a...)))
+}
+
+func (l *eventLogger) Warningf(format string, a ...any) error {
+	return l.send(l.log.Warning(2, fmt.Sprintf(format, a...)))
+}
+
+func (l *eventLogger) Infof(format string, a ...any) error {
+	return l.send(l.log.Info(1, fmt.Sprintf(format, a...)))
+}
+
+// stringOption, boolOption and intOption read an option of a
+// service.KeyValue like kardianos/service does, falling back to the
+// default if it is missing or of another type.
+func stringOption(kv service.KeyValue, name, def string) string {
+	if v, ok := kv[name].(string); ok {
+		return v
+	}
+	return def
+}
+
+func boolOption(kv service.KeyValue, name string, def bool) bool {
+	if v, ok := kv[name].(bool); ok {
+		return v
+	}
+	return def
+}
+
+func intOption(kv service.KeyValue, name string, def int) int {
+	if v, ok := kv[name].(int); ok {
+		return v
+	}
+	return def
+}
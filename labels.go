@@ -0,0 +1,183 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// labelsValue is the multi-string value on a service's registry key that
+// holds its labels.
+const labelsValue = "Labels"
+
+// SetServiceLabels tags a Windows service with labels, replacing any it had,
+// so that related services can be addressed as a group. Labels are compared
+// case-insensitively. Calling it without labels removes them all.
+func SetServiceLabels(name string, labels ...string) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.SetLabels(name, labels...)
+	})
+}
+
+// SetLabels tags the named service with labels, like SetServiceLabels.
+func (m *Manager) SetLabels(name string, labels ...string) (err error) {
+	defer func() { m.audit("set-labels", name, map[string]string{"labels": strings.Join(labels, ",")}, err) }()
+	if err := m.checkAccess(name, windows.SERVICE_CHANGE_CONFIG); err != nil {
+		return err
+	}
+	return m.withRegistry(func(root registry.Key) error {
+		k, err := registry.OpenKey(root, servicesKeyPath+`\`+name, registry.SET_VALUE)
+		if err != nil {
+			if errors.Is(err, registry.ErrNotExist) {
+				return newError(ErrServiceNotFound, "service %s is not installed", name)
+			}
+			return fmt.Errorf("failed to open service key: %w", scmError(err))
+		}
+		defer k.Close()
+
+		if len(labels) == 0 {
+			err = k.DeleteValue(labelsValue)
+			if errors.Is(err, registry.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = k.SetStringsValue(labelsValue, labels)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write labels: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetServiceLabels returns the labels of a Windows service.
+func GetServiceLabels(name string) ([]string, error) {
+	var labels []string
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		labels, err = m.Labels(name)
+		return err
+	})
+	return labels, err
+}
+
+// Labels returns the labels of the named service, like GetServiceLabels.
+func (m *Manager) Labels(name string) ([]string, error) {
+	var labels []string
+	err := m.withRegistry(func(root registry.Key) error {
+		var err error
+		labels, err = readLabels(root, name)
+		return err
+	})
+	return labels, err
+}
+
+// ListServicesWithLabel returns the names of the Win32 services tagged with
+// label.
+func ListServicesWithLabel(label string) ([]string, error) {
+	var names []string
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		names, err = m.ServicesWithLabel(label)
+		return err
+	})
+	return names, err
+}
+
+// ServicesWithLabel returns the names of the services tagged with label,
+// like ListServicesWithLabel.
+func (m *Manager) ServicesWithLabel(label string) ([]string, error) {
+	services, err := m.enumServices(windows.SERVICE_STATE_ALL)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err = m.withRegistry(func(root registry.Key) error {
+		for _, info := range services {
+			labels, err := readLabels(root, info.Name)
+			if errors.Is(err, ErrServiceNotFound) || errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+				// The service was deleted since it was enumerated.
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			for _, l := range labels {
+				if strings.EqualFold(l, label) {
+					names = append(names, info.Name)
+					break
+				}
+			}
+		}
+		return nil
+	})
+	return names, err
+}
+
+func readLabels(root registry.Key, name string) ([]string, error) {
+	k, err := registry.OpenKey(root, servicesKeyPath+`\`+name, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, newError(ErrServiceNotFound, "service %s is not installed", name)
+		}
+		return nil, fmt.Errorf("failed to open service key: %w", scmError(err))
+	}
+	defer k.Close()
+
+	labels, _, err := k.GetStringsValue(labelsValue)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read labels of service %s: %w", name, err)
+	}
+	return labels, nil
+}
+
+// SendControlToGroup sends the custom control code, which must be between
+// 128 and 255, to every service tagged with label, all at once. It returns
+// one result per service; the error is only set if the services could not
+// be resolved.
+func SendControlToGroup(label string, code uint32) ([]BatchResult, error) {
+	var results []BatchResult
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		results, err = m.SendControlToGroup(context.Background(), label, code)
+		return err
+	})
+	return results, err
+}
+
+// SendControlToGroup sends a custom control to every service tagged with
+// label, like the package-level SendControlToGroup.
+func (m *Manager) SendControlToGroup(ctx context.Context, label string, code uint32) ([]BatchResult, error) {
+	if code < 128 || code > 255 {
+		return nil, fmt.Errorf("control code %d is not a custom control", code)
+	}
+	names, err := m.ServicesWithLabel(label)
+	if err != nil {
+		return nil, err
+	}
+
+	c := svc.Cmd(code)
+	results := m.runBatch(ctx, names, len(names), controlAccess(c)|windows.SERVICE_QUERY_STATUS, func(s *mgr.Service) error {
+		if _, err := s.Control(c); err != nil {
+			return fmt.Errorf("could not send control=%d: %w", c, scmError(err))
+		}
+		return nil
+	})
+	for _, r := range results {
+		m.audit("control", r.Name, map[string]string{"code": strconv.FormatUint(uint64(code), 10), "label": label}, r.Err)
+	}
+	return results, nil
+}
@@ -0,0 +1,66 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+func (t HandlerThresholds) withDefaults() HandlerThresholds {
+	if t.Stop <= 0 {
+		t.Stop = 10 * time.Second
+	}
+	if t.Control <= 0 {
+		t.Control = time.Second
+	}
+	return t
+}
+
+// WarnSlowHandlers sets the thresholds above which RunAsService logs a
+// warning about a slow handler. RunAsService always logs how long the
+// start and stop functions ran.
+func WarnSlowHandlers(t HandlerThresholds) RunOption {
+	return func(c *runConfig) {
+		c.thresholds = t
+	}
+}
+
+// logStartupLatency records how long the service took from its process
+// being created by the service control manager to reporting Running.
+func (s *winService) logStartupLatency() {
+	created, err := ProcessStartTime(uint32(os.Getpid()))
+	if err != nil {
+		return
+	}
+	elog.Info(1, fmt.Sprintf("%s service running after %v", s.name, time.Since(created).Round(time.Millisecond)))
+}
+
+// runStart runs the start function and logs how long it ran.
+func (s *winService) runStart() {
+	began := time.Now()
+	s.start()
+	elog.Info(1, fmt.Sprintf("%s service start function returned after %v", s.name, time.Since(began).Round(time.Millisecond)))
+}
+
+// logStopDuration logs how long the stop function took, as a warning if
+// it exceeded the threshold.
+func (s *winService) logStopDuration(took time.Duration) {
+	took = took.Round(time.Millisecond)
+	if took > s.thresholds.Stop {
+		elog.Warning(1, fmt.Sprintf("%s service stop function took %v, more than %v; slow stops risk being terminated at shutdown", s.name, took, s.thresholds.Stop))
+		return
+	}
+	elog.Info(1, fmt.Sprintf("%s service stop function took %v", s.name, took))
+}
+
+// logControlLatency warns if handling the control c took longer than the
+// threshold.
+func (s *winService) logControlLatency(c svc.Cmd, took time.Duration) {
+	if took > s.thresholds.Control {
+		elog.Warning(1, fmt.Sprintf("%s service took %v to handle %s, more than %v", s.name, took.Round(time.Millisecond), ControlName(uint32(c)), s.thresholds.Control))
+	}
+}
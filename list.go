@@ -0,0 +1,130 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ListServices returns all Win32 services that match filter.
+func ListServices(filter ServiceFilter) ([]ServiceInfo, error) {
+	var result []ServiceInfo
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		result, err = m.List(filter)
+		return err
+	})
+	return result, err
+}
+
+// List returns all Win32 services that match filter, like ListServices.
+func (m *Manager) List(filter ServiceFilter) ([]ServiceInfo, error) {
+	services, err := m.enumServices(windows.SERVICE_STATE_ALL)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := strings.ToLower(filter.NamePattern)
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", filter.NamePattern, err)
+		}
+	}
+
+	var result []ServiceInfo
+	for _, info := range services {
+		if len(filter.States) > 0 && !containsState(filter.States, info.Status.State) {
+			continue
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, strings.ToLower(info.Name)); !ok {
+				continue
+			}
+		}
+		if len(filter.StartTypes) > 0 || filter.BinaryPathContains != "" {
+			ok, err := m.matchConfig(info.Name, filter)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		result = append(result, info)
+	}
+
+	return result, nil
+}
+
+func (m *Manager) matchConfig(name string, filter ServiceFilter) (bool, error) {
+	s, err := m.openService(name, windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return false, err
+	}
+	defer s.Close()
+
+	config, err := s.Config()
+	if err != nil {
+		return false, fmt.Errorf("could not query config of service %s: %w", name, err)
+	}
+
+	if len(filter.StartTypes) > 0 {
+		found := false
+		for _, t := range filter.StartTypes {
+			if uint32(t) == config.StartType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if filter.BinaryPathContains != "" {
+		if !strings.Contains(strings.ToLower(config.BinaryPathName), strings.ToLower(filter.BinaryPathContains)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// enumServices lists all Win32 services in the given SERVICE_STATE_* class
+// using EnumServicesStatusEx.
+func (m *Manager) enumServices(state uint32) ([]ServiceInfo, error) {
+	var result []ServiceInfo
+	var resume uint32
+	buf := make([]byte, 64*1024)
+	for {
+		var needed, returned uint32
+		err := windows.EnumServicesStatusEx(m.m.Handle, windows.SC_ENUM_PROCESS_INFO, windows.SERVICE_WIN32, state,
+			&buf[0], uint32(len(buf)), &needed, &returned, &resume, nil)
+		if err != nil && !errors.Is(err, windows.ERROR_MORE_DATA) {
+			return nil, fmt.Errorf("failed to enumerate services: %w", err)
+		}
+
+		if returned > 0 {
+			entries := unsafe.Slice((*windows.ENUM_SERVICE_STATUS_PROCESS)(unsafe.Pointer(&buf[0])), returned)
+			for i := range entries {
+				result = append(result, ServiceInfo{
+					Name:        windows.UTF16PtrToString(entries[i].ServiceName),
+					DisplayName: windows.UTF16PtrToString(entries[i].DisplayName),
+					Status:      newServiceStatus(&entries[i].ServiceStatusProcess),
+				})
+			}
+		}
+
+		if err == nil {
+			return result, nil
+		}
+		if returned == 0 && needed > uint32(len(buf)) {
+			buf = make([]byte, needed)
+		}
+	}
+}
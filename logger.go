@@ -0,0 +1,63 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithLogger makes RunAsService log to l instead of the event log source
+// named after the service, for example in tests or containers without an
+// event log. RunAsService does not close l.
+func WithLogger(l Logger) RunOption {
+	return func(c *runConfig) {
+		c.logger = l
+	}
+}
+
+// logAt writes msg to l with the given severity.
+func logAt(l Logger, severity Severity, eid uint32, msg string) error {
+	switch severity {
+	case SeverityError:
+		return l.Error(eid, msg)
+	case SeverityWarning:
+		return l.Warning(eid, msg)
+	default:
+		return l.Info(eid, msg)
+	}
+}
+
+// NewWriterLogger returns a Logger that writes one line per message to w,
+// prefixed with the time, severity and event ID, such as
+// "2024-05-01T10:00:00Z INFO [1] starting MyService service". It is safe
+// for concurrent use.
+func NewWriterLogger(w io.Writer) Logger {
+	return &writerLogger{w: w}
+}
+
+type writerLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *writerLogger) write(severity string, eid uint32, msg string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := fmt.Fprintf(l.w, "%s %s [%d] %s\n", time.Now().UTC().Format(time.RFC3339), severity, eid, msg)
+	return err
+}
+
+func (l *writerLogger) Info(eid uint32, msg string) error {
+	return l.write("INFO", eid, msg)
+}
+
+func (l *writerLogger) Warning(eid uint32, msg string) error {
+	return l.write("WARNING", eid, msg)
+}
+
+func (l *writerLogger) Error(eid uint32, msg string) error {
+	return l.write("ERROR", eid, msg)
+}
@@ -0,0 +1,308 @@
+package winsvc
+
+import (
+	"fmt"
+	"time"
+)
+
+// Logger receives the messages RunAsService logs about the service's
+// lifecycle. *eventlog.Log and the logger returned by debug.New implement
+// it.
+type Logger interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+}
+
+// Severity is the type of an event log entry.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// Event defines a kind of event a service logs, so that Event Viewer
+// filters and alerting rules can key on its ID and category instead of the
+// event ID 1 RunAsService uses for its own messages.
+//
+//	var EventDatabaseDown = winsvc.Event{Name: "DatabaseDown", ID: 201, Category: 2, Severity: winsvc.SeverityError}
+//	...
+//	winsvc.LogEvent(EventDatabaseDown, "lost connection to db01")
+//
+// The message file InstallService registers for the event source shows the
+// message of events with IDs from 1 to 1000. Categories are shown by
+// number.
+type Event struct {
+	Name     string
+	ID       uint32
+	Category uint16
+	Severity Severity
+}
+
+// StructuredEvent is the JSON document LogStructured writes as the message
+// of an event, such as {"message":"request failed","fields":{"status":503}}.
+// Field names are written in sorted order, so equal events produce equal
+// messages.
+type StructuredEvent struct {
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// LoggedEvent is a structured event read back from the event log.
+type LoggedEvent struct {
+	EventID uint32
+	Time    time.Time
+	StructuredEvent
+}
+
+// FileLogRotation configures when a RotatingFile or FileLogger starts a
+// new file and how many old files it keeps. Zero fields take their
+// defaults.
+type FileLogRotation struct {
+	// MaxSize is the size in bytes at which the file is rotated, 10 MiB by
+	// default.
+	MaxSize int64
+	// MaxAge is the age at which the file is rotated, 24 hours by default.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept, 7 by default.
+	MaxBackups int
+	// Retention is how long rotated files are kept, 30 days by default.
+	Retention time.Duration
+	// Compress gzips rotated files in the background, adding a .gz suffix
+	// to their names.
+	Compress bool
+}
+
+func (r FileLogRotation) withDefaults() FileLogRotation {
+	if r.MaxSize <= 0 {
+		r.MaxSize = 10 << 20
+	}
+	if r.MaxAge <= 0 {
+		r.MaxAge = 24 * time.Hour
+	}
+	if r.MaxBackups <= 0 {
+		r.MaxBackups = 7
+	}
+	if r.Retention <= 0 {
+		r.Retention = 30 * 24 * time.Hour
+	}
+	return r
+}
+
+// JournalEvent is the kind of a lifecycle transition recorded in a journal.
+type JournalEvent string
+
+const (
+	// JournalStart is recorded when RunAsService is called.
+	JournalStart JournalEvent = "start"
+	// JournalReady is recorded when the service reports Running.
+	JournalReady JournalEvent = "ready"
+	// JournalControl is recorded for every control request other than
+	// interrogate.
+	JournalControl JournalEvent = "control"
+	// JournalStopBegin is recorded before the stop function is called.
+	JournalStopBegin JournalEvent = "stop-begin"
+	// JournalStopEnd is recorded when the stop function returns.
+	JournalStopEnd JournalEvent = "stop-end"
+	// JournalExit is recorded when RunAsService returns.
+	JournalExit JournalEvent = "exit"
+)
+
+// JournalEntry is one line of a lifecycle journal.
+type JournalEntry struct {
+	Time    time.Time    `json:"time"`
+	Service string       `json:"service"`
+	PID     int          `json:"pid"`
+	Event   JournalEvent `json:"event"`
+	// Control is the control code of JournalControl entries.
+	Control uint32 `json:"control,omitempty"`
+	// DurationMs is how long the stop function took, for JournalStopEnd
+	// entries.
+	DurationMs int64 `json:"durationMs,omitempty"`
+	// ExitCode and Error describe how the service ended, for JournalExit
+	// entries.
+	ExitCode uint32 `json:"exitCode,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ForwardedEvent is an event log entry shipped by a Forwarder.
+type ForwardedEvent struct {
+	RecordID uint64    `json:"recordId"`
+	Time     time.Time `json:"time"`
+	Host     string    `json:"host"`
+	Source   string    `json:"source"`
+	EventID  uint32    `json:"eventId"`
+	Severity Severity  `json:"severity"`
+	Message  string    `json:"message"`
+}
+
+// GUID is a globally unique identifier, such as the ID of an event
+// provider. It has the layout of windows.GUID, so the two convert to each
+// other.
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// String returns g in registry format, such as
+// {4D36E972-E325-11CE-BFC1-08002BE10318}.
+func (g GUID) String() string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%04X-%012X}", g.Data1, g.Data2, g.Data3, g.Data4[:2], g.Data4[2:])
+}
+
+// EventLevel is the level of an event written to an event log channel.
+type EventLevel uint8
+
+const (
+	LevelCritical EventLevel = iota + 1
+	LevelError
+	LevelWarning
+	LevelInformational
+	LevelVerbose
+)
+
+// ChannelProvider describes a manifest-based event provider with a
+// dedicated operational channel, such as "Contoso-MyService/Operational",
+// which appears under Applications and Services Logs in Event Viewer. Unlike
+// the Application log events RunAsService writes, its events carry proper
+// levels and keywords that collectors can subscribe to.
+type ChannelProvider struct {
+	// Name is the provider name, such as "Contoso-MyService".
+	Name string
+	GUID GUID
+	// Channel is the name of the operational channel; empty means
+	// Name + "/Operational".
+	Channel  string
+	Keywords []ChannelKeyword
+	Events   []ChannelEvent
+}
+
+// ChannelKeyword is a named keyword bit of a ChannelProvider.
+type ChannelKeyword struct {
+	Name string
+	Mask uint64
+}
+
+// ChannelEvent is an event a ChannelProvider writes. Its message is the
+// string passed to ChannelWriter.Write.
+type ChannelEvent struct {
+	ID    uint16
+	Level EventLevel
+	// Keywords names keywords of the provider the event belongs to.
+	Keywords []string
+}
+
+// CounterType is the type of a performance counter, which decides how
+// perfmon displays its value.
+type CounterType uint32
+
+const (
+	// CounterGauge is displayed as its current value, such as a queue
+	// depth.
+	CounterGauge CounterType = 0x00010100 // PERF_COUNTER_LARGE_RAWCOUNT
+	// CounterRate is an ever-increasing count that is displayed as its
+	// change per second, such as requests/sec.
+	CounterRate CounterType = 0x10410500 // PERF_COUNTER_BULK_COUNT
+)
+
+// Counter is a performance counter of a CounterSet.
+type Counter struct {
+	// ID identifies the counter within its set; IDs must be unique.
+	ID          uint32
+	Name        string
+	Description string
+	Type        CounterType
+}
+
+// CounterSet describes a set of performance counters a service publishes
+// through the version 2 performance counter APIs. Once registered with
+// InstallCounterSet or the WithPerfCounters option, the set appears in
+// perfmon and typeperf under Name.
+type CounterSet struct {
+	Name        string
+	Description string
+	// ProviderGUID identifies the provider that publishes the set, and
+	// GUID the set itself.
+	ProviderGUID GUID
+	GUID         GUID
+	Counters     []Counter
+}
+
+// ETWProviderName is the name of the TraceLogging provider through which
+// the package emits ETW events for installs, control requests, state
+// transitions and stop durations. Trace sessions can enable it by name,
+// for example with `wpr -start` and a profile naming "*LibX-Winsvc", or by
+// the GUID ETWProviderID returns.
+const ETWProviderName = "LibX-Winsvc"
+
+// AuditRecord describes one management operation performed through this
+// package.
+type AuditRecord struct {
+	Time time.Time
+	// User is the account that performed the operation, as DOMAIN\user.
+	User string
+	// Host is the machine the operation targeted, empty for the local one.
+	Host string
+	// Operation names the operation, such as "install" or "stop".
+	Operation string
+	Service   string
+	// Parameters holds operation-specific details. Secrets such as
+	// passwords are never included.
+	Parameters map[string]string
+	// Err is the operation's result, nil on success.
+	Err error
+}
+
+// Auditor receives a record of every install, remove, control and
+// configuration operation performed through this package.
+type Auditor interface {
+	Audit(AuditRecord)
+}
+
+// Operation describes a management operation performed through this
+// package, as passed to an Observer.
+type Operation struct {
+	// Name names the operation, such as "install" or "stop", like
+	// AuditRecord.Operation.
+	Name string
+	// Host is the machine the operation targeted, empty for the local one.
+	Host    string
+	Service string
+	// Parameters holds operation-specific details, like
+	// AuditRecord.Parameters.
+	Parameters map[string]string
+}
+
+// Observer is notified of the operations performed through this package,
+// for custom metrics or notifications. Methods are called synchronously
+// after each operation completes, so they should return quickly. Embed
+// NopObserver to implement only some of them.
+type Observer interface {
+	// OnInstall is called after a service was installed or deployed.
+	OnInstall(op Operation)
+	// OnRemove is called after a service was removed.
+	OnRemove(op Operation)
+	// OnControl is called after a service was started, stopped, paused,
+	// continued, restarted, terminated or sent a custom control.
+	OnControl(op Operation)
+	// OnStateChange is called when an operation brought a service into a
+	// new state, and when a service run by RunAsService in this process
+	// reports one.
+	OnStateChange(service string, state State)
+	// OnError is called instead of the other methods when an operation
+	// fails.
+	OnError(op Operation, err error)
+}
+
+// NopObserver implements Observer with methods that do nothing.
+type NopObserver struct{}
+
+func (NopObserver) OnInstall(Operation)         {}
+func (NopObserver) OnRemove(Operation)          {}
+func (NopObserver) OnControl(Operation)         {}
+func (NopObserver) OnStateChange(string, State) {}
+func (NopObserver) OnError(Operation, error)    {}
@@ -0,0 +1,17 @@
+package winsvc
+
+import "testing"
+
+func TestGUIDString(t *testing.T) {
+	for _, tt := range []struct {
+		g    GUID
+		want string
+	}{
+		{GUID{}, "{00000000-0000-0000-0000-000000000000}"},
+		{GUID{0x4d36e972, 0xe325, 0x11ce, [8]byte{0xbf, 0xc1, 0x08, 0x00, 0x2b, 0xe1, 0x03, 0x18}}, "{4D36E972-E325-11CE-BFC1-08002BE10318}"},
+	} {
+		if got := tt.g.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/lib-x/winsvc/logrushook
+
+go 1.22
+
+require (
+	github.com/lib-x/winsvc v0.0.0-20261014063515-b8ca2c9754c2
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require golang.org/x/sys v0.26.0 // indirect
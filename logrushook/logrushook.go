@@ -0,0 +1,69 @@
+// Package logrushook provides a logrus hook that writes log entries to a
+// Windows event log, so services built on logrus can tee their records into
+// the event log source registered for them by winsvc.
+//
+//	elog, err := eventlog.Open("MyService")
+//	...
+//	logrus.AddHook(logrushook.New(elog))
+package logrushook
+
+import (
+	"github.com/lib-x/winsvc"
+	"github.com/sirupsen/logrus"
+)
+
+// EventID is the event ID entries are written with.
+const EventID = 1
+
+// Hook is a logrus.Hook writing to an event log. Entries at error level and
+// above become error events, warnings become warning events, and info
+// entries information events.
+type Hook struct {
+	log       winsvc.Logger
+	levels    []logrus.Level
+	formatter logrus.Formatter
+}
+
+// New returns a Hook writing to log, which is usually an *eventlog.Log but
+// may be any winsvc.Logger, for the given levels. Without levels it fires
+// for info and above. Entries are formatted as text without timestamps,
+// which the event log records itself.
+func New(log winsvc.Logger, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel}
+	}
+	return &Hook{
+		log:       log,
+		levels:    levels,
+		formatter: &logrus.TextFormatter{DisableColors: true, DisableTimestamp: true},
+	}
+}
+
+// SetFormatter makes h format entries with f, for example a
+// logrus.JSONFormatter.
+func (h *Hook) SetFormatter(f logrus.Formatter) {
+	h.formatter = f
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements logrus.Hook.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	b, err := h.formatter.Format(e)
+	if err != nil {
+		return err
+	}
+	msg := string(b)
+
+	switch {
+	case e.Level <= logrus.ErrorLevel:
+		return h.log.Error(EventID, msg)
+	case e.Level == logrus.WarnLevel:
+		return h.log.Warning(EventID, msg)
+	default:
+		return h.log.Info(EventID, msg)
+	}
+}
@@ -0,0 +1,119 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+const (
+	// writerMaxLine is the longest line written as one event; longer lines
+	// are split. Event messages are limited to 31839 characters.
+	writerMaxLine = 16 << 10
+	// writerRate is the number of events an event log writer produces per
+	// second at most. Lines beyond it are dropped and counted.
+	writerRate = 20
+)
+
+// EventLogWriter returns a writer that writes each line written to it as an
+// event of the given severity from the event source name, for example the
+// name of the service. It can be passed to log.SetOutput or used as the
+// stdout or stderr of a child process. The writer produces at most 20
+// events per second; lines beyond that are dropped, and the number dropped
+// is appended to the next event written. The writer also implements
+// io.Closer, which writes any unterminated last line and closes the event
+// log. Writes fail if the event source cannot be opened.
+func EventLogWriter(name string, level Severity) io.Writer {
+	return &eventLogWriter{name: name, level: level}
+}
+
+type eventLogWriter struct {
+	name  string
+	level Severity
+
+	mu         sync.Mutex
+	log        *eventlog.Log
+	buf        []byte
+	window     time.Time
+	count      int
+	suppressed int
+}
+
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.log == nil {
+		l, err := eventlog.Open(w.name)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open event log: %w", err)
+		}
+		w.log = l
+	}
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			if len(w.buf) < writerMaxLine {
+				break
+			}
+			i = writerMaxLine
+		}
+		line := bytes.TrimRight(w.buf[:i], "\r")
+		err := w.emit(string(line))
+		w.buf = w.buf[min(i+1, len(w.buf)):]
+		if err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// emit writes line as an event unless the rate limit is reached.
+func (w *eventLogWriter) emit(line string) error {
+	if line == "" {
+		return nil
+	}
+	now := time.Now()
+	if now.Sub(w.window) >= time.Second {
+		w.window = now
+		w.count = 0
+	}
+	if w.count >= writerRate {
+		w.suppressed++
+		return nil
+	}
+	w.count++
+
+	if w.suppressed > 0 {
+		line = fmt.Sprintf("%s\n(%d earlier lines suppressed)", line, w.suppressed)
+		w.suppressed = 0
+	}
+	return logAt(w.log, w.level, 1, line)
+}
+
+func (w *eventLogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.log == nil {
+		return nil
+	}
+	var err error
+	if len(w.buf) > 0 {
+		err = w.emit(string(bytes.TrimRight(w.buf, "\r")))
+		w.buf = nil
+	}
+	if cerr := w.log.Close(); err == nil {
+		err = cerr
+	}
+	w.log = nil
+	return err
+}
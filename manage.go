@@ -0,0 +1,309 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type manageConfig struct {
+	serviceOptions []ServiceOption
+	runOptions     []RunOption
+	out            io.Writer
+}
+
+// ManageServiceOptions sets options the install command installs the
+// service with, such as WithFirewallRule or Hardened. They are applied
+// after the install flags and take precedence over them.
+func ManageServiceOptions(options ...ServiceOption) ManageOption {
+	return func(c *manageConfig) {
+		c.serviceOptions = append(c.serviceOptions, options...)
+	}
+}
+
+// ManageRunOptions sets the options the run command passes to
+// RunAsService.
+func ManageRunOptions(options ...RunOption) ManageOption {
+	return func(c *manageConfig) {
+		c.runOptions = append(c.runOptions, options...)
+	}
+}
+
+// ManageOutput sets where Manage writes usage, status and confirmation
+// messages, standard output by default.
+func ManageOutput(w io.Writer) ManageOption {
+	return func(c *manageConfig) {
+		c.out = w
+	}
+}
+
+const manageUsage = `usage: %[1]s <command> [flags]
+
+commands:
+  install [flags] [-- args]  install as the %[2]s service, which runs with args
+  uninstall                  stop and remove the service
+  start [args]               start the service and wait for it to run
+  stop                       stop the service
+  restart                    restart the service
+  status                     print the state of the service
+  run                        run the service, in debug mode on a console
+
+Run "%[1]s install -h" for the install flags.
+`
+
+// Manage implements the standard command line of a service binary, so
+// that main can be reduced to:
+//
+//	if err := winsvc.Manage("MyService", svc, os.Args[1:]); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// args start with a command: install installs the running executable as
+// the named service, with flags for its display name, description,
+// account, start type, dependencies and recovery actions; uninstall stops
+// and removes it; start, stop and restart control it and wait for it to
+// get there; status prints its state; and run runs s with RunAsService,
+// which is what the installed service does. Without a command, s is run
+// too, in debug mode unless the process was started by the service control
+// manager. Arguments after "--" on the install command line are passed to
+// the service in addition to run.
+func Manage(name string, s Service, args []string, options ...ManageOption) error {
+	cfg := newManageConfig(options)
+	if len(args) == 0 {
+		return RunAsService(name, s.Start, s.Stop, !InServiceMode(), cfg.runOptions...)
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "install":
+		return manageInstall(name, args, &cfg)
+	case "run":
+		return RunAsService(name, s.Start, s.Stop, !InServiceMode(), cfg.runOptions...)
+	case "start":
+		return manageCommand(name, command, args, &cfg)
+	case "uninstall", "remove", "stop", "restart", "status":
+		if len(args) > 0 {
+			return fmt.Errorf("%s takes no arguments", command)
+		}
+		return manageCommand(name, command, nil, &cfg)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprintf(cfg.out, manageUsage, filepath.Base(os.Args[0]), name)
+		return nil
+	}
+	fmt.Fprintf(cfg.out, manageUsage, filepath.Base(os.Args[0]), name)
+	return fmt.Errorf("unknown command %q", command)
+}
+
+// newManageConfig applies options to the defaults of Manage.
+func newManageConfig(options []ManageOption) manageConfig {
+	cfg := manageConfig{out: os.Stdout}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// manageCommand runs a command of Manage other than install and run.
+func manageCommand(name, command string, args []string, cfg *manageConfig) error {
+	return withDefaults(func(ctx context.Context, m *Manager) error {
+		return m.manageCommand(ctx, name, command, args, cfg)
+	})
+}
+
+func (m *Manager) manageCommand(ctx context.Context, name, command string, args []string, cfg *manageConfig) error {
+	switch command {
+	case "uninstall", "remove":
+		state, err := m.QueryState(name)
+		if err != nil {
+			return err
+		}
+		if state != StateStopped {
+			if err := m.Stop(ctx, name); err != nil {
+				return err
+			}
+		}
+		if err := m.Remove(ctx, name); err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg.out, "%s service removed\n", name)
+	case "start":
+		latency, err := m.StartWait(ctx, name, args...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg.out, "%s service running after %v\n", name, latency.Round(time.Millisecond))
+	case "stop":
+		if err := m.Stop(ctx, name); err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg.out, "%s service stopped\n", name)
+	case "restart":
+		if err := m.Restart(ctx, name); err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg.out, "%s service restarted\n", name)
+	case "status":
+		state, err := m.QueryState(name)
+		if errors.Is(err, ErrServiceNotFound) {
+			fmt.Fprintf(cfg.out, "%s service is not installed\n", name)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg.out, "%s service is %v\n", name, state)
+	}
+	return nil
+}
+
+// installFlags holds the flags of the install command.
+type installFlags struct {
+	displayName   string
+	description   string
+	account       string
+	password      string
+	credential    string
+	startType     string
+	dependencies  string
+	recovery      string
+	recoveryDelay time.Duration
+	recoveryReset time.Duration
+
+	// set holds the names of the flags given on the command line, if
+	// known.
+	set map[string]bool
+}
+
+// register defines the install flags on fs.
+func (f *installFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.displayName, "display-name", "", "display name of the service")
+	fs.StringVar(&f.description, "description", "", "description of the service")
+	fs.StringVar(&f.account, "account", "", `account to run as: LocalSystem (default), LocalService, NetworkService, "virtual" for NT SERVICE\<name>, or a user such as DOMAIN\user`)
+	fs.StringVar(&f.password, "password", "", "password of a user account")
+	fs.StringVar(&f.credential, "credential", "", "Credential Manager target holding the password of a user account, instead of -password")
+	fs.StringVar(&f.startType, "start", "", "start type: auto, delayed, manual or disabled; auto if not set")
+	fs.StringVar(&f.dependencies, "depends", "", "comma-separated services to start first")
+	fs.StringVar(&f.recovery, "recovery", "", "comma-separated failure actions, each restart, reboot or none, such as restart,restart,none")
+	fs.DurationVar(&f.recoveryDelay, "recovery-delay", 10*time.Second, "delay before each failure action")
+	fs.DurationVar(&f.recoveryReset, "recovery-reset", 24*time.Hour, "time without failures after which the failure count is reset")
+}
+
+// parsed records which flags of fs, on which f was registered, were given
+// on the command line.
+func (f *installFlags) parsed(fs *flag.FlagSet) {
+	f.set = map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) {
+		f.set[fl.Name] = true
+	})
+}
+
+// options returns the service options selected by the flags. The start
+// type is only set if -start was given, so that one set by the program
+// applies otherwise.
+func (f *installFlags) options() ([]ServiceOption, error) {
+	var options []ServiceOption
+	if f.displayName != "" {
+		options = append(options, DisplayName(f.displayName))
+	}
+	if f.description != "" {
+		options = append(options, Description(f.description))
+	}
+
+	switch start := strings.ToLower(f.startType); {
+	case start == "" && !f.set["start"]:
+	case start == "auto" || start == "automatic":
+		options = append(options, AutoStart())
+	case start == "delayed":
+		options = append(options, AutoDelayStart())
+	case start == "manual" || start == "demand":
+		options = append(options, OnDemandStart())
+	case start == "disabled":
+		options = append(options, DisabledStart())
+	default:
+		return nil, fmt.Errorf("unknown start type %q", f.startType)
+	}
+
+	switch account := strings.ToLower(f.account); {
+	case account == "" || account == "localsystem":
+	case account == "virtual":
+		options = append(options, VirtualAccount())
+	case account == "localservice" || account == "networkservice":
+		options = append(options, RunAsUser(`NT AUTHORITY\`+f.account, nil))
+	case f.credential != "":
+		options = append(options, RunAsUserFromCredential(f.account, f.credential))
+	default:
+		options = append(options, RunAsUser(f.account, []byte(f.password)))
+	}
+
+	if f.dependencies != "" {
+		options = append(options, Dependencies(splitList(f.dependencies)...))
+	}
+	if f.recovery != "" {
+		c := RecoveryConfig{ResetPeriod: f.recoveryReset}
+		for _, name := range splitList(f.recovery) {
+			var t RecoveryActionType
+			if err := t.UnmarshalText([]byte(name)); err != nil {
+				return nil, err
+			}
+			c.Actions = append(c.Actions, RecoveryAction{Type: t, Delay: f.recoveryDelay})
+		}
+		options = append(options, Recovery(c))
+	}
+	return options, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// manageInstall runs the install command of Manage.
+func manageInstall(name string, args []string, cfg *manageConfig) error {
+	var f installFlags
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0])+" install", flag.ContinueOnError)
+	fs.SetOutput(cfg.out)
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	f.parsed(fs)
+	return installManaged(name, &f, append([]string{"run"}, fs.Args()...), cfg)
+}
+
+// installManaged installs the running executable as the named service,
+// which runs it with runArgs.
+func installManaged(name string, f *installFlags, runArgs []string, cfg *manageConfig) error {
+	options, err := f.options()
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	options = append(options, cfg.serviceOptions...)
+	err = withDefaults(func(ctx context.Context, m *Manager) error {
+		return m.Install(ctx, exe, name, runArgs, options...)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.out, "%s service installed\n", name)
+	return nil
+}
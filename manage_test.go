@@ -0,0 +1,44 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestInstallFlagsStartType(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		want    StartType
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{[]string{"-start", "manual"}, StartTypeManual, false},
+		{[]string{"-start", "delayed"}, StartTypeAutomatic, false},
+		{[]string{"-start="}, 0, true},
+		{[]string{"-start", "sometimes"}, 0, true},
+	} {
+		var f installFlags
+		fs := flag.NewFlagSet("install", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		f.register(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		f.parsed(fs)
+		options, err := f.options()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: options() error = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		var config serviceConfig
+		for _, option := range options {
+			option(&config)
+		}
+		if got := StartType(config.StartType); got != tt.want {
+			t.Errorf("%q: start type = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// scmAccess is the access requested on the service control manager. It is
+// enough to open and enumerate services and is granted to every
+// authenticated user; operations that need more open a separate handle.
+const scmAccess = windows.SC_MANAGER_CONNECT | windows.SC_MANAGER_ENUMERATE_SERVICE
+
+// Manager is a connection to the service control manager. Callers that
+// perform many operations can reuse one Manager instead of paying for the
+// connection every package-level function opens and closes. A Manager is
+// safe for concurrent use.
+type Manager struct {
+	m        *mgr.Mgr
+	host     string
+	session  *remoteSession
+	readOnly bool
+	observer Observer
+	// wait is the policy of the waits of the Manager's methods.
+	wait WaitPolicy
+}
+
+// Connect connects to the service control manager of the local machine.
+func Connect(options ...ConnectOption) (*Manager, error) {
+	return ConnectCtx(context.Background(), options...)
+}
+
+// ConnectCtx is like Connect but honors ctx cancellation.
+func ConnectCtx(ctx context.Context, options ...ConnectOption) (*Manager, error) {
+	return ConnectRemoteCtx(ctx, "", options...)
+}
+
+// Host returns the machine m is connected to, or an empty string for the
+// local machine.
+func (m *Manager) Host() string {
+	return m.host
+}
+
+// Disconnect closes the connection to the service control manager and
+// ends the session established for ConnectAs credentials, if any.
+func (m *Manager) Disconnect() error {
+	err := m.m.Disconnect()
+	if m.session != nil {
+		err = errors.Join(err, m.session.close())
+	}
+	return err
+}
+
+// openService opens the named service with only the given access rights,
+// so that operations which merely read state work without elevation.
+func (m *Manager) openService(name string, access uint32) (*mgr.Service, error) {
+	if err := m.checkAccess(name, access); err != nil {
+		return nil, err
+	}
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.OpenService(m.m.Handle, n, access)
+	if err != nil {
+		return nil, fmt.Errorf("could not access service %s: %w", name, scmError(err))
+	}
+	return &mgr.Service{Name: name, Handle: h}, nil
+}
+
+// reopen opens another connection to the same service control manager with
+// the given access rights. The caller must disconnect it.
+func (m *Manager) reopen(access uint32) (*mgr.Mgr, error) {
+	if m.readOnly && access&^scmAccess != 0 {
+		return nil, &ReadOnlyError{Access: access}
+	}
+	return connect(context.Background(), m.host, access)
+}
+
+// withRegistry calls fn with the HKEY_LOCAL_MACHINE key of the machine m is
+// connected to. Remote machines must run the Remote Registry service.
+func (m *Manager) withRegistry(fn func(root registry.Key) error) error {
+	if m.host == "" {
+		return fn(registry.LOCAL_MACHINE)
+	}
+	root, err := registry.OpenRemoteKey(m.host, registry.LOCAL_MACHINE)
+	if err != nil {
+		return fmt.Errorf("failed to connect to registry of %s: %w", m.host, scmError(err))
+	}
+	defer root.Close()
+	return fn(root)
+}
+
+// localOnly returns an error if m is connected to a remote machine, for
+// operations that can only act on the local one.
+func (m *Manager) localOnly(what string) error {
+	if m.host != "" {
+		return fmt.Errorf("%s is not supported on remote host %s", what, m.host)
+	}
+	return nil
+}
+
+// controlAccess returns the service access right needed to send c.
+func controlAccess(c svc.Cmd) uint32 {
+	switch c {
+	case svc.Stop:
+		return windows.SERVICE_STOP
+	case svc.Interrogate:
+		return windows.SERVICE_INTERROGATE
+	case svc.Pause, svc.Continue, svc.ParamChange, svc.NetBindAdd,
+		svc.NetBindRemove, svc.NetBindEnable, svc.NetBindDisable:
+		return windows.SERVICE_PAUSE_CONTINUE
+	default:
+		return windows.SERVICE_USER_DEFINED_CONTROL
+	}
+}
+
+// withManager connects to the service control manager, calls fn, and
+// disconnects again. It backs the package-level functions.
+func withManager(ctx context.Context, fn func(*Manager) error) error {
+	m, err := ConnectCtx(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return fn(m)
+}
+
+// connect connects to the service control manager of host, or of the local
+// machine if host is empty, with the given access rights, giving up when
+// ctx is done. A connection that completes after ctx is done is closed.
+func connect(ctx context.Context, host string, access uint32) (*mgr.Mgr, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutError(err)
+	}
+	var machine *uint16
+	if host != "" {
+		var err error
+		machine, err = windows.UTF16PtrFromString(host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	type result struct {
+		m   *mgr.Mgr
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		h, err := windows.OpenSCManager(machine, nil, access)
+		if err != nil {
+			done <- result{nil, err}
+			return
+		}
+		done <- result{&mgr.Mgr{Handle: h}, nil}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, fmt.Errorf("failed to connect to service manager: %w", scmError(r.err))
+		}
+		return r.m, nil
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.err == nil {
+				r.m.Disconnect()
+			}
+		}()
+		return nil, timeoutError(ctx.Err())
+	}
+}
@@ -0,0 +1,60 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// WithMitigations makes RunAsService apply the given process mitigation
+// policies at startup. If a policy cannot be applied, RunAsService fails.
+func WithMitigations(policies MitigationPolicy) RunOption {
+	return func(c *runConfig) {
+		c.mitigations |= policies
+	}
+}
+
+// PROCESS_MITIGATION_POLICY values.
+const (
+	processDEPPolicy                   = 0
+	processDynamicCodePolicy           = 2
+	processExtensionPointDisablePolicy = 6
+	processImageLoadPolicy             = 10
+)
+
+// applyMitigations applies policies to the current process.
+func applyMitigations(policies MitigationPolicy) error {
+	if policies&MitigateDEP != 0 && unsafe.Sizeof(uintptr(0)) == 4 {
+		// PROCESS_MITIGATION_DEP_POLICY: Enable, with Permanent set.
+		p := struct {
+			Flags     uint32
+			Permanent uint8
+		}{Flags: 1, Permanent: 1}
+		if err := setProcessMitigationPolicy(processDEPPolicy, unsafe.Pointer(&p), unsafe.Sizeof(p)); err != nil {
+			return fmt.Errorf("failed to enable DEP: %w", err)
+		}
+	}
+	if policies&MitigateDynamicCode != 0 {
+		// ProhibitDynamicCode.
+		flags := uint32(1)
+		if err := setProcessMitigationPolicy(processDynamicCodePolicy, unsafe.Pointer(&flags), unsafe.Sizeof(flags)); err != nil {
+			return fmt.Errorf("failed to prohibit dynamic code: %w", err)
+		}
+	}
+	if policies&MitigateImageLoad != 0 {
+		// NoRemoteImages, NoLowMandatoryLabelImages and PreferSystem32Images.
+		flags := uint32(1 | 2 | 4)
+		if err := setProcessMitigationPolicy(processImageLoadPolicy, unsafe.Pointer(&flags), unsafe.Sizeof(flags)); err != nil {
+			return fmt.Errorf("failed to restrict image loads: %w", err)
+		}
+	}
+	if policies&MitigateExtensionPoints != 0 {
+		// DisableExtensionPoints.
+		flags := uint32(1)
+		if err := setProcessMitigationPolicy(processExtensionPointDisablePolicy, unsafe.Pointer(&flags), unsafe.Sizeof(flags)); err != nil {
+			return fmt.Errorf("failed to disable extension points: %w", err)
+		}
+	}
+	return nil
+}
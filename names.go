@@ -0,0 +1,133 @@
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+)
+
+var controlNames = map[uint32]string{
+	0x1:  "SERVICE_CONTROL_STOP",
+	0x2:  "SERVICE_CONTROL_PAUSE",
+	0x3:  "SERVICE_CONTROL_CONTINUE",
+	0x4:  "SERVICE_CONTROL_INTERROGATE",
+	0x5:  "SERVICE_CONTROL_SHUTDOWN",
+	0x6:  "SERVICE_CONTROL_PARAMCHANGE",
+	0x7:  "SERVICE_CONTROL_NETBINDADD",
+	0x8:  "SERVICE_CONTROL_NETBINDREMOVE",
+	0x9:  "SERVICE_CONTROL_NETBINDENABLE",
+	0xa:  "SERVICE_CONTROL_NETBINDDISABLE",
+	0xb:  "SERVICE_CONTROL_DEVICEEVENT",
+	0xc:  "SERVICE_CONTROL_HARDWAREPROFILECHANGE",
+	0xd:  "SERVICE_CONTROL_POWEREVENT",
+	0xe:  "SERVICE_CONTROL_SESSIONCHANGE",
+	0xf:  "SERVICE_CONTROL_PRESHUTDOWN",
+	0x10: "SERVICE_CONTROL_TIMECHANGE",
+	0x20: "SERVICE_CONTROL_TRIGGEREVENT",
+	0x60: "SERVICE_CONTROL_LOWRESOURCES",
+	0x61: "SERVICE_CONTROL_SYSTEMLOWRESOURCES",
+}
+
+// ControlName returns the SDK name of a control code, such as
+// "SERVICE_CONTROL_PRESHUTDOWN". Custom controls, 128 to 255, are named
+// "SERVICE_CONTROL_USER(n)" and other unknown codes "SERVICE_CONTROL(n)".
+func ControlName(c uint32) string {
+	if name, ok := controlNames[c]; ok {
+		return name
+	}
+	if c >= 128 && c <= 255 {
+		return fmt.Sprintf("SERVICE_CONTROL_USER(%d)", c)
+	}
+	return fmt.Sprintf("SERVICE_CONTROL(%d)", c)
+}
+
+var stateSDKNames = map[State]string{
+	StateStopped:         "SERVICE_STOPPED",
+	StateStartPending:    "SERVICE_START_PENDING",
+	StateStopPending:     "SERVICE_STOP_PENDING",
+	StateRunning:         "SERVICE_RUNNING",
+	StateContinuePending: "SERVICE_CONTINUE_PENDING",
+	StatePausePending:    "SERVICE_PAUSE_PENDING",
+	StatePaused:          "SERVICE_PAUSED",
+}
+
+// StateName returns the SDK name of a service state, such as
+// "SERVICE_RUNNING". State.String returns the shorter "Running".
+func StateName(s State) string {
+	if name, ok := stateSDKNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("SERVICE_STATE(%d)", uint32(s))
+}
+
+var startTypeSDKNames = map[StartType]string{
+	StartTypeBoot:      "SERVICE_BOOT_START",
+	StartTypeSystem:    "SERVICE_SYSTEM_START",
+	StartTypeAutomatic: "SERVICE_AUTO_START",
+	StartTypeManual:    "SERVICE_DEMAND_START",
+	StartTypeDisabled:  "SERVICE_DISABLED",
+}
+
+// StartTypeName returns the SDK name of a start type, such as
+// "SERVICE_AUTO_START", as found in mgr.Config.StartType.
+// StartType.String returns the shorter "Automatic".
+func StartTypeName(t uint32) string {
+	if name, ok := startTypeSDKNames[StartType(t)]; ok {
+		return name
+	}
+	return fmt.Sprintf("SERVICE_START_TYPE(%d)", t)
+}
+
+var errorNames = map[errno]string{
+	errorFileNotFound:                   "ERROR_FILE_NOT_FOUND",
+	errorAccessDenied:                   "ERROR_ACCESS_DENIED",
+	errorBadExeFormat:                   "ERROR_BAD_EXE_FORMAT",
+	errorDependentServicesRunning:       "ERROR_DEPENDENT_SERVICES_RUNNING",
+	errorInvalidServiceControl:          "ERROR_INVALID_SERVICE_CONTROL",
+	errorServiceRequestTimeout:          "ERROR_SERVICE_REQUEST_TIMEOUT",
+	errorServiceNoThread:                "ERROR_SERVICE_NO_THREAD",
+	errorServiceDatabaseLocked:          "ERROR_SERVICE_DATABASE_LOCKED",
+	errorServiceAlreadyRunning:          "ERROR_SERVICE_ALREADY_RUNNING",
+	errorInvalidServiceAccount:          "ERROR_INVALID_SERVICE_ACCOUNT",
+	errorServiceDisabled:                "ERROR_SERVICE_DISABLED",
+	errorCircularDependency:             "ERROR_CIRCULAR_DEPENDENCY",
+	errorServiceDoesNotExist:            "ERROR_SERVICE_DOES_NOT_EXIST",
+	errorServiceCannotAcceptCtrl:        "ERROR_SERVICE_CANNOT_ACCEPT_CTRL",
+	errorServiceNotActive:               "ERROR_SERVICE_NOT_ACTIVE",
+	errorFailedServiceControllerConnect: "ERROR_FAILED_SERVICE_CONTROLLER_CONNECT",
+	errorExceptionInService:             "ERROR_EXCEPTION_IN_SERVICE",
+	errorServiceSpecificError:           "ERROR_SERVICE_SPECIFIC_ERROR",
+	errorProcessAborted:                 "ERROR_PROCESS_ABORTED",
+	errorServiceDependencyFail:          "ERROR_SERVICE_DEPENDENCY_FAIL",
+	errorServiceLogonFailed:             "ERROR_SERVICE_LOGON_FAILED",
+	errorServiceStartHang:               "ERROR_SERVICE_START_HANG",
+	errorInvalidServiceLock:             "ERROR_INVALID_SERVICE_LOCK",
+	errorServiceMarkedForDelete:         "ERROR_SERVICE_MARKED_FOR_DELETE",
+	errorServiceExists:                  "ERROR_SERVICE_EXISTS",
+	errorServiceDependencyDeleted:       "ERROR_SERVICE_DEPENDENCY_DELETED",
+	errorServiceNeverStarted:            "ERROR_SERVICE_NEVER_STARTED",
+	errorDuplicateServiceName:           "ERROR_DUPLICATE_SERVICE_NAME",
+	errorDifferentServiceAccount:        "ERROR_DIFFERENT_SERVICE_ACCOUNT",
+	errorShutdownInProgress:             "ERROR_SHUTDOWN_IN_PROGRESS",
+	errorLogonTypeNotGranted:            "ERROR_LOGON_TYPE_NOT_GRANTED",
+}
+
+// ErrorName returns the SDK name of a Win32 error code commonly returned
+// by the service control manager, such as "ERROR_SERVICE_DOES_NOT_EXIST"
+// for 1060, or "" for other codes.
+func ErrorName(code uint32) string {
+	return errorNames[errno(code)]
+}
+
+// DescribeError returns the Win32 error err wraps as its SDK name, code
+// and system message, such as "ERROR_SERVICE_NOT_ACTIVE (1062): The
+// service has not been started.", or err's message if it wraps none.
+func DescribeError(err error) string {
+	var code errno
+	if !errors.As(err, &code) {
+		return err.Error()
+	}
+	if name := ErrorName(uint32(code)); name != "" {
+		return fmt.Sprintf("%s (%d): %s", name, uint32(code), code.Error())
+	}
+	return fmt.Sprintf("%d: %s", uint32(code), code.Error())
+}
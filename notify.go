@@ -0,0 +1,142 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+var (
+	notifyCallbackOnce sync.Once
+	notifyCallback     uintptr
+)
+
+// serviceNotifyCallback returns the callback used for every SERVICE_NOTIFY.
+// The callback does nothing: by the time it runs the system has already
+// filled in the SERVICE_NOTIFY, and the waiting thread reads it once its
+// alertable wait returns.
+func serviceNotifyCallback() uintptr {
+	notifyCallbackOnce.Do(func() {
+		notifyCallback = windows.NewCallback(func(notify uintptr) uintptr {
+			return 0
+		})
+	})
+	return notifyCallback
+}
+
+// stateMask returns the SERVICE_NOTIFY_* bit for a service state.
+func stateMask(state svc.State) uint32 {
+	return 1 << (uint32(state) - 1)
+}
+
+// allStatesMask selects notifications for every service state.
+const allStatesMask = windows.SERVICE_NOTIFY_STOPPED | windows.SERVICE_NOTIFY_START_PENDING |
+	windows.SERVICE_NOTIFY_STOP_PENDING | windows.SERVICE_NOTIFY_RUNNING |
+	windows.SERVICE_NOTIFY_CONTINUE_PENDING | windows.SERVICE_NOTIFY_PAUSE_PENDING |
+	windows.SERVICE_NOTIFY_PAUSED
+
+// waitNotify blocks until the named service on host is in one of the
+// states selected by mask. It returns immediately if the service already is.
+func waitNotify(ctx context.Context, host, name string, mask uint32) error {
+	// Notifications are delivered as APCs to the registering thread.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	scm, h, err := openNotifyHandle(host, name)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	return notifyLoop(ctx, h, mask, func(*windows.SERVICE_NOTIFY) uint32 {
+		return 0
+	})
+}
+
+// openNotifyHandle opens the named service on host, or on the local machine
+// if host is empty, with just enough access to register for status change
+// notifications. The caller must close both returned handles.
+func openNotifyHandle(host, name string) (scm, h windows.Handle, err error) {
+	var machine *uint16
+	if host != "" {
+		machine, err = windows.UTF16PtrFromString(host)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	scm, err = windows.OpenSCManager(machine, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to connect to service manager: %w", scmError(err))
+	}
+
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		windows.CloseServiceHandle(scm)
+		return 0, 0, err
+	}
+	h, err = windows.OpenService(scm, n, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		windows.CloseServiceHandle(scm)
+		return 0, 0, fmt.Errorf("could not access service: %w", scmError(err))
+	}
+
+	return scm, h, nil
+}
+
+// notifyLoop repeatedly calls NotifyServiceStatusChange on h and waits for
+// the notification, passing each one to fn. fn returns the mask for the
+// next registration, or 0 to stop. notifyLoop must be called on a locked
+// OS thread, and it closes h before returning.
+func notifyLoop(ctx context.Context, h windows.Handle, mask uint32, fn func(*windows.SERVICE_NOTIFY) uint32) error {
+	cancel, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseServiceHandle(h)
+		return err
+	}
+	defer windows.CloseHandle(cancel)
+	stop := context.AfterFunc(ctx, func() {
+		windows.SetEvent(cancel)
+	})
+	defer stop()
+
+	notify := new(windows.SERVICE_NOTIFY)
+	defer func() {
+		// Closing the handle cancels a pending notification; drain any
+		// APC that was already queued while notify is still alive.
+		windows.CloseServiceHandle(h)
+		windows.SleepEx(0, true)
+		runtime.KeepAlive(notify)
+	}()
+
+	for {
+		*notify = windows.SERVICE_NOTIFY{
+			Version:        windows.SERVICE_NOTIFY_STATUS_CHANGE,
+			NotifyCallback: serviceNotifyCallback(),
+		}
+		err := windows.NotifyServiceStatusChange(h, mask, notify)
+		if err != nil {
+			return fmt.Errorf("could not register for status notifications: %w", err)
+		}
+
+		event, err := waitForSingleObjectEx(cancel, windows.INFINITE, true)
+		switch event {
+		case windows.WAIT_IO_COMPLETION:
+			if notify.NotificationStatus != 0 {
+				return fmt.Errorf("status notification failed: %w", windows.Errno(notify.NotificationStatus))
+			}
+			if mask = fn(notify); mask == 0 {
+				return nil
+			}
+		case windows.WAIT_OBJECT_0:
+			return ctx.Err()
+		default:
+			return fmt.Errorf("failed waiting for status notification: %w", err)
+		}
+	}
+}
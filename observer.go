@@ -0,0 +1,132 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"sync"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+var (
+	observerMu sync.RWMutex
+	observer   Observer
+)
+
+// SetObserver makes o observe every operation performed from now on,
+// through any Manager. A nil o removes it, which is the default. Use
+// MultiObserver to register several observers.
+func SetObserver(o Observer) {
+	observerMu.Lock()
+	observer = o
+	observerMu.Unlock()
+}
+
+// SetObserver makes o observe the operations performed through m, in
+// addition to the observer set with the package-level SetObserver. It
+// must not be called while m is in use.
+func (m *Manager) SetObserver(o Observer) {
+	m.observer = o
+}
+
+func globalObserver() Observer {
+	observerMu.RLock()
+	defer observerMu.RUnlock()
+	return observer
+}
+
+// controlOps maps the control operations to the state they leave the
+// service in, or zero if it is not known. A start that does not wait only
+// leaves the service start pending; the ones that wait, such as StartWait,
+// report the state they observed instead.
+var controlOps = map[string]State{
+	"start":      StateStartPending,
+	"start-tree": StateRunning,
+	"restart":    StateRunning,
+	"continue":   StateRunning,
+	"stop":       StateStopped,
+	"stop-tree":  StateStopped,
+	"stop-force": StateStopped,
+	"terminate":  StateStopped,
+	"pause":      StatePaused,
+	"control":    0,
+}
+
+// observe notifies the observers of an operation on the named service,
+// which left it in state if it is a control operation.
+func (m *Manager) observe(op, name string, params map[string]string, state State, err error) {
+	for _, o := range []Observer{globalObserver(), m.observer} {
+		if o == nil {
+			continue
+		}
+		notifyOperation(o, Operation{Name: op, Host: m.host, Service: name, Parameters: params}, state, err)
+	}
+}
+
+func notifyOperation(o Observer, op Operation, state State, err error) {
+	if err != nil {
+		o.OnError(op, err)
+		return
+	}
+	switch op.Name {
+	case "install", "deploy":
+		o.OnInstall(op)
+	case "remove":
+		o.OnRemove(op)
+	default:
+		if _, ok := controlOps[op.Name]; !ok {
+			return
+		}
+		o.OnControl(op)
+		if state != 0 {
+			o.OnStateChange(op.Service, state)
+		}
+	}
+}
+
+// reportState traces a state the running service reported and notifies
+// the global observer.
+func (s *winService) reportState(state svc.State) {
+	traceState(s.name, state)
+	if o := globalObserver(); o != nil {
+		o.OnStateChange(s.name, State(state))
+	}
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) OnInstall(op Operation) {
+	for _, o := range m {
+		o.OnInstall(op)
+	}
+}
+
+func (m multiObserver) OnRemove(op Operation) {
+	for _, o := range m {
+		o.OnRemove(op)
+	}
+}
+
+func (m multiObserver) OnControl(op Operation) {
+	for _, o := range m {
+		o.OnControl(op)
+	}
+}
+
+func (m multiObserver) OnStateChange(service string, state State) {
+	for _, o := range m {
+		o.OnStateChange(service, state)
+	}
+}
+
+func (m multiObserver) OnError(op Operation, err error) {
+	for _, o := range m {
+		o.OnError(op, err)
+	}
+}
+
+// MultiObserver returns an Observer that passes every notification to each
+// of observers.
+func MultiObserver(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package winsvc
+
+import "testing"
+
+type stateObserver struct {
+	NopObserver
+	states []State
+}
+
+func (o *stateObserver) OnStateChange(service string, state State) {
+	o.states = append(o.states, state)
+}
+
+func TestNotifyOperationState(t *testing.T) {
+	for _, tt := range []struct {
+		op    string
+		state State
+		want  []State
+	}{
+		{"start", controlOps["start"], []State{StateStartPending}},
+		{"start", StateRunning, []State{StateRunning}},
+		{"stop", controlOps["stop"], []State{StateStopped}},
+		{"install", 0, nil},
+	} {
+		var o stateObserver
+		notifyOperation(&o, Operation{Name: tt.op, Service: "svc"}, tt.state, nil)
+		if len(o.states) != len(tt.want) || len(tt.want) > 0 && o.states[0] != tt.want[0] {
+			t.Errorf("%s with state %v: states = %v, want %v", tt.op, tt.state, o.states, tt.want)
+		}
+	}
+}
@@ -1,65 +1,294 @@
 package winsvc
 
-import (
-	"golang.org/x/sys/windows"
-	"golang.org/x/sys/windows/svc/mgr"
-)
+type ServiceOption func(*serviceConfig)
+
+type serviceConfig struct {
+	mgrConfig
+	password        func() ([]byte, error)
+	firewallRules   []FirewallRule
+	verifySignature bool
+	publishers      []string
+
+	virtualAccount     bool
+	requiredPrivileges []string
+
+	counterSets []CounterSet
+
+	recovery *RecoveryConfig
+	triggers *[]ServiceTrigger
+}
+
+// mgrConfig has the fields of mgr.Config, which only exists on Windows, so
+// that options build on every platform. It converts to mgr.Config.
+type mgrConfig struct {
+	ServiceType      uint32
+	StartType        uint32
+	ErrorControl     uint32
+	BinaryPathName   string
+	LoadOrderGroup   string
+	TagId            uint32
+	Dependencies     []string
+	ServiceStartName string
+	DisplayName      string
+	Password         string
+	Description      string
+	SidType          uint32
+	DelayedAutoStart bool
+}
 
-type ServiceOption func(*mgr.Config)
+// Service SID types, the SERVICE_SID_TYPE_* values of mgr.Config.SidType.
+const (
+	sidTypeNone         = 0
+	sidTypeUnrestricted = 1
+	sidTypeRestricted   = 3
+)
 
 func DisplayName(displayName string) ServiceOption {
-	return func(config *mgr.Config) {
+	return func(config *serviceConfig) {
 		config.DisplayName = displayName
 	}
 }
 
 func Description(description string) ServiceOption {
-	return func(config *mgr.Config) {
+	return func(config *serviceConfig) {
 		config.Description = description
 	}
 }
 
 func OnBootStart() ServiceOption {
-	return func(config *mgr.Config) {
-		config.StartType = windows.SERVICE_BOOT_START
+	return func(config *serviceConfig) {
+		config.StartType = uint32(StartTypeBoot)
 	}
 }
 
 func OnSystemStart() ServiceOption {
-	return func(config *mgr.Config) {
-		config.StartType = windows.SERVICE_SYSTEM_START
+	return func(config *serviceConfig) {
+		config.StartType = uint32(StartTypeSystem)
 	}
 }
 
 func AutoStart() ServiceOption {
-	return func(config *mgr.Config) {
-		config.StartType = windows.SERVICE_AUTO_START
+	return func(config *serviceConfig) {
+		config.StartType = uint32(StartTypeAutomatic)
 	}
 }
 
 func AutoDelayStart() ServiceOption {
-	return func(config *mgr.Config) {
-		config.StartType = windows.SERVICE_AUTO_START
+	return func(config *serviceConfig) {
+		config.StartType = uint32(StartTypeAutomatic)
 		config.DelayedAutoStart = true
 	}
 }
 
 func OnDemandStart() ServiceOption {
-	return func(config *mgr.Config) {
-		config.StartType = windows.SERVICE_DEMAND_START
+	return func(config *serviceConfig) {
+		config.StartType = uint32(StartTypeManual)
 	}
 }
 
 func DisabledStart() ServiceOption {
-	return func(config *mgr.Config) {
-		config.StartType = windows.SERVICE_DISABLED
+	return func(config *serviceConfig) {
+		config.StartType = uint32(StartTypeDisabled)
 	}
 }
 
 func Dependencies(serviceName ...string) ServiceOption {
-	return func(config *mgr.Config) {
+	return func(config *serviceConfig) {
 		for _, svcName := range serviceName {
 			config.Dependencies = append(config.Dependencies, svcName)
 		}
 	}
 }
+
+func UnrestrictedSID() ServiceOption {
+	return func(config *serviceConfig) {
+		config.SidType = sidTypeUnrestricted
+	}
+}
+
+func WriteRestricted() ServiceOption {
+	return func(config *serviceConfig) {
+		config.SidType = sidTypeRestricted
+	}
+}
+
+// hardenedPrivileges are the only privileges a hardened service keeps.
+var hardenedPrivileges = []string{"SeChangeNotifyPrivilege"}
+
+// VirtualAccount runs the service as its virtual account, NT SERVICE\<name>,
+// which has no password and only the rights granted to the service SID.
+func VirtualAccount() ServiceOption {
+	return func(config *serviceConfig) {
+		config.virtualAccount = true
+		config.ServiceStartName = ""
+		config.Password = ""
+		config.password = nil
+	}
+}
+
+// RequiredPrivileges limits the privileges the service process gets to
+// privileges, such as "SeChangeNotifyPrivilege". All others are removed
+// from its token.
+func RequiredPrivileges(privileges ...string) ServiceOption {
+	return func(config *serviceConfig) {
+		config.requiredPrivileges = privileges
+	}
+}
+
+// Hardened applies an opinionated least-privilege configuration for a new
+// service: it runs as its virtual account with a write-restricted service
+// SID, and keeps only the SeChangeNotifyPrivilege. Such a service can only
+// write to objects that explicitly grant access to its SID. Options that
+// follow it may relax individual settings. Pass NoPauseContinue to
+// RunAsService to also stop it from accepting pause requests, and use
+// GetHardeningReport to see what is in effect.
+func Hardened() ServiceOption {
+	return func(config *serviceConfig) {
+		VirtualAccount()(config)
+		WriteRestricted()(config)
+		RequiredPrivileges(hardenedPrivileges...)(config)
+	}
+}
+
+func virtualAccountName(name string) string {
+	return `NT SERVICE\` + name
+}
+
+// RunAsUser runs the service as account, such as `CONTOSO\svc-app` or
+// `.\localuser`, with the given password. The password is passed to the
+// service control manager without being copied into a string, and password
+// is zeroed once the service is configured, so it should not be reused.
+// It never appears in errors or audit records.
+func RunAsUser(account string, password []byte) ServiceOption {
+	return RunAsUserFunc(account, func() ([]byte, error) {
+		return password, nil
+	})
+}
+
+// RunAsUserFunc is like RunAsUser but obtains the password by calling
+// password at install time, for example to read it from a vault, so that
+// it is held in memory only while the service is being configured. The
+// returned slice is zeroed after use.
+func RunAsUserFunc(account string, password func() ([]byte, error)) ServiceOption {
+	return func(config *serviceConfig) {
+		config.ServiceStartName = account
+		config.Password = ""
+		config.password = password
+		config.virtualAccount = false
+	}
+}
+
+// RunAsUserFromCredential is like RunAsUser but reads the password at
+// install time from the generic credential stored for target in the
+// Windows Credential Manager of the installing user, for example with
+// `cmdkey /generic:target /user:account /pass`, so that it never has to be
+// passed on a command line or held by the caller.
+func RunAsUserFromCredential(account, target string) ServiceOption {
+	return RunAsUserFunc(account, func() ([]byte, error) {
+		return readCredentialPassword(target)
+	})
+}
+
+// FirewallScope selects what a firewall rule created at install time
+// applies to.
+type FirewallScope int
+
+const (
+	// FirewallScopeService applies the rule to the service's per-service
+	// SID, so only the service's own process is matched, whatever its
+	// executable path. It is the default.
+	FirewallScopeService FirewallScope = iota
+	// FirewallScopeProgram applies the rule to the service executable's
+	// path, matching any process started from it.
+	FirewallScopeProgram
+	// FirewallScopeServiceAndProgram applies the rule only to the service
+	// running from its installed executable.
+	FirewallScopeServiceAndProgram
+)
+
+// FirewallRule describes a Windows Firewall rule allowing traffic for a
+// service.
+type FirewallRule struct {
+	Name string
+	// Outbound makes the rule apply to outbound traffic instead of inbound.
+	Outbound bool
+	// Protocol is "TCP", "UDP" or another protocol netsh accepts; empty
+	// means any.
+	Protocol string
+	// LocalPorts and RemoteAddresses restrict the rule, such as "8080,8443"
+	// or "10.0.0.0/8"; empty means any.
+	LocalPorts      string
+	RemoteAddresses string
+	Scope           FirewallScope
+}
+
+// WithFirewallRule creates rule when the service is installed, allowing the
+// traffic it describes. A rule scoped to the service gives the service an
+// unrestricted per-service SID unless a SID type is set by another option.
+// Remove the rule again with RemoveFirewallRules(rule.Name). Firewall rules
+// can only be created on the local machine.
+func WithFirewallRule(rule FirewallRule) ServiceOption {
+	return func(config *serviceConfig) {
+		if rule.Scope != FirewallScopeProgram && config.SidType == sidTypeNone {
+			config.SidType = sidTypeUnrestricted
+		}
+		config.firewallRules = append(config.firewallRules, rule)
+	}
+}
+
+// WithPerfCounters registers s for the service's executable when it is
+// installed. Pass RemovePerfCounters to RemoveService to unregister it.
+func WithPerfCounters(s CounterSet) ServiceOption {
+	return func(config *serviceConfig) {
+		config.counterSets = append(config.counterSets, s)
+	}
+}
+
+// Recovery sets the failure actions of the service: what the service
+// control manager does when it crashes or, with OnNonCrashFailures, stops
+// with a non-zero exit code. The Nth failure within ResetPeriod triggers
+// the Nth action, and later ones the last action.
+func Recovery(c RecoveryConfig) ServiceOption {
+	return func(config *serviceConfig) {
+		config.recovery = &c
+	}
+}
+
+// Triggers makes the service control manager start or stop the service
+// when the given events occur, such as a device arriving or a network
+// address appearing, replacing the triggers it had. With no triggers, the
+// service has none.
+func Triggers(triggers ...ServiceTrigger) ServiceOption {
+	return func(config *serviceConfig) {
+		config.triggers = &triggers
+	}
+}
+
+// RequireSignature makes installing the service fail, before it is
+// created, unless its executable passes VerifySignature with publishers.
+func RequireSignature(publishers ...string) ServiceOption {
+	return func(config *serviceConfig) {
+		config.verifySignature = true
+		config.publishers = publishers
+	}
+}
+
+// RunOption configures RunAsService.
+type RunOption func(*runConfig)
+
+// ConnectOption configures ConnectRemote.
+type ConnectOption func(*connectConfig)
+
+// RemoveOption configures which additional artifacts RemoveService cleans
+// up. Everything under the service's own registry key, such as its
+// Parameters key and Environment value, is deleted with the service.
+type RemoveOption func(*removeConfig)
+
+// DeployOption configures Deploy.
+type DeployOption func(*deployConfig)
+
+// ManageOption configures Manage.
+type ManageOption func(*manageConfig)
+
+// ForwardOption configures a Forwarder.
+type ForwardOption func(*forwardConfig)
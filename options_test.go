@@ -0,0 +1,34 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func TestMgrConfig(t *testing.T) {
+	var config serviceConfig
+	for _, option := range []ServiceOption{
+		DisplayName("My Service"),
+		RunAsUser(`.\svc`, []byte("secret")),
+		MgrConfig(func(c *mgr.Config) {
+			if c.DisplayName != "My Service" {
+				t.Errorf("DisplayName = %q, want the one set before", c.DisplayName)
+			}
+			c.LoadOrderGroup = "NetworkProvider"
+		}),
+	} {
+		option(&config)
+	}
+	if config.LoadOrderGroup != "NetworkProvider" {
+		t.Errorf("LoadOrderGroup = %q, want %q", config.LoadOrderGroup, "NetworkProvider")
+	}
+	if config.DisplayName != "My Service" || config.ServiceStartName != `.\svc` {
+		t.Errorf("config = %+v, lost fields set by other options", config.mgrConfig)
+	}
+	if config.password == nil {
+		t.Error("MgrConfig dropped the password of RunAsUser")
+	}
+}
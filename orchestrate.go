@@ -0,0 +1,253 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// MarshalJSON encodes Err as its message and Duration in milliseconds.
+func (r SetResult) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Name       string        `json:"name"`
+		Status     ServiceStatus `json:"status"`
+		Error      string        `json:"error,omitempty"`
+		Skipped    bool          `json:"skipped,omitempty"`
+		DurationMs int64         `json:"durationMs"`
+	}{Name: r.Name, Status: r.Status, Skipped: r.Skipped, DurationMs: r.Duration.Milliseconds()}
+	if r.Err != nil {
+		v.Error = r.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// StartServiceSet starts the named services in dependency order: a service
+// is started only after every service in the set that it depends on is
+// running. Independent services are started in parallel, up to concurrency
+// at a time. Results are returned in the order of names; an error is
+// returned only if the dependency graph cannot be built.
+func StartServiceSet(ctx context.Context, names []string, concurrency int) ([]SetResult, error) {
+	var results []SetResult
+	err := withManager(ctx, func(m *Manager) error {
+		var err error
+		results, err = m.StartSet(ctx, names, concurrency)
+		return err
+	})
+	return results, err
+}
+
+// StartSet starts the named services in dependency order, like
+// StartServiceSet.
+func (m *Manager) StartSet(ctx context.Context, names []string, concurrency int) ([]SetResult, error) {
+	results, err := m.runSet(ctx, names, concurrency, false, windows.SERVICE_START|windows.SERVICE_QUERY_STATUS, func(s *mgr.Service) error {
+		err := s.Start()
+		if err != nil && !errors.Is(err, windows.ERROR_SERVICE_ALREADY_RUNNING) {
+			return fmt.Errorf("could not start service: %w", scmError(err))
+		}
+		return m.waitRunning(ctx, s)
+	})
+	m.auditSetResults("start", StateRunning, results)
+	return results, err
+}
+
+// StopServiceSet stops the named services in reverse dependency order: a
+// service is stopped only after every service in the set that depends on it
+// is stopped. Independent services are stopped in parallel, up to
+// concurrency at a time. Results are returned in the order of names; an
+// error is returned only if the dependency graph cannot be built.
+func StopServiceSet(ctx context.Context, names []string, concurrency int) ([]SetResult, error) {
+	var results []SetResult
+	err := withManager(ctx, func(m *Manager) error {
+		var err error
+		results, err = m.StopSet(ctx, names, concurrency)
+		return err
+	})
+	return results, err
+}
+
+// StopSet stops the named services in reverse dependency order, like
+// StopServiceSet.
+func (m *Manager) StopSet(ctx context.Context, names []string, concurrency int) ([]SetResult, error) {
+	results, err := m.runSet(ctx, names, concurrency, true, windows.SERVICE_QUERY_STATUS, func(s *mgr.Service) error {
+		return m.stopAndWait(ctx, s.Name)
+	})
+	m.auditSetResults("stop", StateStopped, results)
+	return results, err
+}
+
+func (m *Manager) runSet(ctx context.Context, names []string, concurrency int, reverse bool, access uint32, op func(*mgr.Service) error) ([]SetResult, error) {
+	deps, err := m.dependencyGraph(names)
+	if err != nil {
+		return nil, err
+	}
+	if reverse {
+		deps = reverseGraph(deps)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]SetResult, len(names))
+	done := make([]chan struct{}, len(names))
+	for i, name := range names {
+		results[i].Name = name
+		done[i] = make(chan struct{})
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, d := range deps[i] {
+				<-done[d]
+				if results[d].Err != nil || results[d].Skipped {
+					results[i].Skipped = true
+					return
+				}
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			results[i].Status, results[i].Err = m.runOp(names[i], access, op)
+			results[i].Duration = time.Since(start)
+		}()
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// dependencyGraph returns, for each service in names, the indexes of the
+// services in names it depends on, directly or through services outside
+// the set.
+func (m *Manager) dependencyGraph(names []string) ([][]int, error) {
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		key := strings.ToLower(name)
+		if _, ok := index[key]; ok {
+			return nil, fmt.Errorf("service %s is listed twice", name)
+		}
+		index[key] = i
+	}
+
+	configs := map[string][]string{}
+	var direct func(name string) ([]string, error)
+	direct = func(name string) ([]string, error) {
+		key := strings.ToLower(name)
+		if d, ok := configs[key]; ok {
+			return d, nil
+		}
+		s, err := m.openService(name, windows.SERVICE_QUERY_CONFIG)
+		if err != nil {
+			return nil, err
+		}
+		config, err := s.Config()
+		s.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not query config of service %s: %w", name, err)
+		}
+		var d []string
+		for _, dep := range config.Dependencies {
+			// Group dependencies are prefixed with SC_GROUP_IDENTIFIER.
+			if !strings.HasPrefix(dep, "+") {
+				d = append(d, dep)
+			}
+		}
+		configs[key] = d
+		return d, nil
+	}
+
+	graph := make([][]int, len(names))
+	for i, name := range names {
+		visited := map[string]bool{strings.ToLower(name): true}
+		queue := []string{name}
+		for len(queue) > 0 {
+			d, err := direct(queue[0])
+			if err != nil {
+				return nil, err
+			}
+			queue = queue[1:]
+			for _, dep := range d {
+				key := strings.ToLower(dep)
+				if visited[key] {
+					continue
+				}
+				visited[key] = true
+				if j, ok := index[key]; ok {
+					graph[i] = append(graph[i], j)
+					continue
+				}
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	if cycle := findCycle(graph); cycle >= 0 {
+		return nil, fmt.Errorf("service %s is part of a dependency cycle", names[cycle])
+	}
+	return graph, nil
+}
+
+func reverseGraph(graph [][]int) [][]int {
+	reversed := make([][]int, len(graph))
+	for i, deps := range graph {
+		for _, d := range deps {
+			reversed[d] = append(reversed[d], i)
+		}
+	}
+	return reversed
+}
+
+// findCycle returns the index of a node on a cycle in graph, or -1.
+func findCycle(graph [][]int) int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(graph))
+	var visit func(int) int
+	visit = func(i int) int {
+		state[i] = visiting
+		for _, d := range graph[i] {
+			switch state[d] {
+			case visiting:
+				return d
+			case unvisited:
+				if c := visit(d); c >= 0 {
+					return c
+				}
+			}
+		}
+		state[i] = visited
+		return -1
+	}
+	for i := range graph {
+		if state[i] == unvisited {
+			if c := visit(i); c >= 0 {
+				return c
+			}
+		}
+	}
+	return -1
+}
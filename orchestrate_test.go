@@ -0,0 +1,52 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph [][]int
+		// cycle lists the nodes findCycle may report, none if the graph
+		// is acyclic.
+		cycle []int
+	}{
+		{"empty", nil, nil},
+		{"single", [][]int{nil}, nil},
+		{"chain", [][]int{{1}, {2}, nil}, nil},
+		{"diamond", [][]int{{1, 2}, {3}, {3}, nil}, nil},
+		{"shared dependency", [][]int{{2}, {2}, nil}, nil},
+		{"self", [][]int{{0}}, []int{0}},
+		{"pair", [][]int{{1}, {0}}, []int{0, 1}},
+		{"triangle", [][]int{{1}, {2}, {0}}, []int{0, 1, 2}},
+		{"behind acyclic node", [][]int{{1}, {2}, {3}, {2}}, []int{2, 3}},
+		{"second component", [][]int{{1}, nil, {3}, {2}}, []int{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findCycle(tt.graph)
+			if tt.cycle == nil {
+				if got != -1 {
+					t.Errorf("findCycle = %d, want -1", got)
+				}
+				return
+			}
+			if !slices.Contains(tt.cycle, got) {
+				t.Errorf("findCycle = %d, want one of %v", got, tt.cycle)
+			}
+		})
+	}
+}
+
+func TestReverseGraph(t *testing.T) {
+	graph := [][]int{{1, 2}, {2}, nil}
+	want := [][]int{nil, {0}, {0, 1}}
+	got := reverseGraph(graph)
+	if !slices.EqualFunc(got, want, slices.Equal[[]int]) {
+		t.Errorf("reverseGraph(%v) = %v, want %v", graph, got, want)
+	}
+}
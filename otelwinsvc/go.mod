@@ -0,0 +1,16 @@
+module github.com/lib-x/winsvc/otelwinsvc
+
+go 1.22
+
+require (
+	github.com/lib-x/winsvc v0.0.0-20261014064245-43fe77dec62e
+	go.opentelemetry.io/otel v1.31.0
+	go.opentelemetry.io/otel/trace v1.31.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.31.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+)
@@ -0,0 +1,195 @@
+// Package otelwinsvc wraps a winsvc.Manager so that management operations
+// are recorded as OpenTelemetry spans, letting deployment orchestrators
+// that trace their rollouts see service control manager operations in
+// their traces.
+//
+//	m, err := winsvc.Connect()
+//	...
+//	tm := otelwinsvc.New(m)
+//	err = tm.Start(ctx, "MyService") // recorded as a child of ctx's span
+package otelwinsvc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+
+	"github.com/lib-x/winsvc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer spans are created
+// with.
+const ScopeName = "github.com/lib-x/winsvc/otelwinsvc"
+
+// Attribute keys set on spans.
+const (
+	ServiceNameKey  = attribute.Key("winsvc.service.name")
+	HostKey         = attribute.Key("winsvc.host")
+	TargetStateKey  = attribute.Key("winsvc.target_state")
+	StateKey        = attribute.Key("winsvc.state")
+	ErrorCodeKey    = attribute.Key("winsvc.error_code")
+	DurationKey     = attribute.Key("winsvc.duration_ms")
+	ServiceCountKey = attribute.Key("winsvc.service.count")
+)
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithTracerProvider creates spans with tp instead of the global tracer
+// provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(m *Manager) {
+		m.tracer = tp.Tracer(ScopeName)
+	}
+}
+
+// Manager is a winsvc.Manager whose Install, Remove, Start, Stop, Restart,
+// Query, StartSet and StopSet create a span for each call. Other methods
+// are those of the embedded Manager and are not traced.
+type Manager struct {
+	*winsvc.Manager
+	tracer trace.Tracer
+}
+
+// New returns a Manager tracing the operations performed through m.
+func New(m *winsvc.Manager, options ...Option) *Manager {
+	tm := &Manager{Manager: m}
+	for _, option := range options {
+		option(tm)
+	}
+	if tm.tracer == nil {
+		tm.tracer = otel.Tracer(ScopeName)
+	}
+	return tm
+}
+
+// start begins a span for op on the named service.
+func (m *Manager) start(ctx context.Context, op, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, ServiceNameKey.String(name))
+	if host := m.Host(); host != "" {
+		attrs = append(attrs, HostKey.String(host))
+	}
+	return m.tracer.Start(ctx, "winsvc."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// end records err on span and ends it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if code, ok := errorCode(err); ok {
+			span.SetAttributes(ErrorCodeKey.Int64(int64(code)))
+		}
+	}
+	span.End()
+}
+
+// errorCode returns the Win32 error code err wraps, if any.
+func errorCode(err error) (syscall.Errno, bool) {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno, true
+	}
+	return 0, false
+}
+
+// Install installs a service like winsvc.Manager.Install.
+func (m *Manager) Install(ctx context.Context, appPath, name string, serviceArgs []string, options ...winsvc.ServiceOption) (err error) {
+	ctx, span := m.start(ctx, "install", name, attribute.String("winsvc.binary_path", appPath))
+	defer func() { end(span, err) }()
+	return m.Manager.Install(ctx, appPath, name, serviceArgs, options...)
+}
+
+// Remove removes a service like winsvc.Manager.Remove.
+func (m *Manager) Remove(ctx context.Context, name string, options ...winsvc.RemoveOption) (err error) {
+	ctx, span := m.start(ctx, "remove", name)
+	defer func() { end(span, err) }()
+	return m.Manager.Remove(ctx, name, options...)
+}
+
+// Start starts a service like winsvc.Manager.Start.
+func (m *Manager) Start(ctx context.Context, name string, args ...string) (err error) {
+	ctx, span := m.start(ctx, "start", name, TargetStateKey.String(winsvc.StateRunning.String()))
+	defer func() { end(span, err) }()
+	return m.Manager.Start(ctx, name, args...)
+}
+
+// Stop stops a service like winsvc.Manager.Stop.
+func (m *Manager) Stop(ctx context.Context, name string) (err error) {
+	ctx, span := m.start(ctx, "stop", name, TargetStateKey.String(winsvc.StateStopped.String()))
+	defer func() { end(span, err) }()
+	return m.Manager.Stop(ctx, name)
+}
+
+// Restart restarts a service like winsvc.Manager.Restart.
+func (m *Manager) Restart(ctx context.Context, name string) (err error) {
+	ctx, span := m.start(ctx, "restart", name, TargetStateKey.String(winsvc.StateRunning.String()))
+	defer func() { end(span, err) }()
+	return m.Manager.Restart(ctx, name)
+}
+
+// Query returns the status of a service like winsvc.Manager.Query. Unlike
+// it, Query takes a context to parent the span.
+func (m *Manager) Query(ctx context.Context, name string) (status winsvc.ServiceStatus, err error) {
+	_, span := m.start(ctx, "query", name)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(StateKey.String(status.State.String()))
+		}
+		end(span, err)
+	}()
+	return m.Manager.Query(name)
+}
+
+// StartSet starts services in dependency order like
+// winsvc.Manager.StartSet. The span has an event per service with its
+// resulting state, duration and error.
+func (m *Manager) StartSet(ctx context.Context, names []string, concurrency int) ([]winsvc.SetResult, error) {
+	return m.traceSet(ctx, "start_set", winsvc.StateRunning, names, func(ctx context.Context) ([]winsvc.SetResult, error) {
+		return m.Manager.StartSet(ctx, names, concurrency)
+	})
+}
+
+// StopSet stops services in reverse dependency order like
+// winsvc.Manager.StopSet, recording the span like StartSet.
+func (m *Manager) StopSet(ctx context.Context, names []string, concurrency int) ([]winsvc.SetResult, error) {
+	return m.traceSet(ctx, "stop_set", winsvc.StateStopped, names, func(ctx context.Context) ([]winsvc.SetResult, error) {
+		return m.Manager.StopSet(ctx, names, concurrency)
+	})
+}
+
+func (m *Manager) traceSet(ctx context.Context, op string, target winsvc.State, names []string, fn func(context.Context) ([]winsvc.SetResult, error)) (results []winsvc.SetResult, err error) {
+	ctx, span := m.start(ctx, op, strings.Join(names, ","),
+		TargetStateKey.String(target.String()), ServiceCountKey.Int(len(names)))
+	defer func() { end(span, err) }()
+
+	results, err = fn(ctx)
+	failed := 0
+	for _, r := range results {
+		attrs := []attribute.KeyValue{
+			ServiceNameKey.String(r.Name),
+			StateKey.String(r.Status.State.String()),
+			DurationKey.Int64(r.Duration.Milliseconds()),
+		}
+		switch {
+		case r.Skipped:
+			attrs = append(attrs, attribute.Bool("winsvc.skipped", true))
+		case r.Err != nil:
+			failed++
+			attrs = append(attrs, attribute.String("exception.message", r.Err.Error()))
+			if code, ok := errorCode(r.Err); ok {
+				attrs = append(attrs, ErrorCodeKey.Int64(int64(code)))
+			}
+		}
+		span.AddEvent("winsvc.service", trace.WithAttributes(attrs...))
+	}
+	if err == nil && failed > 0 {
+		span.SetStatus(codes.Error, "some services failed")
+	}
+	return results, err
+}
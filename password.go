@@ -0,0 +1,87 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// readCredentialPassword returns the password of the generic credential for
+// target as UTF-8.
+func readCredentialPassword(target string) ([]byte, error) {
+	cred, err := credRead(target)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return nil, fmt.Errorf("no credential is stored for %s", target)
+		}
+		return nil, fmt.Errorf("failed to read credential %s: %w", target, err)
+	}
+	defer credFree(cred)
+	if cred.CredentialBlobSize == 0 {
+		return nil, nil
+	}
+
+	// Credential Manager stores passwords entered with cmdkey or the
+	// control panel as UTF-16.
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	defer clear(blob)
+	units := unsafe.Slice((*uint16)(unsafe.Pointer(cred.CredentialBlob)), cred.CredentialBlobSize/2)
+	return utf8FromUTF16(units), nil
+}
+
+// setServicePassword sets the logon password of the service with handle h
+// to the one returned by password, zeroing every copy of it afterward.
+func setServicePassword(h windows.Handle, password func() ([]byte, error)) error {
+	b, err := password()
+	if err != nil {
+		return fmt.Errorf("failed to obtain service account password: %w", err)
+	}
+	defer clear(b)
+
+	p := utf16FromBytes(b)
+	defer clear(p)
+
+	err = windows.ChangeServiceConfig(h, windows.SERVICE_NO_CHANGE, windows.SERVICE_NO_CHANGE,
+		windows.SERVICE_NO_CHANGE, nil, nil, nil, nil, nil, &p[0], nil)
+	if err != nil {
+		// The error comes from the service control manager and does not
+		// contain the password.
+		return fmt.Errorf("failed to set service account password: %w", scmError(err))
+	}
+	return nil
+}
+
+// utf8FromUTF16 converts UTF-16 p to UTF-8 without making an intermediate
+// string, so that the result can be zeroed.
+func utf8FromUTF16(p []uint16) []byte {
+	b := make([]byte, 0, 3*len(p))
+	for i := 0; i < len(p); i++ {
+		r := rune(p[i])
+		if utf16.IsSurrogate(r) && i+1 < len(p) {
+			if d := utf16.DecodeRune(r, rune(p[i+1])); d != utf8.RuneError {
+				r = d
+				i++
+			}
+		}
+		b = utf8.AppendRune(b, r)
+	}
+	return b
+}
+
+// utf16FromBytes converts UTF-8 b to a NUL-terminated UTF-16 string without
+// making an intermediate string, so that the result can be zeroed.
+func utf16FromBytes(b []byte) []uint16 {
+	p := make([]uint16, 0, len(b)+1)
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		p = utf16.AppendRune(p, r)
+		b = b[size:]
+	}
+	return append(p, 0)
+}
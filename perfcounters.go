@@ -0,0 +1,231 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"text/template"
+
+	"golang.org/x/sys/windows"
+)
+
+var counterTypeNames = map[CounterType]string{
+	CounterGauge: "perf_counter_large_rawcount",
+	CounterRate:  "perf_counter_bulk_count",
+}
+
+var counterManifestTemplate = template.Must(template.New("counters").Funcs(template.FuncMap{
+	"xml":  xmlText,
+	"type": func(t CounterType) string { return counterTypeNames[t] },
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<instrumentationManifest xmlns="http://schemas.microsoft.com/win/2004/08/events" xmlns:win="http://manifests.microsoft.com/win/2004/08/windows/events" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <instrumentation>
+    <counters xmlns="http://schemas.microsoft.com/win/2005/12/counters" schemaVersion="2.0">
+      <provider providerName="{{xml .S.Name}}" providerGuid="{{.Provider}}" applicationIdentity="{{xml .File}}" providerType="userMode" symbol="PROVIDER">
+        <counterSet guid="{{.GUID}}" uri="{{xml .S.Name}}" name="{{xml .S.Name}}" description="{{xml .S.Description}}" symbol="COUNTERSET" instances="single">
+{{- range .S.Counters}}
+          <counter id="{{.ID}}" uri="{{xml $.S.Name}}.{{.ID}}" name="{{xml .Name}}" description="{{xml .Description}}" type="{{type .Type}}" detailLevel="standard"/>
+{{- end}}
+        </counterSet>
+      </provider>
+    </counters>
+  </instrumentation>
+</instrumentationManifest>
+`))
+
+// Manifest returns the performance counter manifest of s, naming file as
+// the executable that publishes the counters.
+func (s CounterSet) Manifest(file string) ([]byte, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	err := counterManifestTemplate.Execute(&b, struct {
+		S        CounterSet
+		Provider string
+		GUID     string
+		File     string
+	}{s, s.ProviderGUID.String(), s.GUID.String(), file})
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (s CounterSet) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("counter set has no name")
+	}
+	ids := make(map[uint32]bool, len(s.Counters))
+	for _, c := range s.Counters {
+		if _, ok := counterTypeNames[c.Type]; !ok {
+			return fmt.Errorf("counter %d has invalid type %#x", c.ID, uint32(c.Type))
+		}
+		if ids[c.ID] {
+			return fmt.Errorf("counter id %d is used twice", c.ID)
+		}
+		ids[c.ID] = true
+	}
+	return nil
+}
+
+// InstallCounterSet registers s with lodctr, naming file as the executable
+// that publishes the counters. It requires administrator rights.
+func InstallCounterSet(s CounterSet, file string) error {
+	manifest, err := s.Manifest(file)
+	if err != nil {
+		return err
+	}
+	path, err := writeTempManifest(manifest)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	out, err := exec.Command("lodctr", "/m:"+path, filepath.Dir(file)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to register counter set %s: %w: %s", s.Name, err, out)
+	}
+	return nil
+}
+
+// UninstallCounterSet unregisters s with unlodctr.
+func UninstallCounterSet(s CounterSet) error {
+	manifest, err := s.Manifest("")
+	if err != nil {
+		return err
+	}
+	path, err := writeTempManifest(manifest)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	out, err := exec.Command("unlodctr", "/m:"+path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to unregister counter set %s: %w: %s", s.Name, err, out)
+	}
+	return nil
+}
+
+// RemovePerfCounters unregisters the given counter sets.
+func RemovePerfCounters(sets ...CounterSet) RemoveOption {
+	return func(c *removeConfig) {
+		c.counterSets = append(c.counterSets, sets...)
+	}
+}
+
+// Sizes of PERF_COUNTERSET_INFO and PERF_COUNTER_INFO.
+const (
+	perfCounterSetInfoSize = 40
+	perfCounterInfoSize    = 32
+
+	perfCountersetSingleInstance = 0
+	perfDetailNovice             = 100
+)
+
+// CounterPublisher publishes the values of a CounterSet. It is safe for
+// concurrent use.
+type CounterPublisher struct {
+	mu       sync.Mutex
+	provider windows.Handle
+	instance uintptr
+}
+
+// PublishCounterSet starts publishing the counters of s from the calling
+// process. All counters start at zero. s should be registered, for
+// example by installing the service with WithPerfCounters; otherwise
+// consumers do not see the values.
+func PublishCounterSet(s CounterSet) (*CounterPublisher, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	// PERF_COUNTERSET_INFO followed by a PERF_COUNTER_INFO per counter,
+	// each counter's value stored at its index.
+	template := make([]byte, perfCounterSetInfoSize+perfCounterInfoSize*len(s.Counters))
+	putGUID(template[0:], s.GUID)
+	putGUID(template[16:], s.ProviderGUID)
+	binary.LittleEndian.PutUint32(template[32:], uint32(len(s.Counters)))
+	binary.LittleEndian.PutUint32(template[36:], perfCountersetSingleInstance)
+	for i, c := range s.Counters {
+		info := template[perfCounterSetInfoSize+perfCounterInfoSize*i:]
+		binary.LittleEndian.PutUint32(info[0:], c.ID)
+		binary.LittleEndian.PutUint32(info[4:], uint32(c.Type))
+		binary.LittleEndian.PutUint32(info[16:], 8)
+		binary.LittleEndian.PutUint32(info[20:], perfDetailNovice)
+		binary.LittleEndian.PutUint32(info[28:], uint32(8*i))
+	}
+
+	h, err := perfStartProvider(&s.ProviderGUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start counter provider: %w", err)
+	}
+	if err := perfSetCounterSetInfo(h, template); err != nil {
+		perfStopProvider(h)
+		return nil, fmt.Errorf("failed to define counter set %s: %w", s.Name, err)
+	}
+	name, err := windows.UTF16PtrFromString(s.Name)
+	if err != nil {
+		perfStopProvider(h)
+		return nil, err
+	}
+	instance, err := perfCreateInstance(h, &s.GUID, name, 0)
+	if err != nil {
+		perfStopProvider(h)
+		return nil, fmt.Errorf("failed to create counter set instance: %w", err)
+	}
+	return &CounterPublisher{provider: h, instance: instance}, nil
+}
+
+func putGUID(b []byte, g GUID) {
+	binary.LittleEndian.PutUint32(b[0:], g.Data1)
+	binary.LittleEndian.PutUint16(b[4:], g.Data2)
+	binary.LittleEndian.PutUint16(b[6:], g.Data3)
+	copy(b[8:16], g.Data4[:])
+}
+
+// Set sets the value of the counter with the given ID, typically a gauge.
+func (p *CounterPublisher) Set(id uint32, value uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.instance == 0 {
+		return os.ErrClosed
+	}
+	if err := perfSetULongLongCounterValue(p.provider, p.instance, id, value); err != nil {
+		return fmt.Errorf("failed to set counter %d: %w", id, err)
+	}
+	return nil
+}
+
+// Add adds delta to the value of the counter with the given ID, typically
+// a rate counter incremented once per request.
+func (p *CounterPublisher) Add(id uint32, delta uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.instance == 0 {
+		return os.ErrClosed
+	}
+	if err := perfIncrementULongLongCounterValue(p.provider, p.instance, id, delta); err != nil {
+		return fmt.Errorf("failed to add to counter %d: %w", id, err)
+	}
+	return nil
+}
+
+// Close stops publishing the counters.
+func (p *CounterPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.instance == 0 {
+		return nil
+	}
+	perfDeleteInstance(p.provider, p.instance)
+	p.instance = 0
+	return perfStopProvider(p.provider)
+}
@@ -0,0 +1,322 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// GetServiceUptime returns when the process hosting a Windows service was
+// created and how long it has been running since.
+func GetServiceUptime(name string) (startTime time.Time, uptime time.Duration, err error) {
+	status, err := QueryServiceEx(name)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if status.ProcessID == 0 {
+		return time.Time{}, 0, newError(ErrServiceNotActive, "service %s is not running", name)
+	}
+
+	startTime, err = ProcessStartTime(status.ProcessID)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return startTime, time.Since(startTime), nil
+}
+
+// GetServicePID returns the id of the process hosting a running Windows
+// service.
+func GetServicePID(name string) (uint32, error) {
+	var pid uint32
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		pid, err = m.PID(name)
+		return err
+	})
+	return pid, err
+}
+
+// PID returns the id of the process hosting the named service, like
+// GetServicePID.
+func (m *Manager) PID(name string) (uint32, error) {
+	status, err := m.Query(name)
+	if err != nil {
+		return 0, err
+	}
+	if status.ProcessID == 0 {
+		return 0, newError(ErrServiceNotActive, "service %s is not running", name)
+	}
+	return status.ProcessID, nil
+}
+
+// MarshalJSON encodes the CPU times in milliseconds.
+func (p ProcessStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		PID          uint32 `json:"pid"`
+		KernelTimeMs int64  `json:"kernelTimeMs"`
+		UserTimeMs   int64  `json:"userTimeMs"`
+		CPUTimeMs    int64  `json:"cpuTimeMs"`
+		WorkingSet   uint64 `json:"workingSet"`
+		PrivateBytes uint64 `json:"privateBytes"`
+		HandleCount  uint32 `json:"handleCount"`
+		ThreadCount  uint32 `json:"threadCount"`
+	}{
+		PID:          p.PID,
+		KernelTimeMs: p.KernelTime.Milliseconds(),
+		UserTimeMs:   p.UserTime.Milliseconds(),
+		CPUTimeMs:    p.CPUTime.Milliseconds(),
+		WorkingSet:   p.WorkingSet,
+		PrivateBytes: p.PrivateBytes,
+		HandleCount:  p.HandleCount,
+		ThreadCount:  p.ThreadCount,
+	})
+}
+
+// GetServiceProcessStats returns the resource usage of the process hosting
+// a running Windows service.
+func GetServiceProcessStats(name string) (ProcessStats, error) {
+	var stats ProcessStats
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		stats, err = m.ProcessStats(name)
+		return err
+	})
+	return stats, err
+}
+
+// ProcessStats returns the resource usage of the process hosting the named
+// service, like GetServiceProcessStats. It only works on the local machine.
+func (m *Manager) ProcessStats(name string) (ProcessStats, error) {
+	if err := m.localOnly("reading process statistics"); err != nil {
+		return ProcessStats{}, err
+	}
+	pid, err := m.PID(name)
+	if err != nil {
+		return ProcessStats{}, err
+	}
+	return processStats(pid)
+}
+
+func processStats(pid uint32) (ProcessStats, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ProcessStats{}, fmt.Errorf("could not open process %d: %w", pid, scmError(err))
+	}
+	defer windows.CloseHandle(h)
+
+	var creation, exit, kernel, user windows.Filetime
+	err = windows.GetProcessTimes(h, &creation, &exit, &kernel, &user)
+	if err != nil {
+		return ProcessStats{}, fmt.Errorf("could not get process times: %w", err)
+	}
+	mem, err := getProcessMemoryInfo(h)
+	if err != nil {
+		return ProcessStats{}, fmt.Errorf("could not get process memory info: %w", err)
+	}
+	handles, err := getProcessHandleCount(h)
+	if err != nil {
+		return ProcessStats{}, fmt.Errorf("could not get process handle count: %w", err)
+	}
+	threads, err := processThreadCount(pid)
+	if err != nil {
+		return ProcessStats{}, fmt.Errorf("could not get process thread count: %w", err)
+	}
+
+	stats := ProcessStats{
+		PID:          pid,
+		KernelTime:   filetimeDuration(kernel),
+		UserTime:     filetimeDuration(user),
+		WorkingSet:   uint64(mem.WorkingSetSize),
+		PrivateBytes: uint64(mem.PrivateUsage),
+		HandleCount:  handles,
+		ThreadCount:  threads,
+	}
+	stats.CPUTime = stats.KernelTime + stats.UserTime
+	return stats, nil
+}
+
+// processThreadCount returns the number of threads of the process with the
+// given id.
+func processThreadCount(pid uint32) (uint32, error) {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(snap)
+
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snap, &entry); err == nil; err = windows.Process32Next(snap, &entry) {
+		if entry.ProcessID == pid {
+			return entry.Threads, nil
+		}
+	}
+	if !errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return 0, err
+	}
+	return 0, fmt.Errorf("process %d not found", pid)
+}
+
+// filetimeDuration converts a FILETIME holding an interval in 100ns units
+// into a duration.
+func filetimeDuration(ft windows.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
+
+// ProcessStartTime returns when the local process with the given id was
+// created, as GetServiceUptime does for the process of a service. It only
+// needs PROCESS_QUERY_LIMITED_INFORMATION access to the process.
+func ProcessStartTime(pid uint32) (time.Time, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not open process %d: %w", pid, scmError(err))
+	}
+	defer windows.CloseHandle(h)
+
+	var creation, exit, kernel, user windows.Filetime
+	err = windows.GetProcessTimes(h, &creation, &exit, &kernel, &user)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not get process times: %w", err)
+	}
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
+
+// StopServiceForce stops a Windows service and, if it has not stopped once
+// gracePeriod has elapsed, terminates its process and waits for the service
+// control manager to report it stopped. Processes that host other services
+// or run in a system process are never terminated.
+func StopServiceForce(name string, gracePeriod time.Duration) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.StopForce(name, gracePeriod)
+	})
+}
+
+// StopForce stops the named service, terminating its process after
+// gracePeriod, like StopServiceForce.
+func (m *Manager) StopForce(name string, gracePeriod time.Duration) (err error) {
+	defer func() { m.audit("stop-force", name, map[string]string{"gracePeriod": gracePeriod.String()}, err) }()
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	err = m.stopAndWait(ctx, name)
+	cancel()
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return m.boundedWaits().terminateService(context.Background(), s, false)
+}
+
+// TerminateServiceProcess terminates the process hosting a Windows service
+// and waits for the service control manager to report the service stopped.
+// It is a last resort for a service that does not respond to a stop
+// request. Unless force is set it refuses, with an error wrapping
+// ErrSharedProcess, to terminate a process that hosts other services or is
+// a system process.
+func TerminateServiceProcess(name string, force bool) error {
+	return withDefaults(func(ctx context.Context, m *Manager) error {
+		return m.TerminateProcess(ctx, name, force)
+	})
+}
+
+// TerminateServiceProcessCtx is like TerminateServiceProcess but waits
+// until ctx is done at most.
+func TerminateServiceProcessCtx(ctx context.Context, name string, force bool) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.TerminateProcess(ctx, name, force)
+	})
+}
+
+// TerminateProcess terminates the process hosting the named service, like
+// TerminateServiceProcessCtx.
+func (m *Manager) TerminateProcess(ctx context.Context, name string, force bool) (err error) {
+	defer func() { m.audit("terminate", name, map[string]string{"force": strconv.FormatBool(force)}, err) }()
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return m.terminateService(ctx, s, force)
+}
+
+// terminateService terminates the process hosting s and waits until the
+// service is stopped. Unless force is set it refuses to terminate a process
+// that is shared with other services or is a system process.
+func (m *Manager) terminateService(ctx context.Context, s *mgr.Service, force bool) error {
+	// Terminating the process stops the service, so it needs the same
+	// permission from a read-only manager.
+	if err := m.checkAccess(s.Name, windows.SERVICE_STOP); err != nil {
+		return err
+	}
+	status, err := queryStatus(s)
+	if err != nil {
+		return fmt.Errorf("could not query service status: %w", err)
+	}
+	if status.State == StateStopped {
+		return nil
+	}
+	if status.ProcessID == 0 {
+		return newError(ErrServiceNotActive, "service %s has no process", s.Name)
+	}
+
+	if err := m.localOnly("terminating a service process"); err != nil {
+		return err
+	}
+	if !force {
+		if status.RunsInSystemProcess {
+			return newError(ErrSharedProcess, "service %s runs in a system process", s.Name)
+		}
+		shared, err := m.servicesInProcess(status.ProcessID)
+		if err != nil {
+			return err
+		}
+		for _, other := range shared {
+			if !strings.EqualFold(other, s.Name) {
+				return newError(ErrSharedProcess, "process %d of service %s also hosts service %s", status.ProcessID, s.Name, other)
+			}
+		}
+	}
+
+	h, err := windows.OpenProcess(windows.PROCESS_TERMINATE, false, status.ProcessID)
+	if err != nil {
+		return fmt.Errorf("could not open process %d: %w", status.ProcessID, scmError(err))
+	}
+	err = windows.TerminateProcess(h, 1)
+	windows.CloseHandle(h)
+	if err != nil {
+		return fmt.Errorf("could not terminate process %d: %w", status.ProcessID, scmError(err))
+	}
+
+	return m.waitStatus(ctx, s, svc.Status{State: svc.State(status.State)}, svc.Stopped)
+}
+
+// servicesInProcess returns the names of the active services hosted by the
+// process with the given id.
+func (m *Manager) servicesInProcess(pid uint32) ([]string, error) {
+	services, err := m.enumServices(windows.SERVICE_ACTIVE)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range services {
+		if info.Status.ProcessID == pid {
+			names = append(names, info.Name)
+		}
+	}
+	return names, nil
+}
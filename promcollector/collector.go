@@ -0,0 +1,243 @@
+// Package promcollector provides a prometheus.Collector reporting the state,
+// start type, uptime and restart count of Windows services, so existing
+// exporters can embed service monitoring.
+//
+//	m, err := winsvc.ConnectReadOnly()
+//	...
+//	prometheus.MustRegister(promcollector.New(m.Manager,
+//		promcollector.Services("MyService", "MyOtherService")))
+package promcollector
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib-x/winsvc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// Services restricts the collector to the named services, compared
+// case-insensitively. Without it, or Filter, every Win32 service is
+// reported.
+func Services(names ...string) Option {
+	return func(c *Collector) {
+		for _, name := range names {
+			c.names[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// Filter restricts the collector to the services matching f, as in
+// winsvc.ListServices. It can be combined with Services. A service that no
+// longer matches is forgotten, so its restart count starts over if it
+// matches again.
+func Filter(f winsvc.ServiceFilter) Option {
+	return func(c *Collector) {
+		c.filter = f
+	}
+}
+
+// Namespace sets the metric name prefix, "winsvc" by default.
+func Namespace(ns string) Option {
+	return func(c *Collector) {
+		c.namespace = ns
+	}
+}
+
+// ConfigRefresh sets how long the start type of a service is reused before
+// it is read again, DefaultConfigRefresh by default. Unlike the state,
+// which the enumeration of each scrape includes, it takes a call to the
+// service control manager per service.
+func ConfigRefresh(d time.Duration) Option {
+	return func(c *Collector) {
+		c.configRefresh = d
+	}
+}
+
+// DefaultConfigRefresh is how long the start type of a service is reused
+// unless ConfigRefresh is given.
+const DefaultConfigRefresh = 5 * time.Minute
+
+var states = []winsvc.State{
+	winsvc.StateStopped,
+	winsvc.StateStartPending,
+	winsvc.StateStopPending,
+	winsvc.StateRunning,
+	winsvc.StateContinuePending,
+	winsvc.StatePausePending,
+	winsvc.StatePaused,
+}
+
+var startTypes = []winsvc.StartType{
+	winsvc.StartTypeBoot,
+	winsvc.StartTypeSystem,
+	winsvc.StartTypeAutomatic,
+	winsvc.StartTypeManual,
+	winsvc.StartTypeDisabled,
+}
+
+// Collector is a prometheus.Collector for Windows services. Each scrape
+// enumerates the services in a single call to the service control manager
+// and reports, per service:
+//
+//   - <ns>_service_state, 1 for the current state and 0 for the others
+//   - <ns>_service_start_type, 1 for the configured start type
+//   - <ns>_service_uptime_seconds, for running services of the local
+//     machine, since the processes of another host cannot be queried
+//   - <ns>_service_restarts_total, the number of times the service was
+//     seen running in a new process since the collector was created
+//
+// Restarts are detected by comparing process ids between scrapes, so
+// restarts that happen entirely between two scrapes count once. The start
+// type is cached, see ConfigRefresh.
+type Collector struct {
+	m             *winsvc.Manager
+	names         map[string]bool
+	filter        winsvc.ServiceFilter
+	namespace     string
+	configRefresh time.Duration
+
+	state     *prometheus.Desc
+	startType *prometheus.Desc
+	uptime    *prometheus.Desc
+	restarts  *prometheus.Desc
+
+	mu      sync.Mutex
+	seen    map[string]*serviceHistory
+	scrapes uint64
+}
+
+type serviceHistory struct {
+	pid      uint32
+	ran      bool
+	restarts float64
+	// started is when the process pid was created, if known.
+	started time.Time
+	// startType is the start type read at configAt, if hasConfig.
+	startType winsvc.StartType
+	hasConfig bool
+	configAt  time.Time
+	// scrape is the last scrape the service was seen in.
+	scrape uint64
+}
+
+// New returns a Collector that queries services through m, which may be
+// the Manager of a winsvc.ReadOnlyManager since nothing is changed.
+func New(m *winsvc.Manager, options ...Option) *Collector {
+	c := &Collector{
+		m:             m,
+		names:         make(map[string]bool),
+		namespace:     "winsvc",
+		configRefresh: DefaultConfigRefresh,
+		seen:          make(map[string]*serviceHistory),
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	c.state = prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "service", "state"),
+		"The state of the service; 1 for the current state.", []string{"name", "state"}, nil)
+	c.startType = prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "service", "start_type"),
+		"The start type of the service; 1 for the configured start type.", []string{"name", "start_type"}, nil)
+	c.uptime = prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "service", "uptime_seconds"),
+		"Seconds since the process hosting the service was created.", []string{"name"}, nil)
+	c.restarts = prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "service", "restarts_total"),
+		"Times the service was seen running in a new process.", []string{"name"}, nil)
+	return c
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+	ch <- c.startType
+	ch <- c.uptime
+	ch <- c.restarts
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	services, err := c.m.List(c.filter)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.state, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scrapes++
+	now := time.Now()
+	local := c.m.Host() == ""
+	for _, info := range services {
+		key := strings.ToLower(info.Name)
+		if len(c.names) > 0 && !c.names[key] {
+			continue
+		}
+		pid := info.Status.ProcessID
+		h := c.observe(key, pid)
+
+		for _, s := range states {
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, boolValue(info.Status.State == s), info.Name, s.String())
+		}
+
+		if h.configAt.IsZero() || now.Sub(h.configAt) >= c.configRefresh {
+			config, err := c.m.Config(info.Name)
+			h.startType, h.hasConfig, h.configAt = config.StartType, err == nil, now
+		}
+		if h.hasConfig {
+			for _, t := range startTypes {
+				ch <- prometheus.MustNewConstMetric(c.startType, prometheus.GaugeValue, boolValue(h.startType == t), info.Name, t.String())
+			}
+		}
+
+		if local && pid != 0 {
+			if h.started.IsZero() {
+				h.started, _ = winsvc.ProcessStartTime(pid)
+			}
+			if !h.started.IsZero() {
+				ch <- prometheus.MustNewConstMetric(c.uptime, prometheus.GaugeValue, now.Sub(h.started).Seconds(), info.Name)
+			}
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.restarts, prometheus.CounterValue, h.restarts, info.Name)
+	}
+
+	// Forget services that were deleted, or no longer match the filter.
+	for key, h := range c.seen {
+		if h.scrape != c.scrapes {
+			delete(c.seen, key)
+		}
+	}
+}
+
+// observe records the process id a service, by lower-cased name, runs in
+// and counts a restart if the service ran in a different process before.
+func (c *Collector) observe(key string, pid uint32) *serviceHistory {
+	h, ok := c.seen[key]
+	if !ok {
+		h = &serviceHistory{}
+		c.seen[key] = h
+	} else if pid != 0 && pid != h.pid && h.ran {
+		h.restarts++
+	}
+	if pid != h.pid {
+		h.started = time.Time{}
+	}
+	h.pid = pid
+	if pid != 0 {
+		h.ran = true
+	}
+	h.scrape = c.scrapes
+	return h
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,205 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// SetProtectedParameter stores value as key in the Parameters\Protected
+// registry key of a Windows service, encrypted with DPAPI under the
+// machine's key. The Protected key is readable only by the system,
+// administrators and the service's own SID (see ServiceSID), and the
+// encryption keeps the value unreadable in registry exports and backups
+// taken off the machine. value is not modified.
+//
+// The service can only read the key through its SID if it was installed
+// with UnrestrictedSID or WriteRestricted; otherwise its token lacks the
+// SID, and only a service running as LocalSystem can read the key.
+func SetProtectedParameter(name, key string, value []byte) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.SetProtectedParameter(name, key, value)
+	})
+}
+
+// SetProtectedParameter stores an encrypted parameter of the named service,
+// like the package-level SetProtectedParameter. It is only supported on the
+// local machine, whose key is used for the encryption. It fails with
+// ErrServiceNotFound if the service is not installed.
+func (m *Manager) SetProtectedParameter(name, key string, value []byte) (err error) {
+	defer func() { m.audit("set-parameter", name, map[string]string{"key": key}, err) }()
+	if err := m.localOnly("storing protected parameters"); err != nil {
+		return err
+	}
+	if err := m.checkAccess(name, windows.SERVICE_CHANGE_CONFIG); err != nil {
+		return err
+	}
+	s, err := m.openService(name, windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+			return newError(ErrServiceNotFound, "service %s is not installed", name)
+		}
+		return err
+	}
+	s.Close()
+
+	data, err := protectData(name, value)
+	if err != nil {
+		return err
+	}
+
+	k, err := createProtectedKey(name)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	if err := k.SetBinaryValue(key, data); err != nil {
+		return fmt.Errorf("failed to write parameter %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetProtectedParameter returns the value stored as key by
+// SetProtectedParameter for a Windows service. The caller should zero the
+// returned slice when done with it.
+func GetProtectedParameter(name, key string) ([]byte, error) {
+	var value []byte
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		value, err = m.ProtectedParameter(name, key)
+		return err
+	})
+	return value, err
+}
+
+// ProtectedParameter returns an encrypted parameter of the named service,
+// like GetProtectedParameter.
+func (m *Manager) ProtectedParameter(name, key string) ([]byte, error) {
+	if err := m.localOnly("reading protected parameters"); err != nil {
+		return nil, err
+	}
+
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, protectedKeyPath(name), registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, fmt.Errorf("parameter %s of service %s does not exist", key, name)
+		}
+		return nil, fmt.Errorf("failed to open protected parameters key: %w", scmError(err))
+	}
+	defer k.Close()
+
+	data, _, err := k.GetBinaryValue(key)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, fmt.Errorf("parameter %s of service %s does not exist", key, name)
+		}
+		return nil, fmt.Errorf("failed to read parameter %s: %w", key, err)
+	}
+	return unprotectData(name, data)
+}
+
+func protectedKeyPath(name string) string {
+	return servicesKeyPath + `\` + name + `\Parameters\Protected`
+}
+
+// createProtectedKey opens the Protected key of the named service, creating
+// it and the Parameters key if necessary, and restricts it to the system,
+// administrators and the service SID. The key of the service itself must
+// exist: it is never created, so a service deleted meanwhile leaves no
+// orphaned keys behind.
+func createProtectedKey(name string) (registry.Key, error) {
+	sid, err := ServiceSID(name)
+	if err != nil {
+		return 0, err
+	}
+	sd, err := windows.SecurityDescriptorFromString("D:P(A;;KA;;;SY)(A;;KA;;;BA)(A;;KR;;;" + sid + ")")
+	if err != nil {
+		return 0, err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return 0, err
+	}
+
+	sk, err := registry.OpenKey(registry.LOCAL_MACHINE, servicesKeyPath+`\`+name, registry.CREATE_SUB_KEY)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return 0, newError(ErrServiceNotFound, "service %s is not installed", name)
+		}
+		return 0, fmt.Errorf("failed to open service key: %w", scmError(err))
+	}
+	defer sk.Close()
+
+	k, _, err := registry.CreateKey(sk, `Parameters\Protected`, registry.SET_VALUE|windows.WRITE_DAC)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open protected parameters key: %w", scmError(err))
+	}
+	err = windows.SetSecurityInfo(windows.Handle(k), windows.SE_REGISTRY_KEY,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+	if err != nil {
+		k.Close()
+		return 0, fmt.Errorf("failed to secure protected parameters key: %w", err)
+	}
+	return k, nil
+}
+
+// protectData encrypts data with the machine's DPAPI key, using the SID of
+// the named service as additional entropy.
+func protectData(name string, data []byte) ([]byte, error) {
+	entropy, err := serviceEntropy(name)
+	if err != nil {
+		return nil, err
+	}
+	in := newDataBlob(data)
+	var out windows.DataBlob
+	err = windows.CryptProtectData(in, nil, newDataBlob(entropy), 0, nil,
+		windows.CRYPTPROTECT_LOCAL_MACHINE|windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt parameter: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
+
+// unprotectData decrypts data encrypted by protectData for the named
+// service.
+func unprotectData(name string, data []byte) ([]byte, error) {
+	entropy, err := serviceEntropy(name)
+	if err != nil {
+		return nil, err
+	}
+	var out windows.DataBlob
+	err = windows.CryptUnprotectData(newDataBlob(data), nil, newDataBlob(entropy), 0, nil,
+		windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt parameter: %w", err)
+	}
+	plain := unsafe.Slice(out.Data, out.Size)
+	defer func() {
+		clear(plain)
+		windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	}()
+	return append([]byte(nil), plain...), nil
+}
+
+func serviceEntropy(name string) ([]byte, error) {
+	sid, err := ServiceSID(name)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(sid), nil
+}
+
+func newDataBlob(b []byte) *windows.DataBlob {
+	if len(b) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(b)), Data: &b[0]}
+}
@@ -0,0 +1,103 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var protectionLevelNames = map[ProtectionLevel]string{
+	ProtectionNone:             "None",
+	ProtectionWindows:          "Windows",
+	ProtectionWindowsLight:     "WindowsLight",
+	ProtectionAntimalwareLight: "AntimalwareLight",
+}
+
+// String returns the name of the protection level, such as
+// "AntimalwareLight".
+func (l ProtectionLevel) String() string {
+	if name, ok := protectionLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("ProtectionLevel(%d)", uint32(l))
+}
+
+// serviceLaunchProtectedInfo mirrors SERVICE_LAUNCH_PROTECTED_INFO.
+type serviceLaunchProtectedInfo struct {
+	LaunchProtected uint32
+}
+
+// GetProtectionLevel returns the protection level a Windows service is
+// registered with, so security products can check that their services are
+// still protected.
+func GetProtectionLevel(name string) (ProtectionLevel, error) {
+	var level ProtectionLevel
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		level, err = m.ProtectionLevel(name)
+		return err
+	})
+	return level, err
+}
+
+// ProtectionLevel returns the protection level of the named service, like
+// GetProtectionLevel.
+func (m *Manager) ProtectionLevel(name string) (ProtectionLevel, error) {
+	s, err := m.openService(name, windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	var info serviceLaunchProtectedInfo
+	var needed uint32
+	err = windows.QueryServiceConfig2(s.Handle, windows.SERVICE_CONFIG_LAUNCH_PROTECTED,
+		(*byte)(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)), &needed)
+	if err != nil {
+		return 0, fmt.Errorf("could not query protection level: %w", scmError(err))
+	}
+	return ProtectionLevel(info.LaunchProtected), nil
+}
+
+// SetProtectionLevel registers a Windows service to be launched with level.
+// Only ProtectionAntimalwareLight can be requested by third parties; the
+// other protected levels are reserved for Windows and are refused. The
+// service control manager further requires the service's binary to be
+// signed accordingly, and only allows reducing a protection level from a
+// process that is protected itself.
+func SetProtectionLevel(name string, level ProtectionLevel) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.SetProtectionLevel(name, level)
+	})
+}
+
+// SetProtectionLevel changes the protection level of the named service,
+// like the package-level SetProtectionLevel.
+func (m *Manager) SetProtectionLevel(name string, level ProtectionLevel) (err error) {
+	defer func() { m.audit("set-protection", name, map[string]string{"level": level.String()}, err) }()
+	if level != ProtectionNone && level != ProtectionAntimalwareLight {
+		return fmt.Errorf("protection level %s cannot be requested", level)
+	}
+	if level != ProtectionNone && m.host == "" {
+		if err := capProtected.check(); err != nil {
+			return err
+		}
+	}
+
+	s, err := m.openService(name, windows.SERVICE_CHANGE_CONFIG)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	info := serviceLaunchProtectedInfo{LaunchProtected: uint32(level)}
+	err = windows.ChangeServiceConfig2(s.Handle, windows.SERVICE_CONFIG_LAUNCH_PROTECTED, (*byte)(unsafe.Pointer(&info)))
+	if err != nil {
+		return fmt.Errorf("failed to set protection level %s: %w", level, scmError(err))
+	}
+	return nil
+}
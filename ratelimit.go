@@ -0,0 +1,140 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitedLogger is a Logger that protects another from floods of
+// events, such as a hosted service stuck in an error loop. Identical
+// messages logged within a window are collapsed into one "message repeated
+// N times" event, and at most a fixed number of events per minute are
+// passed on; the number dropped is reported once events flow again. It is
+// safe for concurrent use.
+type RateLimitedLogger struct {
+	l         Logger
+	window    time.Duration
+	perMinute int
+
+	mu      sync.Mutex
+	last    loggedMessage
+	lastAt  time.Time
+	repeats int
+	timer   *time.Timer
+
+	minuteStart time.Time
+	count       int
+	dropped     int
+}
+
+type loggedMessage struct {
+	severity Severity
+	eid      uint32
+	msg      string
+}
+
+// NewRateLimitedLogger returns a RateLimitedLogger writing to l that
+// collapses identical messages within window and passes on at most
+// perMinute events per minute. It can be passed to WithLogger:
+//
+//	l, _ := eventlog.Open(name)
+//	winsvc.RunAsService(name, start, stop, false,
+//		winsvc.WithLogger(winsvc.NewRateLimitedLogger(l, 10*time.Second, 60)))
+func NewRateLimitedLogger(l Logger, window time.Duration, perMinute int) *RateLimitedLogger {
+	return &RateLimitedLogger{l: l, window: window, perMinute: perMinute}
+}
+
+// Info implements Logger.
+func (r *RateLimitedLogger) Info(eid uint32, msg string) error {
+	return r.log(SeverityInfo, eid, msg)
+}
+
+// Warning implements Logger.
+func (r *RateLimitedLogger) Warning(eid uint32, msg string) error {
+	return r.log(SeverityWarning, eid, msg)
+}
+
+// Error implements Logger.
+func (r *RateLimitedLogger) Error(eid uint32, msg string) error {
+	return r.log(SeverityError, eid, msg)
+}
+
+// Flush writes the pending repeat count of the last message, if any.
+func (r *RateLimitedLogger) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.flushRepeats()
+}
+
+func (r *RateLimitedLogger) log(severity Severity, eid uint32, msg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := loggedMessage{severity, eid, msg}
+	now := time.Now()
+	if m == r.last && now.Sub(r.lastAt) < r.window {
+		r.repeats++
+		if r.timer == nil {
+			r.timer = time.AfterFunc(r.window-now.Sub(r.lastAt), r.windowEnded)
+		}
+		return nil
+	}
+
+	err := r.flushRepeats()
+	r.last, r.lastAt = m, now
+	if emitErr := r.emit(m); err == nil {
+		err = emitErr
+	}
+	return err
+}
+
+// windowEnded writes the repeat count once the window of the last message
+// has passed, so that a repeated message is reported even if nothing else
+// is logged.
+func (r *RateLimitedLogger) windowEnded() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timer = nil
+	r.flushRepeats()
+	r.last = loggedMessage{}
+}
+
+func (r *RateLimitedLogger) flushRepeats() error {
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	if r.repeats == 0 {
+		return nil
+	}
+	m := r.last
+	m.msg = fmt.Sprintf("%s (message repeated %d times)", m.msg, r.repeats)
+	r.repeats = 0
+	return r.emit(m)
+}
+
+// emit passes m on unless the per-minute limit is reached.
+func (r *RateLimitedLogger) emit(m loggedMessage) error {
+	now := time.Now()
+	if now.Sub(r.minuteStart) >= time.Minute {
+		r.minuteStart = now
+		r.count = 0
+		if r.dropped > 0 {
+			r.count++
+			note := fmt.Sprintf("%d events were dropped by the rate limit", r.dropped)
+			r.dropped = 0
+			if err := r.l.Warning(m.eid, note); err != nil {
+				return err
+			}
+		}
+	}
+	if r.count >= r.perMinute {
+		r.dropped++
+		return nil
+	}
+	r.count++
+	return logAt(r.l, m.severity, m.eid, m.msg)
+}
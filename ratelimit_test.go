@@ -0,0 +1,130 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// recordingLogger is a Logger that records messages as "<severity> <msg>".
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Info(eid uint32, msg string) error {
+	l.messages = append(l.messages, "I "+msg)
+	return nil
+}
+
+func (l *recordingLogger) Warning(eid uint32, msg string) error {
+	l.messages = append(l.messages, "W "+msg)
+	return nil
+}
+
+func (l *recordingLogger) Error(eid uint32, msg string) error {
+	l.messages = append(l.messages, "E "+msg)
+	return nil
+}
+
+func TestRateLimitedLogger(t *testing.T) {
+	type entry struct {
+		severity Severity
+		msg      string
+	}
+	info := func(msg string) entry { return entry{SeverityInfo, msg} }
+	tests := []struct {
+		name      string
+		perMinute int
+		log       []entry
+		want      []string
+	}{
+		{
+			name:      "distinct",
+			perMinute: 10,
+			log:       []entry{info("a"), info("b"), {SeverityError, "c"}},
+			want:      []string{"I a", "I b", "E c"},
+		},
+		{
+			name:      "repeated",
+			perMinute: 10,
+			log:       []entry{info("a"), info("a"), info("a"), info("b")},
+			want:      []string{"I a", "I a (message repeated 2 times)", "I b"},
+		},
+		{
+			name:      "repeated until flush",
+			perMinute: 10,
+			log:       []entry{info("a"), info("a")},
+			want:      []string{"I a", "I a (message repeated 1 times)"},
+		},
+		{
+			name:      "same text other severity",
+			perMinute: 10,
+			log:       []entry{info("a"), {SeverityWarning, "a"}},
+			want:      []string{"I a", "W a"},
+		},
+		{
+			name:      "limited",
+			perMinute: 2,
+			log:       []entry{info("a"), info("b"), info("c"), info("d")},
+			want:      []string{"I a", "I b"},
+		},
+		{
+			name:      "repeat count counts against limit",
+			perMinute: 2,
+			log:       []entry{info("a"), info("a"), info("b"), info("c")},
+			want:      []string{"I a", "I a (message repeated 1 times)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &recordingLogger{}
+			r := NewRateLimitedLogger(l, time.Hour, tt.perMinute)
+			for _, e := range tt.log {
+				if err := logAt(r, e.severity, 1, e.msg); err != nil {
+					t.Fatalf("logging %q: %v", e.msg, err)
+				}
+			}
+			if err := r.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+			if !slices.Equal(l.messages, tt.want) {
+				t.Errorf("logged %q, want %q", l.messages, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitedLoggerReportsDropped(t *testing.T) {
+	l := &recordingLogger{}
+	r := NewRateLimitedLogger(l, time.Hour, 1)
+	for i := 0; i < 4; i++ {
+		r.Info(1, fmt.Sprint(i))
+	}
+	// Start the next minute.
+	r.minuteStart = r.minuteStart.Add(-time.Minute)
+	r.Info(1, "next")
+
+	want := []string{"I 0", "W 3 events were dropped by the rate limit"}
+	if !slices.Equal(l.messages, want) {
+		t.Errorf("logged %q, want %q", l.messages, want)
+	}
+}
+
+func TestRateLimitedLoggerWindowEnds(t *testing.T) {
+	l := &recordingLogger{}
+	r := NewRateLimitedLogger(l, 100*time.Millisecond, 10)
+	r.Info(1, "a")
+	r.Info(1, "a")
+	time.Sleep(300 * time.Millisecond)
+	r.Info(1, "a")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	want := []string{"I a", "I a (message repeated 1 times)", "I a"}
+	if !slices.Equal(l.messages, want) {
+		t.Errorf("logged %q, want %q", l.messages, want)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+
+	"golang.org/x/sys/windows"
+)
+
+// readAccess is the service access a read-only manager may request.
+const readAccess = windows.SERVICE_QUERY_STATUS | windows.SERVICE_QUERY_CONFIG |
+	windows.SERVICE_ENUMERATE_DEPENDENTS | windows.READ_CONTROL
+
+// ReadOnlyManager is a connection to the service control manager for
+// unprivileged monitoring. It embeds Manager, so read operations such as
+// Query, List, Config and Watch work as usual, while every operation that
+// would change a service fails with a *ReadOnlyError before anything is
+// attempted.
+type ReadOnlyManager struct {
+	*Manager
+}
+
+// ConnectReadOnly connects to the service control manager of the local
+// machine for reading only. It needs no administrative rights.
+func ConnectReadOnly() (*ReadOnlyManager, error) {
+	return ConnectReadOnlyCtx(context.Background())
+}
+
+// ConnectReadOnlyCtx is like ConnectReadOnly but honors ctx cancellation.
+func ConnectReadOnlyCtx(ctx context.Context) (*ReadOnlyManager, error) {
+	m, err := ConnectCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.readOnly = true
+	return &ReadOnlyManager{Manager: m}, nil
+}
+
+// checkAccess returns a *ReadOnlyError if m is read-only and access asks
+// for more than reading the named service.
+func (m *Manager) checkAccess(name string, access uint32) error {
+	if m.readOnly && access&^readAccess != 0 {
+		return &ReadOnlyError{Name: name, Access: access}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// GetRecoveryConfig returns the failure actions configured for a Windows service.
+func GetRecoveryConfig(name string) (RecoveryConfig, error) {
+	var config RecoveryConfig
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		config, err = m.RecoveryConfig(name)
+		return err
+	})
+	return config, err
+}
+
+// RecoveryConfig returns the failure actions configured for the named
+// service, like GetRecoveryConfig.
+func (m *Manager) RecoveryConfig(name string) (RecoveryConfig, error) {
+	s, err := m.openService(name, windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return RecoveryConfig{}, err
+	}
+	defer s.Close()
+
+	actions, err := s.RecoveryActions()
+	if err != nil {
+		return RecoveryConfig{}, fmt.Errorf("could not query recovery actions: %w", err)
+	}
+	resetPeriod, err := s.ResetPeriod()
+	if err != nil {
+		return RecoveryConfig{}, fmt.Errorf("could not query reset period: %w", err)
+	}
+	rebootMsg, err := s.RebootMessage()
+	if err != nil {
+		return RecoveryConfig{}, fmt.Errorf("could not query reboot message: %w", err)
+	}
+	command, err := s.RecoveryCommand()
+	if err != nil {
+		return RecoveryConfig{}, fmt.Errorf("could not query recovery command: %w", err)
+	}
+	nonCrash, err := s.RecoveryActionsOnNonCrashFailures()
+	if err != nil {
+		return RecoveryConfig{}, fmt.Errorf("could not query failure actions flag: %w", err)
+	}
+
+	c := RecoveryConfig{
+		ResetPeriod:        time.Duration(resetPeriod) * time.Second,
+		RebootMessage:      rebootMsg,
+		Command:            command,
+		OnNonCrashFailures: nonCrash,
+	}
+	for _, a := range actions {
+		c.Actions = append(c.Actions, RecoveryAction{Type: RecoveryActionType(a.Type), Delay: a.Delay})
+	}
+	return c, nil
+}
+
+// setRecovery sets the failure actions of s to c, clearing those c leaves
+// empty.
+func setRecovery(s *mgr.Service, c RecoveryConfig) error {
+	if len(c.Actions) > 0 {
+		actions := make([]mgr.RecoveryAction, len(c.Actions))
+		for i, a := range c.Actions {
+			actions[i] = mgr.RecoveryAction{Type: int(a.Type), Delay: a.Delay}
+		}
+		if err := s.SetRecoveryActions(actions, uint32(c.ResetPeriod/time.Second)); err != nil {
+			return fmt.Errorf("failed to set recovery actions: %w", scmError(err))
+		}
+	} else {
+		// SetRecoveryActions refuses an empty list; a zero-length
+		// SERVICE_FAILURE_ACTIONS with a reset period clears the actions.
+		info := windows.SERVICE_FAILURE_ACTIONS{ResetPeriod: uint32(c.ResetPeriod / time.Second)}
+		if err := windows.ChangeServiceConfig2(s.Handle, windows.SERVICE_CONFIG_FAILURE_ACTIONS, (*byte)(unsafe.Pointer(&info))); err != nil {
+			return fmt.Errorf("failed to clear recovery actions: %w", scmError(err))
+		}
+	}
+	// Empty strings delete the reboot message and command.
+	if err := s.SetRebootMessage(c.RebootMessage); err != nil {
+		return fmt.Errorf("failed to set reboot message: %w", scmError(err))
+	}
+	if err := s.SetRecoveryCommand(c.Command); err != nil {
+		return fmt.Errorf("failed to set recovery command: %w", scmError(err))
+	}
+	if err := s.SetRecoveryActionsOnNonCrashFailures(c.OnNonCrashFailures); err != nil {
+		return fmt.Errorf("failed to set failure actions flag: %w", scmError(err))
+	}
+	return nil
+}
+
+// GetDelayedAutoStart reports whether a Windows service is configured for
+// delayed automatic start.
+func GetDelayedAutoStart(name string) (bool, error) {
+	c, err := GetServiceConfig(name)
+	if err != nil {
+		return false, err
+	}
+	return c.DelayedStart, nil
+}
@@ -0,0 +1,105 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type connectConfig struct {
+	creds *credentials
+	wait  WaitPolicy
+}
+
+// credentials are the account ConnectRemote authenticates as. password is
+// zeroed once the session is established.
+type credentials struct {
+	user     string
+	domain   string
+	password []byte
+}
+
+// ConnectAs authenticates to the remote host as user instead of with the
+// caller's own token, as "net use \\host\IPC$" would. user may be given as
+// DOMAIN\user, user@domain or, for a local account of the host, just user.
+// This is needed across untrusted domains and in workgroups. password is
+// only used to establish the session: ConnectRemote zeroes it once the
+// session is open, or has failed to open, and the Manager keeps no copy.
+func ConnectAs(user string, password []byte) ConnectOption {
+	return func(c *connectConfig) {
+		if c.creds != nil {
+			clear(c.creds.password)
+		}
+		c.creds = &credentials{user: user, password: password}
+		if domain, name, ok := strings.Cut(user, `\`); ok {
+			c.creds.domain, c.creds.user = domain, name
+		}
+	}
+}
+
+// ConnectRemote connects to the service control manager of another
+// machine, so the Manager methods act on its services. An empty host means
+// the local machine. Unless ConnectAs is given, the caller's credentials
+// must be accepted by host.
+func ConnectRemote(host string, options ...ConnectOption) (*Manager, error) {
+	return ConnectRemoteCtx(context.Background(), host, options...)
+}
+
+// ConnectRemoteCtx is like ConnectRemote but honors ctx cancellation.
+func ConnectRemoteCtx(ctx context.Context, host string, options ...ConnectOption) (*Manager, error) {
+	var cfg connectConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	var session *remoteSession
+	if cfg.creds != nil {
+		defer clear(cfg.creds.password)
+		if host == "" {
+			return nil, fmt.Errorf("credentials can only be used with a remote host")
+		}
+		var err error
+		session, err = openRemoteSession(host, cfg.creds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := connect(ctx, host, scmAccess)
+	if err != nil {
+		if session != nil {
+			session.close()
+		}
+		return nil, err
+	}
+	return &Manager{m: m, host: host, session: session, wait: cfg.wait}, nil
+}
+
+// remoteSession is an authenticated connection to a host's IPC$ share.
+// While it is open, named pipe connections to the host, which the service
+// control manager and the remote registry use, authenticate with its
+// credentials, as does access to the host's administrative shares.
+type remoteSession struct {
+	path string
+}
+
+func openRemoteSession(host string, creds *credentials) (*remoteSession, error) {
+	path := `\\` + host + `\IPC$`
+	user := creds.user
+	if creds.domain != "" {
+		user = creds.domain + `\` + creds.user
+	}
+	if err := wnetAddConnection(path, user, creds.password); err != nil {
+		return nil, fmt.Errorf("failed to authenticate to %s: %w", host, scmError(err))
+	}
+	return &remoteSession{path: path}, nil
+}
+
+func (s *remoteSession) close() error {
+	if err := wnetCancelConnection(s.path); err != nil {
+		return fmt.Errorf("failed to end session with %s: %w", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	servicesKeyPath = `SYSTEM\CurrentControlSet\Services`
+	eventLogKeyPath = `SYSTEM\CurrentControlSet\Services\EventLog`
+)
+
+type removeConfig struct {
+	keepEventSrc   bool
+	eventLogs      []string
+	firewallRules  []string
+	urlACLs        []string
+	programData    bool
+	programDataDir string
+	counterSets    []CounterSet
+}
+
+// RemoveParameters does nothing. Deleting the service deletes its registry
+// key, and the Parameters key under it with it.
+func RemoveParameters() RemoveOption {
+	return func(c *removeConfig) {}
+}
+
+// RemoveEnvironment does nothing. Deleting the service deletes its registry
+// key, and the Environment value in it with it.
+func RemoveEnvironment() RemoveOption {
+	return func(c *removeConfig) {}
+}
+
+// KeepEventSource leaves the service's event log source registered.
+func KeepEventSource() RemoveOption {
+	return func(c *removeConfig) {
+		c.keepEventSrc = true
+	}
+}
+
+// RemoveEventLog deletes the given custom event logs and all of their sources.
+func RemoveEventLog(logNames ...string) RemoveOption {
+	return func(c *removeConfig) {
+		c.eventLogs = append(c.eventLogs, logNames...)
+	}
+}
+
+// RemoveFirewallRules deletes the Windows Firewall rules with the given names.
+func RemoveFirewallRules(ruleNames ...string) RemoveOption {
+	return func(c *removeConfig) {
+		c.firewallRules = append(c.firewallRules, ruleNames...)
+	}
+}
+
+// RemoveURLACLs deletes the HTTP.sys URL reservations for the given URLs.
+func RemoveURLACLs(urls ...string) RemoveOption {
+	return func(c *removeConfig) {
+		c.urlACLs = append(c.urlACLs, urls...)
+	}
+}
+
+// RemoveProgramData deletes the %ProgramData%\<name> directory.
+func RemoveProgramData() RemoveOption {
+	return func(c *removeConfig) {
+		c.programData = true
+	}
+}
+
+// RemoveProgramDataDir deletes the given directory under %ProgramData%
+// instead of the one named after the service.
+func RemoveProgramDataDir(dir string) RemoveOption {
+	return func(c *removeConfig) {
+		c.programData = true
+		c.programDataDir = dir
+	}
+}
+
+func removeEventLog(root registry.Key, logName string) error {
+	err := deleteKeyTree(root, eventLogKeyPath+`\`+logName)
+	if err != nil {
+		return fmt.Errorf("failed to remove event log %s: %w", logName, err)
+	}
+	return nil
+}
+
+// installEventSource registers name as an event source of the Application
+// log under root, as eventlog.InstallAsEventCreate does for the local
+// machine.
+func installEventSource(root registry.Key, name string, eventsSupported uint32) error {
+	k, exists, err := registry.CreateKey(root, eventLogKeyPath+`\Application\`+name, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if exists {
+		return fmt.Errorf("event source %s already exists", name)
+	}
+
+	if err := k.SetDWordValue("CustomSource", 1); err != nil {
+		return err
+	}
+	if err := k.SetExpandStringValue("EventMessageFile", `%SystemRoot%\System32\EventCreate.exe`); err != nil {
+		return err
+	}
+	return k.SetDWordValue("TypesSupported", eventsSupported)
+}
+
+// removeEventSource deletes the Application log event source registered
+// by installEventSource.
+func removeEventSource(root registry.Key, name string) error {
+	err := registry.DeleteKey(root, eventLogKeyPath+`\Application\`+name)
+	if err != nil {
+		return fmt.Errorf("failed to remove event logger: %w", err)
+	}
+	return nil
+}
+
+func removeFirewallRule(ruleName string) error {
+	out, err := exec.Command("netsh", "advfirewall", "firewall", "delete", "rule", "name="+ruleName).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remove firewall rule %s: %w: %s", ruleName, err, out)
+	}
+	return nil
+}
+
+func removeURLACL(url string) error {
+	out, err := exec.Command("netsh", "http", "delete", "urlacl", "url="+url).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remove url acl %s: %w: %s", url, err, out)
+	}
+	return nil
+}
+
+func removeProgramData(dir string) error {
+	root, err := windows.KnownFolderPath(windows.FOLDERID_ProgramData, 0)
+	if err != nil {
+		return fmt.Errorf("failed to locate ProgramData: %w", err)
+	}
+	path, err := programDataPath(root, dir)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(path)
+	if err != nil {
+		return fmt.Errorf("failed to remove ProgramData directory: %w", err)
+	}
+	return nil
+}
+
+// programDataPath returns the path of dir under root, the ProgramData
+// directory. dir must be local to root: not empty, not absolute or
+// drive-relative, and not escaping root with "..".
+func programDataPath(root, dir string) (string, error) {
+	if !filepath.IsLocal(dir) {
+		return "", fmt.Errorf("invalid ProgramData directory %q", dir)
+	}
+	return filepath.Join(root, dir), nil
+}
+
+// deleteKeyTree deletes a registry key and all of its subkeys.
+// A missing key is not an error.
+func deleteKeyTree(root registry.Key, path string) error {
+	k, err := registry.OpenKey(root, path, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	subKeys, err := k.ReadSubKeyNames(-1)
+	k.Close()
+	if err != nil {
+		return err
+	}
+	for _, sub := range subKeys {
+		if err := deleteKeyTree(root, path+`\`+sub); err != nil {
+			return err
+		}
+	}
+	err = registry.DeleteKey(root, path)
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return err
+	}
+	return nil
+}
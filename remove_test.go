@@ -0,0 +1,43 @@
+//go:build windows
+
+package winsvc
+
+import "testing"
+
+func TestProgramDataPath(t *testing.T) {
+	const root = `C:\ProgramData`
+	tests := []struct {
+		dir     string
+		want    string
+		wantErr bool
+	}{
+		{dir: "MyService", want: `C:\ProgramData\MyService`},
+		{dir: `Vendor\MyService`, want: `C:\ProgramData\Vendor\MyService`},
+		{dir: `Vendor\..\MyService`, want: `C:\ProgramData\MyService`},
+		{dir: "", wantErr: true},
+		{dir: "..", wantErr: true},
+		{dir: `..\Windows`, wantErr: true},
+		{dir: `Vendor\..\..\Windows`, wantErr: true},
+		{dir: `C:\Windows`, wantErr: true},
+		{dir: `C:Windows`, wantErr: true},
+		{dir: `\Windows`, wantErr: true},
+		{dir: `\\server\share`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got, err := programDataPath(root, tt.dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("programDataPath(%q) = %q, want error", tt.dir, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("programDataPath(%q): %v", tt.dir, err)
+			}
+			if got != tt.want {
+				t.Errorf("programDataPath(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
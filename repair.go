@@ -0,0 +1,136 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// IsImagePathBroken reports whether the executable a Windows service is
+// configured to run no longer exists, which makes starting it fail with
+// ERROR_FILE_NOT_FOUND or ERROR_BAD_EXE_FORMAT.
+func IsImagePathBroken(name string) (bool, error) {
+	var broken bool
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		broken, err = m.IsImagePathBroken(name)
+		return err
+	})
+	return broken, err
+}
+
+// IsImagePathBroken reports whether the named service's executable is
+// missing, like the package-level IsImagePathBroken.
+func (m *Manager) IsImagePathBroken(name string) (bool, error) {
+	config, err := m.Config(name)
+	if err != nil {
+		return false, err
+	}
+	exe, _ := splitImagePath(config.BinaryPath)
+	return !m.fileExists(exe), nil
+}
+
+// RepairService points a Windows service whose executable no longer exists
+// at correctPath, for example after its folder was moved by hand. Arguments
+// embedded in the configured image path are kept, and the new path is
+// quoted if the old one was or if it contains spaces. It fails if the
+// configured executable still exists or correctPath does not.
+func RepairService(name, correctPath string) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.Repair(name, correctPath)
+	})
+}
+
+// Repair rewrites the image path of the named service, like RepairService.
+func (m *Manager) Repair(name, correctPath string) (err error) {
+	defer func() { m.audit("repair", name, map[string]string{"path": correctPath}, err) }()
+	s, err := m.openService(name, windows.SERVICE_QUERY_CONFIG|windows.SERVICE_CHANGE_CONFIG)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	config, err := s.Config()
+	if err != nil {
+		return fmt.Errorf("could not query service config: %w", err)
+	}
+	exe, args := splitImagePath(config.BinaryPathName)
+	if m.fileExists(exe) {
+		return fmt.Errorf("executable %s of service %s exists, nothing to repair", exe, name)
+	}
+	if !m.fileExists(correctPath) {
+		return fmt.Errorf("executable %s does not exist", correctPath)
+	}
+
+	quoted := strings.HasPrefix(strings.TrimSpace(config.BinaryPathName), `"`)
+	path := joinImagePath(correctPath, args, quoted)
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	err = windows.ChangeServiceConfig(s.Handle, windows.SERVICE_NO_CHANGE, windows.SERVICE_NO_CHANGE,
+		windows.SERVICE_NO_CHANGE, p, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update image path: %w", scmError(err))
+	}
+	return nil
+}
+
+// fileExists reports whether path names a regular file on the machine m is
+// connected to. Environment variables in path are expanded locally.
+func (m *Manager) fileExists(path string) bool {
+	if expanded, err := registry.ExpandString(path); err == nil {
+		path = expanded
+	}
+	target, err := m.adminSharePath(path)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(target)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// splitImagePath splits a service image path into the executable and the
+// arguments that follow it. An unquoted path is taken to end after the
+// first ".exe" that is followed by a space or the end of the string, the
+// way the service control manager resolves it, or at the first space.
+func splitImagePath(imagePath string) (exe, args string) {
+	imagePath = strings.TrimSpace(imagePath)
+	if rest, ok := strings.CutPrefix(imagePath, `"`); ok {
+		exe, args, _ = strings.Cut(rest, `"`)
+		return exe, strings.TrimSpace(args)
+	}
+
+	lower := strings.ToLower(imagePath)
+	for i := 0; ; {
+		j := strings.Index(lower[i:], ".exe")
+		if j < 0 {
+			break
+		}
+		end := i + j + len(".exe")
+		if end == len(imagePath) || imagePath[end] == ' ' {
+			return imagePath[:end], strings.TrimSpace(imagePath[end:])
+		}
+		i = end
+	}
+	exe, args, _ = strings.Cut(imagePath, " ")
+	return exe, strings.TrimSpace(args)
+}
+
+// joinImagePath builds an image path from an executable and its arguments,
+// quoting the executable if quote is set or it contains a space.
+func joinImagePath(exe, args string, quote bool) string {
+	if quote || strings.ContainsAny(exe, " \t") {
+		exe = `"` + exe + `"`
+	}
+	if args == "" {
+		return exe
+	}
+	return exe + " " + args
+}
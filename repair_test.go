@@ -0,0 +1,40 @@
+//go:build windows
+
+package winsvc
+
+import "testing"
+
+func TestSplitImagePath(t *testing.T) {
+	for _, tt := range []struct {
+		in, exe, args string
+	}{
+		{`"C:\Program Files\App\app.exe" -run`, `C:\Program Files\App\app.exe`, "-run"},
+		{`C:\Program Files\App\app.exe -run`, `C:\Program Files\App\app.exe`, "-run"},
+		{`C:\Windows\system32\svchost.exe -k netsvcs -p`, `C:\Windows\system32\svchost.exe`, "-k netsvcs -p"},
+		{`C:\Apps\old.exe.d\app.exe`, `C:\Apps\old.exe.d\app.exe`, ""},
+		{`C:\APPS\APP.EXE /service`, `C:\APPS\APP.EXE`, "/service"},
+		{`C:\tools\agent run`, `C:\tools\agent`, "run"},
+		{`  "C:\My App\app.exe"  `, `C:\My App\app.exe`, ""},
+	} {
+		exe, args := splitImagePath(tt.in)
+		if exe != tt.exe || args != tt.args {
+			t.Errorf("splitImagePath(%q) = %q, %q, want %q, %q", tt.in, exe, args, tt.exe, tt.args)
+		}
+	}
+}
+
+func TestJoinImagePath(t *testing.T) {
+	for _, tt := range []struct {
+		exe, args string
+		quote     bool
+		want      string
+	}{
+		{`C:\Program Files\App\app.exe`, "-run", false, `"C:\Program Files\App\app.exe" -run`},
+		{`C:\App\app.exe`, "", false, `C:\App\app.exe`},
+		{`C:\App\app.exe`, "-run", true, `"C:\App\app.exe" -run`},
+	} {
+		if got := joinImagePath(tt.exe, tt.args, tt.quote); got != tt.want {
+			t.Errorf("joinImagePath(%q, %q, %v) = %q, want %q", tt.exe, tt.args, tt.quote, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package winsvc
+
+import "context"
+
+// SCM is the subset of Manager's methods that install, control and inspect
+// services. Code written against SCM instead of *Manager can be tested with
+// a FakeSCM, without the service control manager or administrative rights.
+type SCM interface {
+	// Install installs a service, like Manager.Install.
+	Install(ctx context.Context, appPath, name string, serviceArgs []string, options ...ServiceOption) error
+	// Remove removes a service, like Manager.Remove.
+	Remove(ctx context.Context, name string, options ...RemoveOption) error
+	// Exists reports whether a service is installed, like Manager.Exists.
+	Exists(name string) (bool, error)
+	// Config returns the configuration of a service, like Manager.Config.
+	Config(name string) (ServiceConfig, error)
+	// Query returns the status of a service, like Manager.Query.
+	Query(name string) (ServiceStatus, error)
+	// QueryState returns the state of a service, like Manager.QueryState.
+	QueryState(name string) (State, error)
+	// List returns the services matching filter, like Manager.List.
+	List(filter ServiceFilter) ([]ServiceInfo, error)
+	// Start starts a service without waiting for it to run, like
+	// Manager.Start.
+	Start(ctx context.Context, name string, args ...string) error
+	// Stop stops a service and waits for it to stop, like Manager.Stop.
+	Stop(ctx context.Context, name string) error
+	// Pause pauses a service and waits for it to pause, like Manager.Pause.
+	Pause(ctx context.Context, name string) error
+	// Continue resumes a service and waits for it to run, like
+	// Manager.Continue.
+	Continue(ctx context.Context, name string) error
+	// Restart stops and starts a service, like Manager.Restart.
+	Restart(ctx context.Context, name string) error
+	// WaitForState waits until a service reaches state, like
+	// Manager.WaitForState.
+	WaitForState(ctx context.Context, name string, state State) error
+}
+
+var (
+	_ SCM = (*Manager)(nil)
+	_ SCM = (*FakeSCM)(nil)
+)
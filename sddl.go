@@ -0,0 +1,247 @@
+package winsvc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AccessRights is a set of access rights to a service.
+type AccessRights uint32
+
+const (
+	RightQueryConfig         AccessRights = 0x00001 // SERVICE_QUERY_CONFIG
+	RightChangeConfig        AccessRights = 0x00002 // SERVICE_CHANGE_CONFIG
+	RightQueryStatus         AccessRights = 0x00004 // SERVICE_QUERY_STATUS
+	RightEnumerateDependents AccessRights = 0x00008 // SERVICE_ENUMERATE_DEPENDENTS
+	RightStart               AccessRights = 0x00010 // SERVICE_START
+	RightStop                AccessRights = 0x00020 // SERVICE_STOP
+	RightPauseContinue       AccessRights = 0x00040 // SERVICE_PAUSE_CONTINUE
+	RightInterrogate         AccessRights = 0x00080 // SERVICE_INTERROGATE
+	RightUserDefinedControl  AccessRights = 0x00100 // SERVICE_USER_DEFINED_CONTROL
+	RightDelete              AccessRights = 0x10000 // DELETE
+	RightReadControl         AccessRights = 0x20000 // READ_CONTROL
+	RightWriteDAC            AccessRights = 0x40000 // WRITE_DAC
+	RightWriteOwner          AccessRights = 0x80000 // WRITE_OWNER
+
+	// RightsRead lets a trustee query a service, like the default rights
+	// of interactive users.
+	RightsRead = RightQueryConfig | RightQueryStatus | RightEnumerateDependents | RightInterrogate |
+		RightUserDefinedControl | RightReadControl
+	// RightsOperate additionally lets a trustee start, stop, pause and
+	// continue a service.
+	RightsOperate = RightsRead | RightStart | RightStop | RightPauseContinue
+	// RightsAll is full control of a service.
+	RightsAll AccessRights = 0xf01ff // SERVICE_ALL_ACCESS
+)
+
+// rightLetters lists the SDDL letters for service rights in the order
+// Windows writes them.
+var rightLetters = []struct {
+	right  AccessRights
+	letter string
+}{
+	{RightQueryConfig, "CC"},
+	{RightChangeConfig, "DC"},
+	{RightQueryStatus, "LC"},
+	{RightEnumerateDependents, "SW"},
+	{RightStart, "RP"},
+	{RightStop, "WP"},
+	{RightPauseContinue, "DT"},
+	{RightInterrogate, "LO"},
+	{RightUserDefinedControl, "CR"},
+	{RightDelete, "SD"},
+	{RightReadControl, "RC"},
+	{RightWriteDAC, "WD"},
+	{RightWriteOwner, "WO"},
+}
+
+// String returns r as an SDDL rights string, such as "CCLCSWRPWPDTLOCRRC".
+// If any of the rights has no letter, the whole mask is returned in
+// hexadecimal instead, such as "0x100010", since SDDL does not allow
+// letters and a mask in combination.
+func (r AccessRights) String() string {
+	var b strings.Builder
+	rest := r
+	for _, l := range rightLetters {
+		if r&l.right != 0 {
+			b.WriteString(l.letter)
+			rest &^= l.right
+		}
+	}
+	if rest != 0 {
+		return fmt.Sprintf("0x%x", uint32(r))
+	}
+	return b.String()
+}
+
+// ParseAccessRights parses an SDDL rights string of service rights letters,
+// such as "RPWPLC", or a hexadecimal mask such as "0x30".
+func ParseAccessRights(s string) (AccessRights, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		n, err := strconv.ParseUint(s[2:], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid access mask %q", s)
+		}
+		return AccessRights(n), nil
+	}
+	if s == "GA" || s == "KA" {
+		return RightsAll, nil
+	}
+	if len(s)%2 != 0 {
+		return 0, fmt.Errorf("invalid access rights %q", s)
+	}
+	var r AccessRights
+next:
+	for i := 0; i < len(s); i += 2 {
+		for _, l := range rightLetters {
+			if s[i:i+2] == l.letter {
+				r |= l.right
+				continue next
+			}
+		}
+		return 0, fmt.Errorf("unknown service right %q in %q", s[i:i+2], s)
+	}
+	return r, nil
+}
+
+// Trustee identifies the account or group an ACE applies to, either as an
+// SDDL SID alias such as TrusteeSystem or as a SID string.
+type Trustee string
+
+const (
+	TrusteeSystem             Trustee = "SY"
+	TrusteeAdministrators     Trustee = "BA"
+	TrusteeInteractiveUsers   Trustee = "IU"
+	TrusteeAuthenticatedUsers Trustee = "AU"
+	TrusteeServiceUsers       Trustee = "SU"
+	TrusteeLocalService       Trustee = "LS"
+	TrusteeNetworkService     Trustee = "NS"
+	TrusteeEveryone           Trustee = "WD"
+)
+
+// ServiceTrustee returns the trustee for the per-service SID of the named
+// service.
+func ServiceTrustee(name string) (Trustee, error) {
+	sid, err := ServiceSID(name)
+	if err != nil {
+		return "", err
+	}
+	return Trustee(sid), nil
+}
+
+// ACEType is the type of an ACE in a service DACL.
+type ACEType int
+
+const (
+	ACEAllow ACEType = iota
+	ACEDeny
+)
+
+// ACE is an access control entry granting or denying rights on a service to
+// a trustee.
+type ACE struct {
+	Type    ACEType
+	Rights  AccessRights
+	Trustee Trustee
+}
+
+// String returns e in SDDL form, such as "(A;;RPWPLC;;;IU)".
+func (e ACE) String() string {
+	t := "A"
+	if e.Type == ACEDeny {
+		t = "D"
+	}
+	return fmt.Sprintf("(%s;;%s;;;%s)", t, e.Rights, e.Trustee)
+}
+
+// DACL is the discretionary access control list of a service.
+type DACL []ACE
+
+// String returns d as the DACL part of an SDDL string, such as
+// "D:(A;;CCLCSWRPWPDTLOCRRC;;;SY)(A;;RPWPLC;;;IU)", suitable for
+// SetServiceSecurity.
+func (d DACL) String() string {
+	var b strings.Builder
+	b.WriteString("D:")
+	for _, e := range d {
+		b.WriteString(e.String())
+	}
+	return b.String()
+}
+
+// ParseDACL parses the DACL of an SDDL string, such as the one returned by
+// GetServiceSecurity. DACL flags and the other parts of the security
+// descriptor are ignored. ACEs other than plain access-allowed and
+// access-denied entries, which services do not use, are reported as an
+// error.
+func ParseDACL(sddl string) (DACL, error) {
+	start := sectionIndex(sddl, "D:")
+	if start < 0 {
+		return nil, fmt.Errorf("security descriptor %q has no DACL", sddl)
+	}
+	s := sddl[start+2:]
+
+	var dacl DACL
+	for {
+		open := strings.IndexByte(s, '(')
+		if open < 0 || strings.Contains(s[:open], ":") {
+			return dacl, nil
+		}
+		end := strings.IndexByte(s[open:], ')')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated ACE in %q", sddl)
+		}
+		e, err := parseACE(s[open+1 : open+end])
+		if err != nil {
+			return nil, err
+		}
+		dacl = append(dacl, e)
+		s = s[open+end+1:]
+	}
+}
+
+// sectionIndex returns the index of the SDDL section starting with prefix,
+// skipping over ACEs, or -1.
+func sectionIndex(sddl, prefix string) int {
+	depth := 0
+	for i := 0; i < len(sddl); i++ {
+		switch sddl[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		default:
+			if depth == 0 && strings.HasPrefix(sddl[i:], prefix) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseACE(s string) (ACE, error) {
+	fields := strings.Split(s, ";")
+	if len(fields) != 6 {
+		return ACE{}, fmt.Errorf("invalid ACE %q", s)
+	}
+	var e ACE
+	switch fields[0] {
+	case "A":
+		e.Type = ACEAllow
+	case "D":
+		e.Type = ACEDeny
+	default:
+		return ACE{}, fmt.Errorf("unsupported ACE type %q in %q", fields[0], s)
+	}
+	if fields[3] != "" || fields[4] != "" {
+		return ACE{}, fmt.Errorf("unsupported object ACE %q", s)
+	}
+	rights, err := ParseAccessRights(fields[2])
+	if err != nil {
+		return ACE{}, err
+	}
+	e.Rights = rights
+	e.Trustee = Trustee(fields[5])
+	return e, nil
+}
@@ -0,0 +1,139 @@
+package winsvc
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseAccessRights(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    AccessRights
+		wantErr bool
+	}{
+		{in: "RPWPLC", want: RightStart | RightStop | RightQueryStatus},
+		{in: "CCLCSWRPWPDTLOCRRC", want: RightsOperate},
+		{in: "GA", want: RightsAll},
+		{in: "KA", want: RightsAll},
+		{in: "0x30", want: RightStart | RightStop},
+		{in: "0X10", want: RightStart},
+		{in: "", want: 0},
+		{in: "RPW", wantErr: true},
+		{in: "RPZZ", wantErr: true},
+		{in: "0xZZ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseAccessRights(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseAccessRights(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAccessRights(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAccessRights(%q) = %#x, want %#x", tt.in, uint32(got), uint32(tt.want))
+			}
+		})
+	}
+}
+
+func TestAccessRightsString(t *testing.T) {
+	tests := []struct {
+		rights AccessRights
+		want   string
+	}{
+		{RightStart | RightStop | RightQueryStatus, "LCRPWP"},
+		{RightsRead, "CCLCSWLOCRRC"},
+		{RightStart | 0x100000, "0x100010"},
+		{0x100000, "0x100000"},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.rights.String(); got != tt.want {
+			t.Errorf("AccessRights(%#x).String() = %q, want %q", uint32(tt.rights), got, tt.want)
+		}
+	}
+}
+
+func TestParseDACL(t *testing.T) {
+	tests := []struct {
+		name    string
+		sddl    string
+		want    DACL
+		wantErr bool
+	}{
+		{
+			name: "default service",
+			sddl: "D:(A;;CCLCSWRPWPDTLOCRRC;;;SY)(A;;CCDCLCSWRPWPDTLOCRSDRCWDWO;;;BA)(A;;CCLCSWLOCRRC;;;IU)",
+			want: DACL{
+				{ACEAllow, RightsOperate, TrusteeSystem},
+				{ACEAllow, RightsAll, TrusteeAdministrators},
+				{ACEAllow, RightsRead, TrusteeInteractiveUsers},
+			},
+		},
+		{
+			name: "owner, group and SACL",
+			sddl: "O:SYG:SYD:(D;;WP;;;WD)(A;;RP;;;S-1-5-80-1)S:(AU;FA;CCDCLCSWRPWPDTLOCRSDRCWDWO;;;WD)",
+			want: DACL{
+				{ACEDeny, RightStop, TrusteeEveryone},
+				{ACEAllow, RightStart, Trustee("S-1-5-80-1")},
+			},
+		},
+		{
+			name: "DACL flags",
+			sddl: "D:PAI(A;;GA;;;SY)",
+			want: DACL{{ACEAllow, RightsAll, TrusteeSystem}},
+		},
+		{name: "empty DACL", sddl: "D:", want: nil},
+		{name: "no DACL", sddl: "O:SYG:SY", wantErr: true},
+		{name: "unterminated ACE", sddl: "D:(A;;RP;;;SY", wantErr: true},
+		{name: "object ACE", sddl: "D:(OA;;RP;bf967aba-0de6-11d0-a285-00aa003049e2;;SY)", wantErr: true},
+		{name: "audit ACE", sddl: "D:(AU;;RP;;;SY)", wantErr: true},
+		{name: "bad rights", sddl: "D:(A;;XX;;;SY)", wantErr: true},
+		{name: "missing field", sddl: "D:(A;;RP;;SY)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDACL(tt.sddl)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDACL(%q) = %v, want error", tt.sddl, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDACL(%q): %v", tt.sddl, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseDACL(%q) = %v, want %v", tt.sddl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDACLRoundTrip(t *testing.T) {
+	dacls := []DACL{
+		nil,
+		{{ACEAllow, RightsAll, TrusteeSystem}},
+		{
+			{ACEDeny, RightStop | RightPauseContinue, TrusteeEveryone},
+			{ACEAllow, RightsOperate, TrusteeServiceUsers},
+			{ACEAllow, RightsRead, Trustee("S-1-5-21-1-2-3-1001")},
+		},
+	}
+	for _, d := range dacls {
+		s := d.String()
+		got, err := ParseDACL(s)
+		if err != nil {
+			t.Errorf("ParseDACL(%q): %v", s, err)
+			continue
+		}
+		if !slices.Equal(got, d) {
+			t.Errorf("ParseDACL(%q) = %v, want %v", s, got, d)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// GetServiceSecurity returns the owner, group and DACL of a Windows service
+// as an SDDL string, such as "O:SYG:SYD:(A;;CCLCSWRPWPDTLOCRRC;;;SY)...".
+func GetServiceSecurity(name string) (string, error) {
+	var sddl string
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		sddl, err = m.Security(name)
+		return err
+	})
+	return sddl, err
+}
+
+// Security returns the security descriptor of the named service as SDDL,
+// like GetServiceSecurity.
+func (m *Manager) Security(name string) (string, error) {
+	sd, err := m.securityDescriptor(name)
+	if err != nil {
+		return "", err
+	}
+	return sd.String(), nil
+}
+
+func (m *Manager) securityDescriptor(name string) (*windows.SECURITY_DESCRIPTOR, error) {
+	s, err := m.openService(name, windows.READ_CONTROL)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	sd, err := windows.GetSecurityInfo(s.Handle, windows.SE_SERVICE,
+		windows.OWNER_SECURITY_INFORMATION|windows.GROUP_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return nil, fmt.Errorf("could not query service security: %w", scmError(err))
+	}
+	return sd, nil
+}
+
+// SetServiceSecurity replaces the parts of a Windows service's security
+// descriptor that sddl contains: the owner (O:), group (G:), DACL (D:) and
+// SACL (S:). Setting a SACL requires the SeSecurityPrivilege.
+func SetServiceSecurity(name, sddl string) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.SetSecurity(name, sddl)
+	})
+}
+
+// SetSecurity replaces the security descriptor of the named service, like
+// SetServiceSecurity.
+func (m *Manager) SetSecurity(name, sddl string) (err error) {
+	defer func() { m.audit("set-security", name, map[string]string{"sddl": sddl}, err) }()
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return fmt.Errorf("invalid security descriptor %q: %w", sddl, err)
+	}
+	return m.setSecurityDescriptor(name, sd)
+}
+
+func (m *Manager) setSecurityDescriptor(name string, sd *windows.SECURITY_DESCRIPTOR) error {
+	var info windows.SECURITY_INFORMATION
+	var access uint32
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return err
+	}
+	if owner != nil {
+		info |= windows.OWNER_SECURITY_INFORMATION
+		access |= windows.WRITE_OWNER
+	}
+	group, _, err := sd.Group()
+	if err != nil {
+		return err
+	}
+	if group != nil {
+		info |= windows.GROUP_SECURITY_INFORMATION
+		access |= windows.WRITE_OWNER
+	}
+	dacl, _, err := sd.DACL()
+	switch {
+	case err == nil:
+		info |= windows.DACL_SECURITY_INFORMATION
+		access |= windows.WRITE_DAC
+	case !errors.Is(err, windows.ERROR_OBJECT_NOT_FOUND):
+		return err
+	}
+	sacl, _, err := sd.SACL()
+	switch {
+	case err == nil:
+		info |= windows.SACL_SECURITY_INFORMATION
+		access |= windows.ACCESS_SYSTEM_SECURITY
+	case !errors.Is(err, windows.ERROR_OBJECT_NOT_FOUND):
+		return err
+	}
+	if info == 0 {
+		return fmt.Errorf("security descriptor is empty")
+	}
+
+	s, err := m.openService(name, access)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	err = windows.SetSecurityInfo(s.Handle, windows.SE_SERVICE, info, owner, group, dacl, sacl)
+	if err != nil {
+		return fmt.Errorf("could not set service security: %w", scmError(err))
+	}
+	return nil
+}
+
+// GrantServiceAccess adds an access-allowed entry for trustee to the DACL
+// of a Windows service, merging it with any rights trustee already has.
+// trustee is an account or group name such as `CONTOSO\MyOpsGroup`, or a
+// SID string. rights is a combination of SERVICE_* access rights, for
+// example windows.SERVICE_START|windows.SERVICE_STOP|windows.SERVICE_QUERY_STATUS
+// to let support staff restart the service.
+func GrantServiceAccess(name, trustee string, rights uint32) error {
+	return withManager(context.Background(), func(m *Manager) error {
+		return m.GrantAccess(name, trustee, rights)
+	})
+}
+
+// GrantAccess adds rights for trustee to the DACL of the named service,
+// like GrantServiceAccess.
+func (m *Manager) GrantAccess(name, trustee string, rights uint32) (err error) {
+	defer func() {
+		m.audit("grant-access", name, map[string]string{"trustee": trustee, "rights": fmt.Sprintf("%#x", rights)}, err)
+	}()
+	s, err := m.openService(name, windows.READ_CONTROL|windows.WRITE_DAC)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	sd, err := windows.GetSecurityInfo(s.Handle, windows.SE_SERVICE, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return fmt.Errorf("could not query service security: %w", scmError(err))
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil && !errors.Is(err, windows.ERROR_OBJECT_NOT_FOUND) {
+		return err
+	}
+
+	t, err := newTrustee(trustee)
+	if err != nil {
+		return err
+	}
+	merged, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{{
+		AccessPermissions: windows.ACCESS_MASK(rights),
+		AccessMode:        windows.GRANT_ACCESS,
+		Inheritance:       windows.NO_INHERITANCE,
+		Trustee:           t,
+	}}, dacl)
+	if err != nil {
+		return fmt.Errorf("failed to grant access to %s: %w", trustee, err)
+	}
+
+	err = windows.SetSecurityInfo(s.Handle, windows.SE_SERVICE, windows.DACL_SECURITY_INFORMATION, nil, nil, merged, nil)
+	if err != nil {
+		return fmt.Errorf("could not set service security: %w", scmError(err))
+	}
+	return nil
+}
+
+// newTrustee returns a trustee for an account name or SID string.
+func newTrustee(trustee string) (windows.TRUSTEE, error) {
+	if strings.HasPrefix(trustee, "S-1-") {
+		sid, err := windows.StringToSid(trustee)
+		if err != nil {
+			return windows.TRUSTEE{}, fmt.Errorf("invalid SID %q: %w", trustee, err)
+		}
+		return windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_UNKNOWN,
+			TrusteeValue: windows.TrusteeValueFromSID(sid),
+		}, nil
+	}
+	return windows.TRUSTEE{
+		TrusteeForm:  windows.TRUSTEE_IS_NAME,
+		TrusteeType:  windows.TRUSTEE_IS_UNKNOWN,
+		TrusteeValue: windows.TrusteeValueFromString(trustee),
+	}, nil
+}
@@ -1,13 +1,20 @@
-// Package winsvc provides utilities for creating and managing Windows services.
+//go:build windows
+
 package winsvc
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/debug"
 	"golang.org/x/sys/windows/svc/eventlog"
@@ -58,225 +65,708 @@ func IsAnInteractiveSession() bool {
 // InstallService installs a Windows service with the given parameters.
 // It takes the application path, service name, display name, description, and optional parameters.
 func InstallService(appPath, name, displayName, desc string, params ...string) error {
-	m, err := mgr.Connect()
+	return InstallServiceCtx(context.Background(), appPath, name, displayName, desc, params...)
+}
+
+// InstallServiceCtx is like InstallService but honors ctx cancellation.
+func InstallServiceCtx(ctx context.Context, appPath, name, displayName, desc string, params ...string) error {
+	return InstallServiceWithOptionCtx(ctx, appPath, name, params, DisplayName(displayName), Description(desc), AutoStart())
+}
+
+// MgrConfig adapts f, which edits the mgr.Config the service is created
+// with, to a ServiceOption. ServiceOption used to be a func(*mgr.Config);
+// options written that way keep working wrapped in MgrConfig. f only sees
+// the mgr.Config fields, not the settings of options such as RunAsUser
+// that mgr.Config has no room for.
+func MgrConfig(f func(*mgr.Config)) ServiceOption {
+	return func(config *serviceConfig) {
+		c := mgr.Config(config.mgrConfig)
+		f(&c)
+		config.mgrConfig = mgrConfig(c)
+	}
+}
+
+// InstallServiceWithOption installs a Windows service with custom options.
+// It takes the application path, service name, a ServiceArgsOption function, and variadic ServiceOption functions.
+func InstallServiceWithOption(appPath, name string, serviceArgs []string, options ...ServiceOption) error {
+	return InstallServiceWithOptionCtx(context.Background(), appPath, name, serviceArgs, options...)
+}
+
+// InstallServiceWithOptionCtx is like InstallServiceWithOption but honors ctx cancellation.
+func InstallServiceWithOptionCtx(ctx context.Context, appPath, name string, serviceArgs []string, options ...ServiceOption) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.Install(ctx, appPath, name, serviceArgs, options...)
+	})
+}
+
+// Install installs a Windows service with custom options, like
+// InstallServiceWithOption.
+func (m *Manager) Install(ctx context.Context, appPath, name string, serviceArgs []string, options ...ServiceOption) (err error) {
+	config := serviceConfig{mgrConfig: mgrConfig{
+		StartType: mgr.StartAutomatic,
+	}}
+
+	// Apply all provided options
+	for _, option := range options {
+		option(&config)
+	}
+	defer func() {
+		m.audit("install", name, map[string]string{
+			"binaryPath": appPath,
+			"args":       strings.Join(serviceArgs, " "),
+			"startType":  strconv.FormatUint(uint64(config.StartType), 10),
+			"account":    config.ServiceStartName,
+		}, err)
+	}()
+
+	if err := m.checkElevation("installing a service"); err != nil {
+		return err
+	}
+	// The local version of Windows says nothing about a remote host's.
+	if m.host == "" {
+		if err := checkCapabilities(&config); err != nil {
+			return err
+		}
+	}
+
+	exists, err := serviceExists(m.m.Handle, name)
 	if err != nil {
-		return fmt.Errorf("failed to connect to service manager: %w", err)
+		return err
+	}
+	if exists {
+		if marked, _ := m.isMarkedForDeletion(name); marked {
+			return m.markedForDeletionError(name)
+		}
+		return newError(ErrServiceExists, "service %s already exists", name)
 	}
-	defer m.Disconnect()
 
-	s, err := m.OpenService(name)
-	if err == nil {
-		s.Close()
-		return fmt.Errorf("service %s already exists", name)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(config.firewallRules) > 0 {
+		if err := m.localOnly("firewall rule creation"); err != nil {
+			return err
+		}
+	}
+	if len(config.counterSets) > 0 {
+		if err := m.localOnly("performance counter registration"); err != nil {
+			return err
+		}
+	}
+	if config.virtualAccount {
+		config.ServiceStartName = virtualAccountName(name)
+	}
+	if config.verifySignature {
+		if err := m.verifyExecutable(appPath, config.publishers); err != nil {
+			return err
+		}
 	}
 
-	s, err = m.CreateService(name, appPath, mgr.Config{
-		DisplayName: displayName,
-		Description: desc,
-		StartType:   windows.SERVICE_AUTO_START,
-	}, params...)
+	cm, err := m.reopen(windows.SC_MANAGER_CONNECT | windows.SC_MANAGER_CREATE_SERVICE)
 	if err != nil {
-		return fmt.Errorf("failed to create service: %w", err)
+		return m.elevationError(err, "installing a service")
 	}
-	defer s.Close()
+	defer cm.Disconnect()
 
-	err = eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info)
+	s, err := cm.CreateService(name, appPath, mgr.Config(config.mgrConfig), serviceArgs...)
 	if err != nil {
-		s.Delete()
-		return fmt.Errorf("failed to install event logger: %w", err)
+		if errors.Is(err, windows.ERROR_SERVICE_MARKED_FOR_DELETE) {
+			return m.markedForDeletionError(name)
+		}
+		return fmt.Errorf("failed to create service: %w", scmError(err))
+	}
+	defer s.Close()
+
+	if config.password != nil {
+		if err := setServicePassword(s.Handle, config.password); err != nil {
+			s.Delete()
+			return err
+		}
+	}
+	if config.requiredPrivileges != nil {
+		if err := setRequiredPrivileges(s, config.requiredPrivileges); err != nil {
+			s.Delete()
+			return err
+		}
+	}
+	if config.recovery != nil {
+		if err := setRecovery(s, *config.recovery); err != nil {
+			s.Delete()
+			return err
+		}
+	}
+	if config.triggers != nil {
+		if err := setTriggers(s, *config.triggers); err != nil {
+			s.Delete()
+			return err
+		}
+	}
+
+	degraded := m.host == "" && DegradedMode()
+	if !degraded {
+		err = m.withRegistry(func(root registry.Key) error {
+			return installEventSource(root, name, eventlog.Error|eventlog.Warning|eventlog.Info)
+		})
+		if err != nil {
+			s.Delete()
+			return fmt.Errorf("failed to install event logger: %w", err)
+		}
+	}
+
+	if degraded && InContainer() {
+		config.firewallRules = nil
+	}
+	for i, rule := range config.firewallRules {
+		if err := addFirewallRule(rule, name, appPath); err != nil {
+			for _, added := range config.firewallRules[:i] {
+				removeFirewallRule(added.Name)
+			}
+			m.withRegistry(func(root registry.Key) error { return removeEventSource(root, name) })
+			s.Delete()
+			return err
+		}
+	}
+
+	for i, set := range config.counterSets {
+		if err := InstallCounterSet(set, appPath); err != nil {
+			for _, added := range config.counterSets[:i] {
+				UninstallCounterSet(added)
+			}
+			for _, rule := range config.firewallRules {
+				removeFirewallRule(rule.Name)
+			}
+			m.withRegistry(func(root registry.Key) error { return removeEventSource(root, name) })
+			s.Delete()
+			return err
+		}
 	}
 
 	return nil
 }
 
-// InstallServiceWithOption installs a Windows service with custom options.
-// It takes the application path, service name, a ServiceArgsOption function, and variadic ServiceOption functions.
-func InstallServiceWithOption(appPath, name string, serviceArgs []string, options ...ServiceOption) error {
-	m, err := mgr.Connect()
-	if err != nil {
-		return fmt.Errorf("failed to connect to service manager: %w", err)
+// RemoveService removes a Windows service with the given name.
+// Additional artifacts can be cleaned up with RemoveOption values. Every
+// cleanup step is attempted even if an earlier one fails, and all failures
+// are reported together in the returned error.
+func RemoveService(name string, options ...RemoveOption) error {
+	return RemoveServiceCtx(context.Background(), name, options...)
+}
+
+// RemoveServiceCtx is like RemoveService but honors ctx cancellation.
+// Cleanup steps that have not started when ctx is done are skipped.
+func RemoveServiceCtx(ctx context.Context, name string, options ...RemoveOption) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.Remove(ctx, name, options...)
+	})
+}
+
+// Remove removes a Windows service and the artifacts selected by options,
+// like RemoveServiceCtx.
+func (m *Manager) Remove(ctx context.Context, name string, options ...RemoveOption) (err error) {
+	defer func() { m.audit("remove", name, nil, err) }()
+	if err := m.checkAccess(name, windows.DELETE); err != nil {
+		return err
+	}
+	if err := m.checkElevation("removing a service"); err != nil {
+		return err
 	}
-	defer m.Disconnect()
 
-	s, err := m.OpenService(name)
-	if err == nil {
-		s.Close()
-		return fmt.Errorf("service %s already exists", name)
+	var cfg removeConfig
+	for _, option := range options {
+		option(&cfg)
 	}
 
-	config := mgr.Config{
-		StartType: mgr.StartAutomatic,
+	steps := []func() error{func() error { return m.deleteService(name) }}
+	if !cfg.keepEventSrc {
+		// Install registers no event source in degraded mode.
+		degraded := m.host == "" && DegradedMode()
+		steps = append(steps, func() error {
+			err := m.withRegistry(func(root registry.Key) error { return removeEventSource(root, name) })
+			if degraded && errors.Is(err, registry.ErrNotExist) {
+				return nil
+			}
+			return err
+		})
+	}
+	for _, logName := range cfg.eventLogs {
+		steps = append(steps, func() error {
+			return m.withRegistry(func(root registry.Key) error { return removeEventLog(root, logName) })
+		})
+	}
+	for _, rule := range cfg.firewallRules {
+		steps = append(steps, func() error {
+			if err := m.localOnly("firewall rule removal"); err != nil {
+				return err
+			}
+			return removeFirewallRule(rule)
+		})
+	}
+	for _, set := range cfg.counterSets {
+		steps = append(steps, func() error {
+			if err := m.localOnly("performance counter removal"); err != nil {
+				return err
+			}
+			return UninstallCounterSet(set)
+		})
+	}
+	for _, url := range cfg.urlACLs {
+		steps = append(steps, func() error {
+			if err := m.localOnly("url acl removal"); err != nil {
+				return err
+			}
+			return removeURLACL(url)
+		})
+	}
+	if cfg.programData {
+		dir := cfg.programDataDir
+		if dir == "" {
+			dir = name
+		}
+		steps = append(steps, func() error {
+			if err := m.localOnly("ProgramData removal"); err != nil {
+				return err
+			}
+			return removeProgramData(dir)
+		})
 	}
 
-	// Apply all provided options
-	for _, option := range options {
-		option(&config)
+	var errs []error
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		errs = append(errs, step())
 	}
 
-	s, err = m.CreateService(name, appPath, config, serviceArgs...)
+	return errors.Join(errs...)
+}
+
+func (m *Manager) deleteService(name string) error {
+	s, err := m.openService(name, windows.DELETE)
 	if err != nil {
-		return fmt.Errorf("failed to create service: %w", err)
+		if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+			return newError(ErrServiceNotFound, "service %s is not installed", name)
+		}
+		return m.elevationError(err, "removing a service")
 	}
 	defer s.Close()
 
-	err = eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info)
+	err = s.Delete()
 	if err != nil {
-		s.Delete()
-		return fmt.Errorf("failed to install event logger: %w", err)
+		return fmt.Errorf("failed to delete service: %w", scmError(err))
 	}
 
 	return nil
 }
 
-// RemoveService removes a Windows service with the given name.
-func RemoveService(name string) error {
-	m, err := mgr.Connect()
-	if err != nil {
-		return fmt.Errorf("failed to connect to service manager: %w", err)
-	}
-	defer m.Disconnect()
+// StartService starts a Windows service with the given name, passing args
+// to the service's Execute method.
+func StartService(name string, args ...string) error {
+	return StartServiceCtx(context.Background(), name, args...)
+}
+
+// StartServiceCtx is like StartService but honors ctx cancellation.
+func StartServiceCtx(ctx context.Context, name string, args ...string) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.Start(ctx, name, args...)
+	})
+}
 
-	s, err := m.OpenService(name)
+// Start starts the named service without waiting for it to run.
+func (m *Manager) Start(ctx context.Context, name string, args ...string) (err error) {
+	defer func() { m.audit("start", name, argsParams(args), err) }()
+	s, err := m.openService(name, windows.SERVICE_START)
 	if err != nil {
-		return fmt.Errorf("service %s is not installed", name)
+		return err
 	}
 	defer s.Close()
 
-	err = s.Delete()
-	if err != nil {
-		return fmt.Errorf("failed to delete service: %w", err)
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	err = eventlog.Remove(name)
+	err = s.Start(args...)
 	if err != nil {
-		return fmt.Errorf("failed to remove event logger: %w", err)
+		return fmt.Errorf("could not start service: %w", scmError(err))
 	}
 
 	return nil
 }
 
-// StartService starts a Windows service with the given name.
-func StartService(name string) error {
-	m, err := mgr.Connect()
-	if err != nil {
-		return fmt.Errorf("failed to connect to service manager: %w", err)
-	}
-	defer m.Disconnect()
+// StartServiceWait starts a Windows service and blocks until it is running
+// or ctx is done. It returns the startup latency, the time from the start
+// request until the service was seen running, so regressions in startup
+// time can be tracked. If the service stops instead, the returned error
+// carries the exit code the service reported.
+func StartServiceWait(ctx context.Context, name string, args ...string) (time.Duration, error) {
+	var latency time.Duration
+	err := withManager(ctx, func(m *Manager) error {
+		var err error
+		latency, err = m.StartWait(ctx, name, args...)
+		return err
+	})
+	return latency, err
+}
 
-	s, err := m.OpenService(name)
+// StartWait starts the named service and waits for it to run, like
+// StartServiceWait.
+func (m *Manager) StartWait(ctx context.Context, name string, args ...string) (latency time.Duration, err error) {
+	defer func() { m.auditState("start", name, argsParams(args), StateRunning, err) }()
+	s, err := m.openService(name, windows.SERVICE_START|windows.SERVICE_QUERY_STATUS)
 	if err != nil {
-		return fmt.Errorf("could not access service: %w", err)
+		return 0, err
 	}
 	defer s.Close()
 
-	err = s.Start("is", "manual-started")
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	begin := time.Now()
+	err = s.Start(args...)
 	if err != nil {
-		return fmt.Errorf("could not start service: %w", err)
+		return 0, fmt.Errorf("could not start service: %w", scmError(err))
 	}
 
-	return nil
+	if err := m.waitRunning(ctx, s); err != nil {
+		return 0, err
+	}
+	return time.Since(begin), nil
 }
 
 // StopService stops a Windows service with the given name.
 func StopService(name string) error {
-	return controlService(name, svc.Stop, svc.Stopped)
+	return withDefaults(func(ctx context.Context, m *Manager) error {
+		return m.Stop(ctx, name)
+	})
+}
+
+// StopServiceCtx stops a Windows service and waits until it is stopped or ctx is done.
+func StopServiceCtx(ctx context.Context, name string) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.Stop(ctx, name)
+	})
+}
+
+// Stop stops the named service and waits until it is stopped or ctx is done.
+func (m *Manager) Stop(ctx context.Context, name string) (err error) {
+	defer func() { m.audit("stop", name, nil, err) }()
+	return m.control(ctx, name, svc.Stop, svc.Stopped)
+}
+
+// PauseService pauses a Windows service with the given name.
+func PauseService(name string) error {
+	return withDefaults(func(ctx context.Context, m *Manager) error {
+		return m.Pause(ctx, name)
+	})
+}
+
+// PauseServiceCtx pauses a Windows service and waits until it is paused or ctx is done.
+func PauseServiceCtx(ctx context.Context, name string) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.Pause(ctx, name)
+	})
+}
+
+// Pause pauses the named service and waits until it is paused or ctx is done.
+func (m *Manager) Pause(ctx context.Context, name string) (err error) {
+	defer func() { m.audit("pause", name, nil, err) }()
+	return m.control(ctx, name, svc.Pause, svc.Paused)
+}
+
+// ContinueService resumes a paused Windows service with the given name.
+func ContinueService(name string) error {
+	return withDefaults(func(ctx context.Context, m *Manager) error {
+		return m.Continue(ctx, name)
+	})
+}
+
+// ContinueServiceCtx resumes a paused Windows service and waits until it is running or ctx is done.
+func ContinueServiceCtx(ctx context.Context, name string) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.Continue(ctx, name)
+	})
+}
+
+// Continue resumes the named service and waits until it is running or ctx is done.
+func (m *Manager) Continue(ctx context.Context, name string) (err error) {
+	defer func() { m.audit("continue", name, nil, err) }()
+	return m.control(ctx, name, svc.Continue, svc.Running)
+}
+
+// RestartService stops a Windows service, waits for it to stop, then starts it
+// again and waits for it to run. A service that is already stopped is simply
+// started, and one that is already stopping is waited on rather than sent a
+// second stop control. The timeout applies to the whole operation.
+func RestartService(name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return RestartServiceCtx(ctx, name)
+}
+
+// RestartServiceCtx is like RestartService but is bounded by ctx instead of a timeout.
+func RestartServiceCtx(ctx context.Context, name string) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.Restart(ctx, name)
+	})
+}
+
+// Restart stops and starts the named service, like RestartServiceCtx.
+func (m *Manager) Restart(ctx context.Context, name string) (err error) {
+	defer func() { m.audit("restart", name, nil, err) }()
+	err = m.stopAndWait(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	s, err := m.openService(name, windows.SERVICE_START|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	err = s.Start()
+	if err != nil {
+		return fmt.Errorf("could not start service: %w", scmError(err))
+	}
+
+	return m.waitRunning(ctx, s)
 }
 
 // QueryService returns the current status of a Windows service.
 func QueryService(name string) (string, error) {
-	m, err := mgr.Connect()
+	return QueryServiceCtx(context.Background(), name)
+}
+
+// QueryServiceCtx is like QueryService but honors ctx cancellation.
+func QueryServiceCtx(ctx context.Context, name string) (string, error) {
+	state, err := QueryServiceStateCtx(ctx, name)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to service manager: %w", err)
+		return "", err
+	}
+	if _, ok := stateNames[state]; !ok {
+		return "", fmt.Errorf("unknown service state")
 	}
-	defer m.Disconnect()
+	return state.String(), nil
+}
+
+// QueryServiceState returns the current state of a Windows service.
+func QueryServiceState(name string) (State, error) {
+	return QueryServiceStateCtx(context.Background(), name)
+}
 
-	s, err := m.OpenService(name)
+// QueryServiceStateCtx is like QueryServiceState but honors ctx cancellation.
+func QueryServiceStateCtx(ctx context.Context, name string) (State, error) {
+	var state State
+	err := withManager(ctx, func(m *Manager) error {
+		var err error
+		state, err = m.QueryState(name)
+		return err
+	})
+	return state, err
+}
+
+// QueryState returns the current state of the named service.
+func (m *Manager) QueryState(name string) (State, error) {
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS)
 	if err != nil {
-		return "", fmt.Errorf("could not access service: %w", err)
+		return 0, err
 	}
 	defer s.Close()
 
 	status, err := s.Query()
 	if err != nil {
-		return "", fmt.Errorf("could not query service status: %w", err)
-	}
-
-	switch status.State {
-	case svc.Stopped:
-		return "Stopped", nil
-	case svc.StartPending:
-		return "StartPending", nil
-	case svc.StopPending:
-		return "StopPending", nil
-	case svc.Running:
-		return "Running", nil
-	case svc.ContinuePending:
-		return "ContinuePending", nil
-	case svc.PausePending:
-		return "PausePending", nil
-	case svc.Paused:
-		return "Paused", nil
-	default:
-		return "", fmt.Errorf("unknown service state")
+		return 0, fmt.Errorf("could not query service status: %w", err)
 	}
+
+	return State(status.State), nil
 }
 
-func controlService(name string, c svc.Cmd, to svc.State) error {
-	m, err := mgr.Connect()
+func (m *Manager) control(ctx context.Context, name string, c svc.Cmd, to svc.State) error {
+	s, err := m.openService(name, controlAccess(c)|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	status, err := s.Control(c)
 	if err != nil {
-		return fmt.Errorf("failed to connect to service manager: %w", err)
+		return fmt.Errorf("could not send control=%d: %w", c, scmError(err))
 	}
-	defer m.Disconnect()
 
-	s, err := m.OpenService(name)
+	return m.waitStatus(ctx, s, status, to)
+}
+
+// WaitForState blocks until the named service reaches the given state or ctx
+// is done, whichever happens first.
+func WaitForState(ctx context.Context, name string, state State) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.WaitForState(ctx, name, state)
+	})
+}
+
+// WaitForState blocks until the named service reaches the given state or
+// ctx is done.
+func (m *Manager) WaitForState(ctx context.Context, name string, state State) error {
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS)
 	if err != nil {
-		return fmt.Errorf("could not access service: %w", err)
+		return err
 	}
 	defer s.Close()
 
-	status, err := s.Control(c)
+	status, err := s.Query()
 	if err != nil {
-		return fmt.Errorf("could not send control=%d: %w", c, err)
+		return fmt.Errorf("could not query service status: %w", err)
 	}
 
-	timeout := time.Now().Add(10 * time.Second)
-	for status.State != to {
-		if timeout.Before(time.Now()) {
-			return fmt.Errorf("timeout waiting for service to go to state=%d", to)
+	return m.waitStatus(ctx, s, status, svc.State(state))
+}
+
+var elog Logger
+
+type runConfig struct {
+	mitigations MitigationPolicy
+	noPause     bool
+	logger      Logger
+	debugPipe   bool
+	fileLog     FileLogRotation
+	journal     string
+	thresholds  HandlerThresholds
+	exit        <-chan error
+	paramChange func()
+	console     io.Reader
+	consoleOut  io.Writer
+}
+
+// ExitWhen makes the service stop on its own when a value is received from
+// exit, as if it had been asked to stop: it reports StopPending, calls the
+// stop function and exits. A non-nil error is reported to the service
+// control manager as a service-specific exit code, which lets recovery
+// actions restart the service; the code is the error's ExitCode() if it
+// has that method, as *exec.ExitError does, and 1 otherwise.
+func ExitWhen(exit <-chan error) RunOption {
+	return func(c *runConfig) {
+		c.exit = exit
+	}
+}
+
+// OnParamChange makes the service accept SERVICE_CONTROL_PARAMCHANGE
+// requests, sent by "sc paramchange" after its parameters were changed,
+// and call f for each one. f should reload the service's configuration
+// and return quickly.
+func OnParamChange(f func()) RunOption {
+	return func(c *runConfig) {
+		c.paramChange = f
+	}
+}
+
+// NoPauseContinue makes the service refuse pause and continue requests,
+// for services that cannot meaningfully be paused.
+func NoPauseContinue() RunOption {
+	return func(c *runConfig) {
+		c.noPause = true
+	}
+}
+
+// RunAsService runs the provided start and stop functions as a Windows service.
+// It takes the service name, start function, stop function, a debug flag,
+// and optional RunOption values.
+func RunAsService(name string, start, stop func(), isDebug bool, options ...RunOption) (err error) {
+	var cfg runConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	switch {
+	case cfg.logger != nil:
+		elog = cfg.logger
+	case isDebug:
+		l := debug.New(name)
+		defer l.Close()
+		elog = l
+	case DegradedMode():
+		fl, err := openFallbackLog(name, cfg.fileLog)
+		if err != nil {
+			return fmt.Errorf("failed to open file log: %w", err)
 		}
-		time.Sleep(300 * time.Millisecond)
-		status, err = s.Query()
+		defer fl.Close()
+		elog = fl
+	default:
+		l, err := eventlog.Open(name)
 		if err != nil {
-			return fmt.Errorf("could not retrieve service status: %w", err)
+			// Fall back to a file rather than refusing to run on images
+			// without a usable event log.
+			fl, ferr := openFallbackLog(name, cfg.fileLog)
+			if ferr != nil {
+				return fmt.Errorf("failed to open event log: %w", err)
+			}
+			defer fl.Close()
+			fl.Warning(1, fmt.Sprintf("event log unavailable, logging to %s: %v", fl.Path(), err))
+			elog = fl
+			break
 		}
+		defer l.Close()
+		elog = l
 	}
 
-	return nil
-}
+	var j *journal
+	if cfg.journal != "" {
+		var jerr error
+		if j, jerr = openJournal(cfg.journal, name); jerr != nil {
+			elog.Warning(1, fmt.Sprintf("%s service cannot write its journal: %v", name, jerr))
+		}
+		defer j.Close()
+		j.record(JournalEntry{Event: JournalStart})
+		defer func() {
+			e := JournalEntry{Event: JournalExit}
+			if err != nil {
+				e.Error = err.Error()
+				var errno windows.Errno
+				if errors.As(err, &errno) {
+					e.ExitCode = uint32(errno)
+				}
+			}
+			j.record(e)
+		}()
+	}
 
-var elog debug.Log
+	if cfg.mitigations != 0 {
+		if err := applyMitigations(cfg.mitigations); err != nil {
+			elog.Error(1, fmt.Sprintf("%s service failed: %v", name, err))
+			return err
+		}
+	}
 
-// RunAsService runs the provided start and stop functions as a Windows service.
-// It takes the service name, start function, stop function, and a debug flag.
-func RunAsService(name string, start, stop func(), isDebug bool) error {
-	var err error
-	if isDebug {
-		elog = debug.New(name)
-	} else {
-		elog, err = eventlog.Open(name)
+	if cfg.debugPipe {
+		pipe, err := serveDebugPipe(name)
 		if err != nil {
-			return fmt.Errorf("failed to open event log: %w", err)
+			elog.Warning(1, fmt.Sprintf("%s service cannot serve debug variables: %v", name, err))
+		} else {
+			defer pipe.Close()
 		}
 	}
-	defer elog.Close()
 
 	run := svc.Run
-	if isDebug {
+	switch {
+	case isDebug && cfg.console != nil:
+		run = func(name string, handler svc.Handler) error {
+			return RunSimulated(name, handler, cfg.console, cfg.consoleOut)
+		}
+	case isDebug:
 		run = debug.Run
 	}
 
 	elog.Info(1, fmt.Sprintf("starting %s service", name))
-	err = run(name, &winService{start: start, stop: stop})
+	ws := &winService{name: name, start: start, stop: stop, accepts: svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue,
+		journal: j, thresholds: cfg.thresholds.withDefaults(), exit: cfg.exit, paramChange: cfg.paramChange}
+	if cfg.noPause {
+		ws.accepts &^= svc.AcceptPauseAndContinue
+	}
+	if cfg.paramChange != nil {
+		ws.accepts |= svc.AcceptParamChange
+	}
+	err = run(name, ws)
 	if err != nil {
 		elog.Error(1, fmt.Sprintf("%s service failed: %v", name, err))
 		return fmt.Errorf("service run failed: %w", err)
@@ -286,35 +776,96 @@ func RunAsService(name string, start, stop func(), isDebug bool) error {
 }
 
 type winService struct {
-	start func()
-	stop  func()
+	name        string
+	start       func()
+	stop        func()
+	accepts     svc.Accepted
+	journal     *journal
+	thresholds  HandlerThresholds
+	exit        <-chan error
+	paramChange func()
 }
 
 func (s *winService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
-	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue
+	cmdsAccepted := s.accepts
 	changes <- svc.Status{State: svc.StartPending}
 	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
+	s.reportState(svc.Running)
+	s.journal.record(JournalEntry{Event: JournalReady})
+	s.logStartupLatency()
+
+	go s.runStart()
+
+	for {
+		var c svc.ChangeRequest
+		var ok bool
+		select {
+		case c, ok = <-r:
+			if !ok {
+				return false, 0
+			}
+		case err := <-s.exit:
+			if err != nil {
+				elog.Error(1, fmt.Sprintf("%s service is exiting: %v", s.name, err))
+			}
+			s.stopService(changes)
+			return exitCode(err)
+		}
 
-	go s.start()
-
-	for c := range r {
+		if c.Cmd != svc.Interrogate {
+			traceControl(s.name, c.Cmd)
+			s.journal.control(c.Cmd)
+		}
 		switch c.Cmd {
 		case svc.Interrogate:
 			changes <- c.CurrentStatus
 			time.Sleep(100 * time.Millisecond)
 			changes <- c.CurrentStatus
 		case svc.Stop, svc.Shutdown:
-			changes <- svc.Status{State: svc.StopPending}
-			s.stop()
+			s.stopService(changes)
 			return false, 0
 		case svc.Pause:
+			began := time.Now()
 			changes <- svc.Status{State: svc.Paused, Accepts: cmdsAccepted}
+			s.reportState(svc.Paused)
+			s.logControlLatency(c.Cmd, time.Since(began))
 		case svc.Continue:
+			began := time.Now()
 			changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
+			s.reportState(svc.Running)
+			s.logControlLatency(c.Cmd, time.Since(began))
+		case svc.ParamChange:
+			began := time.Now()
+			s.paramChange()
+			s.logControlLatency(c.Cmd, time.Since(began))
 		default:
-			elog.Error(1, fmt.Sprintf("unexpected control request #%d", c))
+			elog.Error(1, fmt.Sprintf("unexpected control request %s", ControlName(uint32(c.Cmd))))
 		}
 	}
+}
+
+// stopService reports StopPending and runs the stop function.
+func (s *winService) stopService(changes chan<- svc.Status) {
+	changes <- svc.Status{State: svc.StopPending}
+	s.reportState(svc.StopPending)
+	s.journal.record(JournalEntry{Event: JournalStopBegin})
+	began := time.Now()
+	s.stop()
+	took := time.Since(began)
+	traceStopDuration(s.name, took)
+	s.logStopDuration(took)
+	s.journal.record(JournalEntry{Event: JournalStopEnd, DurationMs: took.Milliseconds()})
+}
 
-	return false, 0
+// exitCode returns the Execute results that report err to the service
+// control manager.
+func exitCode(err error) (bool, uint32) {
+	if err == nil {
+		return false, 0
+	}
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) && coder.ExitCode() > 0 {
+		return true, uint32(coder.ExitCode())
+	}
+	return true, 1
 }
@@ -3,16 +3,533 @@
 // license that can be found in the LICENSE file.
 
 //go:build !windows
-// +build !windows
+
+// This file declares the functions and opaque types of the package for
+// platforms other than Windows, so that cross-platform programs build; the
+// data types and constants are shared with Windows. Functions and methods
+// returning an error return ErrUnsupportedPlatform, others return zero
+// values, InServiceMode reports false and IsAnInteractiveSession true.
+// See the Windows build for the documentation.
 
 package winsvc
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
 )
 
+type EventLogAuditor struct {
+}
+
+type FileAuditor struct {
+}
+
+type ChannelWriter struct {
+}
+
+type FileLogger struct {
+}
+
+type RotatingFile struct {
+}
+
+type InstallFlags struct{}
+
+type ServiceFlags struct{}
+
+type Forwarder struct {
+}
+
+type Manager struct {
+}
+
+type CounterPublisher struct {
+}
+
+type RateLimitedLogger struct {
+}
+
+type ReadOnlyManager struct {
+	*Manager
+}
+
+type connectConfig struct{}
+
+type deployConfig struct{}
+
+type forwardConfig struct{}
+
+// errno stands in for windows.Errno.
+type errno uint32
+
+func readCredentialPassword(target string) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (e errno) Error() string {
+	if name := ErrorName(uint32(e)); name != "" {
+		return name
+	}
+	return fmt.Sprintf("winapi error #%d", uint32(e))
+}
+
+type manageConfig struct{}
+
+type removeConfig struct{}
+
+type runConfig struct{}
+
+func SetAuditor(a Auditor) {
+}
+
+func MultiAuditor(auditors ...Auditor) Auditor {
+	return nil
+}
+
+func NewEventLogAuditor(source string) (*EventLogAuditor, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func NewFileAuditor(path string) (*FileAuditor, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func VerifySignature(path string, publishers ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func VerifyServiceSignature(name string, publishers ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func BatchStart(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return nil
+}
+
+func BatchStop(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return nil
+}
+
+func BatchQuery(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return nil
+}
+
+func CurrentOSVersion() OSVersion {
+	return OSVersion{}
+}
+
+func SupportsDelayedAutoStart() bool {
+	return false
+}
+
+func SupportsTriggers() bool {
+	return false
+}
+
+func SupportsProtectedServices() bool {
+	return false
+}
+
+func SupportsPerUserServices() bool {
+	return false
+}
+
+func WatchServiceCatalog(ctx context.Context) (<-chan CatalogEvent, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func InstallChannelProvider(p ChannelProvider, file string) error {
+	return ErrUnsupportedPlatform
+}
+
+func UninstallChannelProvider(p ChannelProvider) error {
+	return ErrUnsupportedPlatform
+}
+
+func OpenChannelProvider(p ChannelProvider) (*ChannelWriter, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func GetServiceConfig(name string) (ServiceConfig, error) {
+	return ServiceConfig{}, ErrUnsupportedPlatform
+}
+
+func GetServiceConfigCtx(ctx context.Context, name string) (ServiceConfig, error) {
+	return ServiceConfig{}, ErrUnsupportedPlatform
+}
+
+func InContainer() bool {
+	return false
+}
+
+func IsNanoServer() bool {
+	return false
+}
+
+func DegradedMode() bool {
+	return false
+}
+
+func DebugPipePath(name string) string {
+	return ""
+}
+
+func DebugPipe() RunOption {
+	return nil
+}
+
+func PublishGauge(name string, fn func() float64) {
+}
+
+func DumpDebugVars(name string) (map[string]json.RawMessage, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func CheckMarkedForDeletion(name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func DeployDestination(dir string) DeployOption {
+	return nil
+}
+
+func DeployServiceArgs(args ...string) DeployOption {
+	return nil
+}
+
+func DeployServiceOptions(options ...ServiceOption) DeployOption {
+	return nil
+}
+
+func DeployOverwrite() DeployOption {
+	return nil
+}
+
+func DeployStart(args ...string) DeployOption {
+	return nil
+}
+
+func DeployService(ctx context.Context, host string, exePath string, name string, options ...DeployOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func IsElevated() bool {
+	return false
+}
+
+func RelaunchElevated(args ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func ETWProviderID() GUID {
+	return GUID{}
+}
+
+func LogEvent(e Event, msg string) error {
+	return ErrUnsupportedPlatform
+}
+
+func ServiceExists(name string) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+func GetLastExitCode(name string) (ExitCode, error) {
+	return ExitCode{}, ErrUnsupportedPlatform
+}
+
+func GetFailureReason(name string) (FailureReason, error) {
+	return FailureReason{}, ErrUnsupportedPlatform
+}
+
+func WithFileLogRotation(r FileLogRotation) RunOption {
+	return nil
+}
+
+func ServiceLogDir(name string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+func NewFileLogger(dir string, name string, rotation FileLogRotation) (*FileLogger, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func NewRotatingFile(dir string, name string, rotation FileLogRotation) (*RotatingFile, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func RegisterServiceFlags(fs *flag.FlagSet) *ServiceFlags {
+	return &ServiceFlags{}
+}
+
+func ForwardFromLog(name string) ForwardOption {
+	return nil
+}
+
+func ForwardPollInterval(d time.Duration) ForwardOption {
+	return nil
+}
+
+func ForwardBufferSize(n int) ForwardOption {
+	return nil
+}
+
+func ForwardBackoff(min time.Duration, max time.Duration) ForwardOption {
+	return nil
+}
+
+func NewForwarder(source string, endpoint string, options ...ForwardOption) (*Forwarder, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func GetHardeningReport(name string) (HardeningReport, error) {
+	return HardeningReport{}, ErrUnsupportedPlatform
+}
+
+func WithJournal(path string) RunOption {
+	return nil
+}
+
+func ReadJournal(path string) ([]JournalEntry, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func SetServiceLabels(name string, labels ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func GetServiceLabels(name string) ([]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func ListServicesWithLabel(label string) ([]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func SendControlToGroup(label string, code uint32) ([]BatchResult, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func WarnSlowHandlers(t HandlerThresholds) RunOption {
+	return nil
+}
+
+func ListServices(filter ServiceFilter) ([]ServiceInfo, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func WithLogger(l Logger) RunOption {
+	return nil
+}
+
+func NewWriterLogger(w io.Writer) Logger {
+	return nil
+}
+
+func EventLogWriter(name string, level Severity) io.Writer {
+	return nil
+}
+
+func Connect(options ...ConnectOption) (*Manager, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func ConnectCtx(ctx context.Context, options ...ConnectOption) (*Manager, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func ManageServiceOptions(options ...ServiceOption) ManageOption {
+	return nil
+}
+
+func ManageRunOptions(options ...RunOption) ManageOption {
+	return nil
+}
+
+func ManageOutput(w io.Writer) ManageOption {
+	return nil
+}
+
+func Manage(name string, s Service, args []string, options ...ManageOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func WithMitigations(policies MitigationPolicy) RunOption {
+	return nil
+}
+
+func SetObserver(o Observer) {
+}
+
+func MultiObserver(observers ...Observer) Observer {
+	return nil
+}
+
+func StartServiceSet(ctx context.Context, names []string, concurrency int) ([]SetResult, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func StopServiceSet(ctx context.Context, names []string, concurrency int) ([]SetResult, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func InstallCounterSet(s CounterSet, file string) error {
+	return ErrUnsupportedPlatform
+}
+
+func UninstallCounterSet(s CounterSet) error {
+	return ErrUnsupportedPlatform
+}
+
+func RemovePerfCounters(sets ...CounterSet) RemoveOption {
+	return nil
+}
+
+func PublishCounterSet(s CounterSet) (*CounterPublisher, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func GetServiceUptime(name string) (time.Time, time.Duration, error) {
+	return time.Time{}, 0, ErrUnsupportedPlatform
+}
+
+func ProcessStartTime(pid uint32) (time.Time, error) {
+	return time.Time{}, ErrUnsupportedPlatform
+}
+
+func GetServicePID(name string) (uint32, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func GetServiceProcessStats(name string) (ProcessStats, error) {
+	return ProcessStats{}, ErrUnsupportedPlatform
+}
+
+func StopServiceForce(name string, gracePeriod time.Duration) error {
+	return ErrUnsupportedPlatform
+}
+
+func TerminateServiceProcess(name string, force bool) error {
+	return ErrUnsupportedPlatform
+}
+
+func TerminateServiceProcessCtx(ctx context.Context, name string, force bool) error {
+	return ErrUnsupportedPlatform
+}
+
+func SetProtectedParameter(name string, key string, value []byte) error {
+	return ErrUnsupportedPlatform
+}
+
+func GetProtectedParameter(name string, key string) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func GetProtectionLevel(name string) (ProtectionLevel, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func SetProtectionLevel(name string, level ProtectionLevel) error {
+	return ErrUnsupportedPlatform
+}
+
+func NewRateLimitedLogger(l Logger, window time.Duration, perMinute int) *RateLimitedLogger {
+	return nil
+}
+
+func ConnectReadOnly() (*ReadOnlyManager, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func ConnectReadOnlyCtx(ctx context.Context) (*ReadOnlyManager, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func GetRecoveryConfig(name string) (RecoveryConfig, error) {
+	return RecoveryConfig{}, ErrUnsupportedPlatform
+}
+
+func GetDelayedAutoStart(name string) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+func DefaultWaitPolicy() WaitPolicy {
+	return WaitPolicy{}
+}
+
+func ConnectAs(user string, password []byte) ConnectOption {
+	return nil
+}
+
+func ConnectRemote(host string, options ...ConnectOption) (*Manager, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func ConnectRemoteCtx(ctx context.Context, host string, options ...ConnectOption) (*Manager, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func RemoveParameters() RemoveOption {
+	return nil
+}
+
+func RemoveEnvironment() RemoveOption {
+	return nil
+}
+
+func KeepEventSource() RemoveOption {
+	return nil
+}
+
+func RemoveEventLog(logNames ...string) RemoveOption {
+	return nil
+}
+
+func RemoveFirewallRules(ruleNames ...string) RemoveOption {
+	return nil
+}
+
+func RemoveURLACLs(urls ...string) RemoveOption {
+	return nil
+}
+
+func RemoveProgramData() RemoveOption {
+	return nil
+}
+
+func RemoveProgramDataDir(dir string) RemoveOption {
+	return nil
+}
+
+func IsImagePathBroken(name string) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+func RepairService(name string, correctPath string) error {
+	return ErrUnsupportedPlatform
+}
+
+func ReconfigureService(name string, options ...ServiceOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func GetServiceSecurity(name string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+func SetServiceSecurity(name string, sddl string) error {
+	return ErrUnsupportedPlatform
+}
+
+func GrantServiceAccess(name string, trustee string, rights uint32) error {
+	return ErrUnsupportedPlatform
+}
+
 func GetAppPath() (string, error) {
 	prog := os.Args[0]
 	p, err := filepath.Abs(prog)
@@ -40,29 +557,512 @@ func GetAppPath() (string, error) {
 }
 
 func InServiceMode() bool {
-	panic("winsvc: only support windows!")
+	return false
 }
+
 func IsAnInteractiveSession() bool {
-	panic("winsvc: only support windows!")
+	return true
+}
+
+func InstallService(appPath string, name string, displayName string, desc string, params ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func InstallServiceCtx(ctx context.Context, appPath string, name string, displayName string, desc string, params ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func InstallServiceWithOption(appPath string, name string, serviceArgs []string, options ...ServiceOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func InstallServiceWithOptionCtx(ctx context.Context, appPath string, name string, serviceArgs []string, options ...ServiceOption) error {
+	return ErrUnsupportedPlatform
 }
-func InstallService(appPath, name, desc string, params ...string) error {
-	panic("winsvc: only support windows!")
+
+func RemoveService(name string, options ...RemoveOption) error {
+	return ErrUnsupportedPlatform
 }
-func InstallServiceWithOption(appPath, name string, params ServiceArgsOption, option ...ServiceOption) error {
-	panic("winsvc: only support windows!")
+
+func RemoveServiceCtx(ctx context.Context, name string, options ...RemoveOption) error {
+	return ErrUnsupportedPlatform
 }
-func RemoveService(name string) error {
-	panic("winsvc: only support windows!")
+
+func StartService(name string, args ...string) error {
+	return ErrUnsupportedPlatform
 }
-func RunAsService(name string, start, stop func(), isDebug bool) (err error) {
-	panic("winsvc: only support windows!")
+
+func StartServiceCtx(ctx context.Context, name string, args ...string) error {
+	return ErrUnsupportedPlatform
 }
-func StartService(name string) error {
-	panic("winsvc: only support windows!")
+
+func StartServiceWait(ctx context.Context, name string, args ...string) (time.Duration, error) {
+	return 0, ErrUnsupportedPlatform
 }
+
 func StopService(name string) error {
-	panic("winsvc: only support windows!")
+	return ErrUnsupportedPlatform
+}
+
+func StopServiceCtx(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func PauseService(name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func PauseServiceCtx(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func ContinueService(name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func ContinueServiceCtx(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func RestartService(name string, timeout time.Duration) error {
+	return ErrUnsupportedPlatform
+}
+
+func RestartServiceCtx(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func QueryService(name string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+func QueryServiceCtx(ctx context.Context, name string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+func QueryServiceState(name string) (State, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func QueryServiceStateCtx(ctx context.Context, name string) (State, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func WaitForState(ctx context.Context, name string, state State) error {
+	return ErrUnsupportedPlatform
+}
+
+func ExitWhen(exit <-chan error) RunOption {
+	return nil
+}
+
+func Simulate(console io.Reader, out io.Writer) RunOption {
+	return nil
+}
+
+func OnParamChange(f func()) RunOption {
+	return nil
+}
+
+func NoPauseContinue() RunOption {
+	return nil
+}
+
+func RunAsService(name string, start func(), stop func(), isDebug bool, options ...RunOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func QueryServiceEx(name string) (ServiceStatus, error) {
+	return ServiceStatus{}, ErrUnsupportedPlatform
+}
+
+func QueryServiceExCtx(ctx context.Context, name string) (ServiceStatus, error) {
+	return ServiceStatus{}, ErrUnsupportedPlatform
+}
+
+func StopServiceWithReason(name string, reason StopReason, comment string) error {
+	return ErrUnsupportedPlatform
+}
+
+func StopServiceWithReasonCtx(ctx context.Context, name string, reason StopReason, comment string) error {
+	return ErrUnsupportedPlatform
 }
-func QueryService(name string) (status string, err error) {
-	panic("winsvc: only support windows!")
+
+func LogStructured(l Logger, severity Severity, eid uint32, msg string, fields map[string]any) error {
+	return ErrUnsupportedPlatform
+}
+
+func ParseStructuredEvent(message string) (StructuredEvent, error) {
+	return StructuredEvent{}, ErrUnsupportedPlatform
+}
+
+func ReadStructuredEvents(source string, max int) ([]LoggedEvent, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func StopServiceTree(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func ListDependentServices(name string, recursive bool) ([]DependentService, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func StartServiceTree(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func WithWaitPolicy(policy WaitPolicy) ConnectOption {
+	return nil
+}
+
+func WatchService(ctx context.Context, name string) (<-chan StatusEvent, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (r AuditRecord) MarshalJSON() ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (a *EventLogAuditor) Audit(r AuditRecord) {
+}
+
+func (a *EventLogAuditor) Close() error {
+	return ErrUnsupportedPlatform
+}
+
+func (a *FileAuditor) Audit(r AuditRecord) {
+}
+
+func (a *FileAuditor) Close() error {
+	return ErrUnsupportedPlatform
+}
+
+func (r BatchResult) MarshalJSON() ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (p ChannelProvider) Manifest(file string) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (w *ChannelWriter) Write(id uint16, message string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (w *ChannelWriter) Close() error {
+	return ErrUnsupportedPlatform
+}
+
+func (e Event) String() string {
+	return ""
+}
+
+func (e Event) Log(l Logger, msg string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (c ExitCode) Err() error {
+	return ErrUnsupportedPlatform
+}
+
+func (l *FileLogger) Path() string {
+	return ""
+}
+
+func (l *FileLogger) Info(eid uint32, msg string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (l *FileLogger) Warning(eid uint32, msg string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (l *FileLogger) Error(eid uint32, msg string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (l *FileLogger) Close() error {
+	return ErrUnsupportedPlatform
+}
+
+func (r *RotatingFile) Path() string {
+	return ""
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func (r *RotatingFile) Close() error {
+	return ErrUnsupportedPlatform
+}
+
+func (f *InstallFlags) Register(fs *flag.FlagSet, prefix string) {}
+
+func (f *InstallFlags) Install(name string, runArgs []string, options ...ManageOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func (f *ServiceFlags) Requested() bool {
+	return false
+}
+
+func (f *ServiceFlags) Run(name string, s Service, args []string, options ...ManageOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func (f *Forwarder) Dropped() uint64 {
+	return 0
+}
+
+func (f *Forwarder) Run(ctx context.Context) error {
+	return ErrUnsupportedPlatform
+}
+
+func (r HardeningReport) String() string {
+	return ""
+}
+
+func (m *Manager) VerifySignature(name string, publishers ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) BatchStart(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return nil
+}
+
+func (m *Manager) BatchStop(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return nil
+}
+
+func (m *Manager) BatchQuery(ctx context.Context, names []string, concurrency int) []BatchResult {
+	return nil
+}
+
+func (m *Manager) Config(name string) (ServiceConfig, error) {
+	return ServiceConfig{}, ErrUnsupportedPlatform
+}
+
+func (m *Manager) Deploy(ctx context.Context, exePath string, name string, options ...DeployOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Exists(name string) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+func (m *Manager) FailureReason(name string) (FailureReason, error) {
+	return FailureReason{}, ErrUnsupportedPlatform
+}
+
+func (m *Manager) HardeningReport(name string) (HardeningReport, error) {
+	return HardeningReport{}, ErrUnsupportedPlatform
+}
+
+func (m *Manager) SetLabels(name string, labels ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Labels(name string) ([]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (m *Manager) ServicesWithLabel(label string) ([]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (m *Manager) SendControlToGroup(ctx context.Context, label string, code uint32) ([]BatchResult, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (m *Manager) List(filter ServiceFilter) ([]ServiceInfo, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (m *Manager) Host() string {
+	return ""
+}
+
+func (m *Manager) Disconnect() error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) SetObserver(o Observer) {
+}
+
+func (m *Manager) StartSet(ctx context.Context, names []string, concurrency int) ([]SetResult, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (m *Manager) StopSet(ctx context.Context, names []string, concurrency int) ([]SetResult, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (m *Manager) PID(name string) (uint32, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func (m *Manager) ProcessStats(name string) (ProcessStats, error) {
+	return ProcessStats{}, ErrUnsupportedPlatform
+}
+
+func (m *Manager) StopForce(name string, gracePeriod time.Duration) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) TerminateProcess(ctx context.Context, name string, force bool) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) SetProtectedParameter(name string, key string, value []byte) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) ProtectedParameter(name string, key string) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (m *Manager) ProtectionLevel(name string) (ProtectionLevel, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func (m *Manager) SetProtectionLevel(name string, level ProtectionLevel) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) RecoveryConfig(name string) (RecoveryConfig, error) {
+	return RecoveryConfig{}, ErrUnsupportedPlatform
+}
+
+func (m *Manager) IsImagePathBroken(name string) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+func (m *Manager) Repair(name string, correctPath string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Reconfigure(name string, options ...ServiceOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Security(name string) (string, error) {
+	return "", ErrUnsupportedPlatform
+}
+
+func (m *Manager) SetSecurity(name string, sddl string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) GrantAccess(name string, trustee string, rights uint32) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Install(ctx context.Context, appPath string, name string, serviceArgs []string, options ...ServiceOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Remove(ctx context.Context, name string, options ...RemoveOption) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Start(ctx context.Context, name string, args ...string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) StartWait(ctx context.Context, name string, args ...string) (time.Duration, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func (m *Manager) Stop(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Pause(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Continue(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Restart(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) QueryState(name string) (State, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+func (m *Manager) WaitForState(ctx context.Context, name string, state State) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Query(name string) (ServiceStatus, error) {
+	return ServiceStatus{}, ErrUnsupportedPlatform
+}
+
+func (m *Manager) StopWithReason(ctx context.Context, name string, reason StopReason, comment string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) StopTree(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) ListDependentServices(name string, recursive bool) ([]DependentService, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (m *Manager) StartTree(ctx context.Context, name string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (m *Manager) Watch(ctx context.Context, name string) (<-chan StatusEvent, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (r SetResult) MarshalJSON() ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (s CounterSet) Manifest(file string) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (p *CounterPublisher) Set(id uint32, value uint64) error {
+	return ErrUnsupportedPlatform
+}
+
+func (p *CounterPublisher) Add(id uint32, delta uint64) error {
+	return ErrUnsupportedPlatform
+}
+
+func (p *CounterPublisher) Close() error {
+	return ErrUnsupportedPlatform
+}
+
+func (p ProcessStats) MarshalJSON() ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (l ProtectionLevel) String() string {
+	return ""
+}
+
+func (r *RateLimitedLogger) Info(eid uint32, msg string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (r *RateLimitedLogger) Warning(eid uint32, msg string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (r *RateLimitedLogger) Error(eid uint32, msg string) error {
+	return ErrUnsupportedPlatform
+}
+
+func (r *RateLimitedLogger) Flush() error {
+	return ErrUnsupportedPlatform
 }
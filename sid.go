@@ -0,0 +1,35 @@
+package winsvc
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// ServiceSID returns the string form of the per-service SID of the named
+// service, the SID of the account NT SERVICE\<name>, for use in ACLs,
+// firewall rules and security descriptors. The SID is derived from the
+// name alone, so the service need not be installed or have a SidType set.
+func ServiceSID(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("service name is empty")
+	}
+
+	// The SID is S-1-5-80 followed by the SHA-1 hash of the upper-cased
+	// UTF-16LE name, read as five little-endian sub-authorities.
+	units := utf16.Encode([]rune(strings.ToUpper(name)))
+	buf := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[2*i:], u)
+	}
+	sum := sha1.Sum(buf)
+
+	var b strings.Builder
+	b.WriteString("S-1-5-80")
+	for i := 0; i < len(sum); i += 4 {
+		fmt.Fprintf(&b, "-%d", binary.LittleEndian.Uint32(sum[i:]))
+	}
+	return b.String(), nil
+}
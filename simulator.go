@@ -0,0 +1,303 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+// Simulate makes RunAsService, in debug mode, run the service in a local
+// simulator of the service control manager instead of only stopping it on
+// Ctrl+C. The simulator reads commands from console, one per line, sends
+// the corresponding control requests to the service, and writes the
+// status changes the service reports to out. It has no effect outside
+// debug mode. See RunSimulated for the commands.
+func Simulate(console io.Reader, out io.Writer) RunOption {
+	return func(c *runConfig) {
+		c.console = console
+		c.consoleOut = out
+	}
+}
+
+// simulatorCommands maps the simple commands of the simulator to the
+// control they send.
+var simulatorCommands = map[string]svc.Cmd{
+	"stop":        svc.Stop,
+	"shutdown":    svc.Shutdown,
+	"preshutdown": svc.PreShutdown,
+	"pause":       svc.Pause,
+	"continue":    svc.Continue,
+	"interrogate": svc.Interrogate,
+	"paramchange": svc.ParamChange,
+}
+
+// sessionEvents maps the session change events of the simulator's
+// sessionchange command to their WTS_* codes.
+var sessionEvents = map[string]uint32{
+	"console-connect":    windows.WTS_CONSOLE_CONNECT,
+	"console-disconnect": windows.WTS_CONSOLE_DISCONNECT,
+	"remote-connect":     windows.WTS_REMOTE_CONNECT,
+	"remote-disconnect":  windows.WTS_REMOTE_DISCONNECT,
+	"logon":              windows.WTS_SESSION_LOGON,
+	"logoff":             windows.WTS_SESSION_LOGOFF,
+	"lock":               windows.WTS_SESSION_LOCK,
+	"unlock":             windows.WTS_SESSION_UNLOCK,
+	"remote-control":     windows.WTS_SESSION_REMOTE_CONTROL,
+	"create":             windows.WTS_SESSION_CREATE,
+	"terminate":          windows.WTS_SESSION_TERMINATE,
+}
+
+// acceptedBy maps the controls a service must accept to receive them to
+// the flag accepting them.
+var acceptedBy = map[svc.Cmd]svc.Accepted{
+	svc.Stop:          svc.AcceptStop,
+	svc.Shutdown:      svc.AcceptShutdown,
+	svc.PreShutdown:   svc.AcceptPreShutdown,
+	svc.Pause:         svc.AcceptPauseAndContinue,
+	svc.Continue:      svc.AcceptPauseAndContinue,
+	svc.ParamChange:   svc.AcceptParamChange,
+	svc.SessionChange: svc.AcceptSessionChange,
+}
+
+const simulatorHelp = `commands:
+  stop, shutdown, preshutdown, pause, continue, interrogate, paramchange
+  custom <128-255>                 send a custom control
+  control <code>                   send any control code
+  sessionchange <event> [session]  send a session change, event being one of
+                                   %s
+  status                           print the current status
+  help                             print this help
+`
+
+// RunSimulated runs handler as the named service in a local simulator of
+// the service control manager, like debug.Run but driven by commands read
+// from console, one per line:
+//
+//	stop, shutdown, preshutdown, pause, continue, interrogate, paramchange
+//	custom <128-255>
+//	control <code>
+//	sessionchange <event> [session]
+//	status
+//	help
+//
+// The event of sessionchange is one of logon, logoff, lock, unlock,
+// console-connect, console-disconnect, remote-connect, remote-disconnect,
+// remote-control, create and terminate; the session defaults to 1. Like the
+// service control manager, the simulator only sends a control the service
+// accepts in its current state, and reports why it refused others. Every
+// status the service reports is written to out. Ctrl+C, and the end of
+// console, send a stop request.
+//
+// RunSimulated returns once the handler does, with an error if it reported
+// an exit code.
+func RunSimulated(name string, handler svc.Handler, console io.Reader, out io.Writer) error {
+	cmds := make(chan svc.ChangeRequest)
+	changes := make(chan svc.Status)
+	type result struct {
+		ssec  bool
+		errno uint32
+	}
+	done := make(chan result, 1)
+	go func() {
+		ssec, errno := handler.Execute([]string{name}, cmds, changes)
+		done <- result{ssec, errno}
+	}()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(console)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	fmt.Fprintf(out, "simulating service %s; type help for the commands\n", name)
+	status := svc.Status{State: svc.Stopped}
+	// session is the notification the last sessionchange command points
+	// the service to; it stays referenced while the service may read it.
+	var session windows.WTSSESSION_NOTIFICATION
+	var pending []svc.ChangeRequest
+	for {
+		var send chan<- svc.ChangeRequest
+		var next svc.ChangeRequest
+		if len(pending) > 0 {
+			send, next = cmds, pending[0]
+			next.CurrentStatus = status
+		}
+
+		select {
+		case r := <-done:
+			fmt.Fprintf(out, "service %s exited\n", name)
+			if r.errno == 0 {
+				return nil
+			}
+			if r.ssec {
+				return ExitCode{Win32ExitCode: uint32(windows.ERROR_SERVICE_SPECIFIC_ERROR), ServiceSpecificExitCode: r.errno}.Err()
+			}
+			return windows.Errno(r.errno)
+		case status = <-changes:
+			fmt.Fprintf(out, "service %s\n", describeStatus(status))
+		case send <- next:
+			pending = pending[1:]
+		case <-sig:
+			pending = append(pending, svc.ChangeRequest{Cmd: svc.Stop})
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(out, "console closed; stopping the service")
+				lines = nil
+				pending = append(pending, svc.ChangeRequest{Cmd: svc.Stop})
+				continue
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "":
+				continue
+			case "status":
+				fmt.Fprintf(out, "service %s\n", describeStatus(status))
+				continue
+			case "help", "?":
+				fmt.Fprint(out, simulatorUsage())
+				continue
+			}
+			r, err := parseSimulatorCommand(line, &session)
+			if err == nil {
+				err = checkAccepted(status, r.Cmd)
+			}
+			if err != nil {
+				fmt.Fprintln(out, err)
+				continue
+			}
+			pending = append(pending, r)
+		}
+	}
+}
+
+// parseSimulatorCommand parses a control command typed into the
+// simulator. session is filled in for sessionchange.
+func parseSimulatorCommand(line string, session *windows.WTSSESSION_NOTIFICATION) (svc.ChangeRequest, error) {
+	fields := strings.Fields(strings.ToLower(line))
+	if c, ok := simulatorCommands[fields[0]]; ok && len(fields) == 1 {
+		return svc.ChangeRequest{Cmd: c}, nil
+	}
+
+	switch fields[0] {
+	case "custom", "control":
+		if len(fields) != 2 {
+			return svc.ChangeRequest{}, fmt.Errorf("usage: %s <code>", fields[0])
+		}
+		code, err := strconv.ParseUint(fields[1], 0, 32)
+		if err != nil {
+			return svc.ChangeRequest{}, fmt.Errorf("invalid control code %q", fields[1])
+		}
+		if fields[0] == "custom" && (code < 128 || code > 255) {
+			return svc.ChangeRequest{}, fmt.Errorf("custom control codes are between 128 and 255, not %d", code)
+		}
+		return svc.ChangeRequest{Cmd: svc.Cmd(code)}, nil
+	case "sessionchange":
+		if len(fields) < 2 || len(fields) > 3 {
+			return svc.ChangeRequest{}, fmt.Errorf("usage: sessionchange <event> [session]")
+		}
+		event, ok := sessionEvents[fields[1]]
+		if !ok {
+			return svc.ChangeRequest{}, fmt.Errorf("unknown session change event %q; type help for the events", fields[1])
+		}
+		id := uint64(1)
+		if len(fields) == 3 {
+			var err error
+			if id, err = strconv.ParseUint(fields[2], 10, 32); err != nil {
+				return svc.ChangeRequest{}, fmt.Errorf("invalid session %q", fields[2])
+			}
+		}
+		*session = windows.WTSSESSION_NOTIFICATION{
+			Size:      uint32(unsafe.Sizeof(*session)),
+			SessionID: uint32(id),
+		}
+		return svc.ChangeRequest{
+			Cmd:       svc.SessionChange,
+			EventType: event,
+			EventData: uintptr(unsafe.Pointer(session)),
+		}, nil
+	}
+	return svc.ChangeRequest{}, fmt.Errorf("unknown command %q; type help for the commands", fields[0])
+}
+
+// simulatorUsage returns the help text of the simulator.
+func simulatorUsage() string {
+	var events []string
+	for event := range sessionEvents {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	return fmt.Sprintf(simulatorHelp, strings.Join(events, ", "))
+}
+
+// checkAccepted returns an error if a service with the given status would
+// not be sent c by the service control manager.
+func checkAccepted(status svc.Status, c svc.Cmd) error {
+	if c == svc.Interrogate {
+		return nil
+	}
+	if status.State != svc.Running && status.State != svc.Paused {
+		return fmt.Errorf("service cannot accept %s while %v: %w", ControlName(uint32(c)), State(status.State), windows.ERROR_SERVICE_CANNOT_ACCEPT_CTRL)
+	}
+	if flag, ok := acceptedBy[c]; ok && status.Accepts&flag == 0 {
+		return fmt.Errorf("service does not accept %s: %w", ControlName(uint32(c)), windows.ERROR_INVALID_SERVICE_CONTROL)
+	}
+	return nil
+}
+
+// describeStatus formats a status reported by the service for the
+// simulator.
+func describeStatus(status svc.Status) string {
+	s := State(status.State).String()
+	if status.State == svc.StartPending || status.State == svc.StopPending ||
+		status.State == svc.PausePending || status.State == svc.ContinuePending {
+		s += fmt.Sprintf(" (checkpoint %d, wait hint %dms)", status.CheckPoint, status.WaitHint)
+	}
+	if status.State == svc.Stopped && status.Win32ExitCode != 0 {
+		s += fmt.Sprintf(" (exit code %d)", status.Win32ExitCode)
+	}
+	if status.Accepts != 0 {
+		s += ", accepting " + describeAccepts(status.Accepts)
+	}
+	return s
+}
+
+var acceptNames = []struct {
+	flag svc.Accepted
+	name string
+}{
+	{svc.AcceptStop, "stop"},
+	{svc.AcceptShutdown, "shutdown"},
+	{svc.AcceptPreShutdown, "preshutdown"},
+	{svc.AcceptPauseAndContinue, "pause/continue"},
+	{svc.AcceptParamChange, "paramchange"},
+	{svc.AcceptSessionChange, "sessionchange"},
+	{svc.AcceptPowerEvent, "powerevent"},
+	{svc.AcceptHardwareProfileChange, "hardwareprofilechange"},
+}
+
+func describeAccepts(accepts svc.Accepted) string {
+	var names []string
+	for _, a := range acceptNames {
+		if accepts&a.flag != 0 {
+			names = append(names, a.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
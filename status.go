@@ -0,0 +1,70 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// QueryServiceEx returns the detailed status of a Windows service.
+func QueryServiceEx(name string) (ServiceStatus, error) {
+	return QueryServiceExCtx(context.Background(), name)
+}
+
+// QueryServiceExCtx is like QueryServiceEx but honors ctx cancellation.
+func QueryServiceExCtx(ctx context.Context, name string) (ServiceStatus, error) {
+	var status ServiceStatus
+	err := withManager(ctx, func(m *Manager) error {
+		var err error
+		status, err = m.Query(name)
+		return err
+	})
+	return status, err
+}
+
+// Query returns the detailed status of the named service, like
+// QueryServiceEx.
+func (m *Manager) Query(name string) (ServiceStatus, error) {
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+	defer s.Close()
+
+	status, err := queryStatus(s)
+	if err != nil {
+		return ServiceStatus{}, fmt.Errorf("could not query service status: %w", err)
+	}
+
+	return status, nil
+}
+
+func queryStatus(s *mgr.Service) (ServiceStatus, error) {
+	var p windows.SERVICE_STATUS_PROCESS
+	var needed uint32
+	err := windows.QueryServiceStatusEx(s.Handle, windows.SC_STATUS_PROCESS_INFO, (*byte)(unsafe.Pointer(&p)), uint32(unsafe.Sizeof(p)), &needed)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	return newServiceStatus(&p), nil
+}
+
+func newServiceStatus(p *windows.SERVICE_STATUS_PROCESS) ServiceStatus {
+	return ServiceStatus{
+		State:                   State(p.CurrentState),
+		ProcessID:               p.ProcessId,
+		ServiceType:             p.ServiceType,
+		ControlsAccepted:        Accepted(p.ControlsAccepted),
+		Win32ExitCode:           p.Win32ExitCode,
+		ServiceSpecificExitCode: p.ServiceSpecificExitCode,
+		CheckPoint:              p.CheckPoint,
+		WaitHint:                p.WaitHint,
+		RunsInSystemProcess:     p.ServiceFlags&windows.SERVICE_RUNS_IN_SYSTEM_PROCESS != 0,
+	}
+}
@@ -0,0 +1,64 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+// maxStopReasonComment is the longest comment, in characters, that the
+// service control manager accepts with a stop reason.
+const maxStopReasonComment = 128
+
+// StopServiceWithReason stops a Windows service, recording reason and an
+// optional comment of up to 128 characters in the System event log, and
+// waits for it to stop.
+func StopServiceWithReason(name string, reason StopReason, comment string) error {
+	return withDefaults(func(ctx context.Context, m *Manager) error {
+		return m.StopWithReason(ctx, name, reason, comment)
+	})
+}
+
+// StopServiceWithReasonCtx is like StopServiceWithReason but waits until
+// ctx is done at most.
+func StopServiceWithReasonCtx(ctx context.Context, name string, reason StopReason, comment string) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.StopWithReason(ctx, name, reason, comment)
+	})
+}
+
+// StopWithReason stops the named service with a reason code, like
+// StopServiceWithReasonCtx.
+func (m *Manager) StopWithReason(ctx context.Context, name string, reason StopReason, comment string) (err error) {
+	defer func() {
+		m.audit("stop", name, map[string]string{"reason": fmt.Sprintf("%#x", uint32(reason)), "comment": comment}, err)
+	}()
+	if len([]rune(comment)) > maxStopReasonComment {
+		return fmt.Errorf("stop reason comment is longer than %d characters", maxStopReasonComment)
+	}
+
+	s, err := m.openService(name, windows.SERVICE_STOP|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	params := serviceControlStatusReasonParams{Reason: uint32(reason)}
+	if comment != "" {
+		params.Comment, err = windows.UTF16PtrFromString(comment)
+		if err != nil {
+			return err
+		}
+	}
+	err = controlServiceEx(s.Handle, windows.SERVICE_CONTROL_STOP, serviceControlStatusReasonInfo, &params)
+	if err != nil {
+		return fmt.Errorf("could not send control=%d: %w", svc.Stop, scmError(err))
+	}
+
+	status := svc.Status{State: svc.State(params.ServiceStatus.CurrentState)}
+	return m.waitStatus(ctx, s, status, svc.Stopped)
+}
@@ -0,0 +1,90 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// LogStructured writes msg and fields to l as a StructuredEvent, so that log
+// pipelines such as Winlogbeat can decode the message as JSON.
+func LogStructured(l Logger, severity Severity, eid uint32, msg string, fields map[string]any) error {
+	data, err := json.Marshal(StructuredEvent{Message: msg, Fields: fields})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return logAt(l, severity, eid, string(data))
+}
+
+// ParseStructuredEvent decodes an event message written by LogStructured.
+// Numbers in fields are decoded as json.Number.
+func ParseStructuredEvent(message string) (StructuredEvent, error) {
+	d := json.NewDecoder(strings.NewReader(message))
+	d.UseNumber()
+	var e StructuredEvent
+	if err := d.Decode(&e); err != nil {
+		return StructuredEvent{}, fmt.Errorf("event message is not a structured event: %w", err)
+	}
+	return e, nil
+}
+
+// ReadStructuredEvents returns up to max of the newest structured events
+// that source wrote to the Application log of the local machine, newest
+// first. Events from source whose message is not a structured event are
+// skipped.
+func ReadStructuredEvents(source string, max int) ([]LoggedEvent, error) {
+	if strings.ContainsAny(source, `'"`) {
+		return nil, fmt.Errorf("invalid event source %q", source)
+	}
+	query := fmt.Sprintf(`*[System[Provider[@Name='%s']]]`, source)
+	rs, err := evtQuery(0, "Application", query, evtQueryChannelPath|evtQueryReverseDirection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query application event log: %w", err)
+	}
+	defer evtClose(rs)
+
+	var logged []LoggedEvent
+	events := make([]windows.Handle, 16)
+	for len(logged) < max {
+		n, err := evtNext(rs, events, windows.INFINITE)
+		if err != nil {
+			if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+				break
+			}
+			return logged, fmt.Errorf("failed to read application event log: %w", err)
+		}
+		for _, h := range events[:n] {
+			if len(logged) < max {
+				if e, ok := parseLoggedEvent(h); ok {
+					logged = append(logged, e)
+				}
+			}
+			evtClose(h)
+		}
+	}
+	return logged, nil
+}
+
+func parseLoggedEvent(h windows.Handle) (LoggedEvent, bool) {
+	text, err := evtRenderXML(h)
+	if err != nil {
+		return LoggedEvent{}, false
+	}
+	var x eventXML
+	if err := xml.Unmarshal([]byte(text), &x); err != nil || len(x.Data) == 0 {
+		return LoggedEvent{}, false
+	}
+	e, err := ParseStructuredEvent(x.Data[0])
+	if err != nil {
+		return LoggedEvent{}, false
+	}
+	t, _ := time.Parse(time.RFC3339Nano, x.System.TimeCreated.SystemTime)
+	return LoggedEvent{EventID: x.System.EventID, Time: t, StructuredEvent: e}, true
+}
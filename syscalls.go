@@ -0,0 +1,487 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// errno is the type of the Win32 error codes used by the files of the
+// package that build on every platform.
+type errno = windows.Errno
+
+var (
+	modadvapi32 = windows.NewLazySystemDLL("advapi32.dll")
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+	modwevtapi  = windows.NewLazySystemDLL("wevtapi.dll")
+	modmpr      = windows.NewLazySystemDLL("mpr.dll")
+	modcrypt32  = windows.NewLazySystemDLL("crypt32.dll")
+
+	procControlServiceExW   = modadvapi32.NewProc("ControlServiceExW")
+	procCredReadW           = modadvapi32.NewProc("CredReadW")
+	procCredFree            = modadvapi32.NewProc("CredFree")
+	procEventRegister       = modadvapi32.NewProc("EventRegister")
+	procEventUnregister     = modadvapi32.NewProc("EventUnregister")
+	procEventWrite          = modadvapi32.NewProc("EventWrite")
+	procEventWriteTransfer  = modadvapi32.NewProc("EventWriteTransfer")
+	procEventSetInformation = modadvapi32.NewProc("EventSetInformation")
+
+	procPerfStartProvider                  = modadvapi32.NewProc("PerfStartProvider")
+	procPerfStopProvider                   = modadvapi32.NewProc("PerfStopProvider")
+	procPerfSetCounterSetInfo              = modadvapi32.NewProc("PerfSetCounterSetInfo")
+	procPerfCreateInstance                 = modadvapi32.NewProc("PerfCreateInstance")
+	procPerfDeleteInstance                 = modadvapi32.NewProc("PerfDeleteInstance")
+	procPerfSetULongLongCounterValue       = modadvapi32.NewProc("PerfSetULongLongCounterValue")
+	procPerfIncrementULongLongCounterValue = modadvapi32.NewProc("PerfIncrementULongLongCounterValue")
+
+	procWaitForSingleObjectEx      = modkernel32.NewProc("WaitForSingleObjectEx")
+	procSetProcessMitigationPolicy = modkernel32.NewProc("SetProcessMitigationPolicy")
+	procK32GetProcessMemoryInfo    = modkernel32.NewProc("K32GetProcessMemoryInfo")
+	procGetProcessHandleCount      = modkernel32.NewProc("GetProcessHandleCount")
+
+	procEvtOpenSession = modwevtapi.NewProc("EvtOpenSession")
+	procEvtQuery       = modwevtapi.NewProc("EvtQuery")
+	procEvtNext        = modwevtapi.NewProc("EvtNext")
+	procEvtRender      = modwevtapi.NewProc("EvtRender")
+	procEvtClose       = modwevtapi.NewProc("EvtClose")
+
+	procWNetAddConnection2W    = modmpr.NewProc("WNetAddConnection2W")
+	procWNetCancelConnection2W = modmpr.NewProc("WNetCancelConnection2W")
+
+	procCryptMsgGetParam = modcrypt32.NewProc("CryptMsgGetParam")
+	procCryptMsgClose    = modcrypt32.NewProc("CryptMsgClose")
+)
+
+const (
+	serviceControlStatusReasonInfo = 1
+	evtRPCLoginClass               = 1
+	evtQueryChannelPath            = 0x1
+	evtQueryReverseDirection       = 0x200
+	evtRenderEventXML              = 1
+	cmsgSignerInfoParam            = 6
+	credTypeGeneric                = 1
+)
+
+// callErr converts the errno returned by a failed proc call into an error.
+func callErr(e syscall.Errno) error {
+	if e == 0 {
+		return syscall.EINVAL
+	}
+	return e
+}
+
+// evtRPCLogin mirrors EVT_RPC_LOGIN.
+type evtRPCLogin struct {
+	Server   *uint16
+	User     *uint16
+	Domain   *uint16
+	Password *uint16
+	Flags    uint32
+}
+
+// evtOpenSession opens an event log session on a remote host with the
+// caller's credentials.
+func evtOpenSession(host string) (windows.Handle, error) {
+	server, err := windows.UTF16PtrFromString(host)
+	if err != nil {
+		return 0, err
+	}
+	login := evtRPCLogin{Server: server}
+	r, _, e := procEvtOpenSession.Call(evtRPCLoginClass, uintptr(unsafe.Pointer(&login)), 0, 0)
+	if r == 0 {
+		return 0, callErr(e.(syscall.Errno))
+	}
+	return windows.Handle(r), nil
+}
+
+// evtQuery runs query against the log at path. A zero session queries the
+// local machine.
+func evtQuery(session windows.Handle, path, query string, flags uint32) (windows.Handle, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	q, err := windows.UTF16PtrFromString(query)
+	if err != nil {
+		return 0, err
+	}
+	r, _, e := procEvtQuery.Call(uintptr(session), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(q)), uintptr(flags))
+	if r == 0 {
+		return 0, callErr(e.(syscall.Errno))
+	}
+	return windows.Handle(r), nil
+}
+
+func evtNext(resultSet windows.Handle, events []windows.Handle, timeout uint32) (int, error) {
+	var returned uint32
+	r, _, e := procEvtNext.Call(uintptr(resultSet), uintptr(len(events)), uintptr(unsafe.Pointer(&events[0])), uintptr(timeout), 0, uintptr(unsafe.Pointer(&returned)))
+	if r == 0 {
+		return 0, callErr(e.(syscall.Errno))
+	}
+	return int(returned), nil
+}
+
+// evtRenderXML renders an event handle as its XML representation.
+func evtRenderXML(event windows.Handle) (string, error) {
+	var used, count uint32
+	buf := make([]uint16, 4096)
+	for {
+		r, _, e := procEvtRender.Call(0, uintptr(event), evtRenderEventXML, uintptr(len(buf)*2), uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&count)))
+		if r != 0 {
+			return windows.UTF16ToString(buf[:used/2]), nil
+		}
+		if e.(syscall.Errno) != windows.ERROR_INSUFFICIENT_BUFFER {
+			return "", callErr(e.(syscall.Errno))
+		}
+		buf = make([]uint16, used/2+1)
+	}
+}
+
+func evtClose(h windows.Handle) {
+	procEvtClose.Call(uintptr(h))
+}
+
+func waitForSingleObjectEx(h windows.Handle, milliseconds uint32, alertable bool) (uint32, error) {
+	var a uintptr
+	if alertable {
+		a = 1
+	}
+	r, _, e := procWaitForSingleObjectEx.Call(uintptr(h), uintptr(milliseconds), a)
+	if uint32(r) == windows.WAIT_FAILED {
+		return uint32(r), callErr(e.(syscall.Errno))
+	}
+	return uint32(r), nil
+}
+
+// netResource mirrors NETRESOURCEW.
+type netResource struct {
+	Scope       uint32
+	Type        uint32
+	DisplayType uint32
+	Usage       uint32
+	LocalName   *uint16
+	RemoteName  *uint16
+	Comment     *uint16
+	Provider    *uint16
+}
+
+// wnetAddConnection connects to remoteName as user with the UTF-8 password,
+// zeroing its UTF-16 copy afterward.
+func wnetAddConnection(remoteName, user string, password []byte) error {
+	remote, err := windows.UTF16PtrFromString(remoteName)
+	if err != nil {
+		return err
+	}
+	u, err := windows.UTF16PtrFromString(user)
+	if err != nil {
+		return err
+	}
+	p := utf16FromBytes(password)
+	defer clear(p)
+	res := netResource{RemoteName: remote}
+	r, _, _ := procWNetAddConnection2W.Call(uintptr(unsafe.Pointer(&res)), uintptr(unsafe.Pointer(&p[0])), uintptr(unsafe.Pointer(u)), 0)
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+func wnetCancelConnection(remoteName string) error {
+	remote, err := windows.UTF16PtrFromString(remoteName)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procWNetCancelConnection2W.Call(uintptr(unsafe.Pointer(remote)), 0, 1)
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+// processMemoryCountersEx mirrors PROCESS_MEMORY_COUNTERS_EX.
+type processMemoryCountersEx struct {
+	CB                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+	PrivateUsage               uintptr
+}
+
+func getProcessMemoryInfo(h windows.Handle) (processMemoryCountersEx, error) {
+	c := processMemoryCountersEx{CB: uint32(unsafe.Sizeof(processMemoryCountersEx{}))}
+	r, _, e := procK32GetProcessMemoryInfo.Call(uintptr(h), uintptr(unsafe.Pointer(&c)), uintptr(c.CB))
+	if r == 0 {
+		return c, callErr(e.(syscall.Errno))
+	}
+	return c, nil
+}
+
+func getProcessHandleCount(h windows.Handle) (uint32, error) {
+	var n uint32
+	r, _, e := procGetProcessHandleCount.Call(uintptr(h), uintptr(unsafe.Pointer(&n)))
+	if r == 0 {
+		return 0, callErr(e.(syscall.Errno))
+	}
+	return n, nil
+}
+
+// serviceControlStatusReasonParams mirrors
+// SERVICE_CONTROL_STATUS_REASON_PARAMSW.
+type serviceControlStatusReasonParams struct {
+	Reason        uint32
+	Comment       *uint16
+	ServiceStatus windows.SERVICE_STATUS_PROCESS
+}
+
+func controlServiceEx(service windows.Handle, control, infoLevel uint32, params *serviceControlStatusReasonParams) error {
+	r, _, e := procControlServiceExW.Call(uintptr(service), uintptr(control), uintptr(infoLevel), uintptr(unsafe.Pointer(params)))
+	if r == 0 {
+		return callErr(e.(syscall.Errno))
+	}
+	return nil
+}
+
+func setProcessMitigationPolicy(policy uint32, buf unsafe.Pointer, size uintptr) error {
+	r, _, e := procSetProcessMitigationPolicy.Call(uintptr(policy), uintptr(buf), size)
+	if r == 0 {
+		return callErr(e.(syscall.Errno))
+	}
+	return nil
+}
+
+// cmsgSignerInfo mirrors the leading fields of CMSG_SIGNER_INFO.
+type cmsgSignerInfo struct {
+	Version      uint32
+	Issuer       windows.CertNameBlob
+	SerialNumber windows.CryptIntegerBlob
+}
+
+// cryptMsgSignerInfo returns the CMSG_SIGNER_INFO of the first signer of
+// msg, backed by the returned buffer.
+func cryptMsgSignerInfo(msg windows.Handle) (*cmsgSignerInfo, []byte, error) {
+	var size uint32
+	r, _, e := procCryptMsgGetParam.Call(uintptr(msg), cmsgSignerInfoParam, 0, 0, uintptr(unsafe.Pointer(&size)))
+	if r == 0 {
+		return nil, nil, callErr(e.(syscall.Errno))
+	}
+	buf := make([]byte, size)
+	r, _, e = procCryptMsgGetParam.Call(uintptr(msg), cmsgSignerInfoParam, 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if r == 0 {
+		return nil, nil, callErr(e.(syscall.Errno))
+	}
+	return (*cmsgSignerInfo)(unsafe.Pointer(&buf[0])), buf, nil
+}
+
+func cryptMsgClose(msg windows.Handle) {
+	procCryptMsgClose.Call(uintptr(msg))
+}
+
+// credential mirrors CREDENTIALW.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credRead reads the generic credential stored for target. The caller must
+// release it with credFree.
+func credRead(target string) (*credential, error) {
+	t, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return nil, err
+	}
+	var cred *credential
+	r, _, e := procCredReadW.Call(uintptr(unsafe.Pointer(t)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		return nil, callErr(e.(syscall.Errno))
+	}
+	return cred, nil
+}
+
+func credFree(cred *credential) {
+	procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+}
+
+// eventDescriptor mirrors EVENT_DESCRIPTOR.
+type eventDescriptor struct {
+	ID      uint16
+	Version uint8
+	Channel uint8
+	Level   uint8
+	Opcode  uint8
+	Task    uint16
+	Keyword uint64
+}
+
+// eventDataDescriptor mirrors EVENT_DATA_DESCRIPTOR.
+type eventDataDescriptor struct {
+	Ptr      uint64
+	Size     uint32
+	Reserved uint32
+}
+
+// regHandleArgs returns the arguments that pass an ETW REGHANDLE, which is
+// 64 bits wide on every architecture. It is only for calls without pointer
+// arguments: a pointer converted to uintptr must be converted within the
+// call expression itself, so those calls spell out both cases with
+// is64Bit.
+func regHandleArgs(h uint64) []uintptr {
+	if is64Bit {
+		return []uintptr{uintptr(h)}
+	}
+	return []uintptr{uintptr(uint32(h)), uintptr(h >> 32)}
+}
+
+const is64Bit = unsafe.Sizeof(uintptr(0)) == 8
+
+func eventRegister(provider *GUID) (uint64, error) {
+	var h uint64
+	r, _, _ := procEventRegister.Call(uintptr(unsafe.Pointer(provider)), 0, 0, uintptr(unsafe.Pointer(&h)))
+	if r != 0 {
+		return 0, windows.Errno(r)
+	}
+	return h, nil
+}
+
+func eventUnregister(h uint64) error {
+	r, _, _ := procEventUnregister.Call(regHandleArgs(h)...)
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+func eventWrite(h uint64, desc *eventDescriptor, data []eventDataDescriptor) error {
+	var p *eventDataDescriptor
+	if len(data) > 0 {
+		p = &data[0]
+	}
+	var r uintptr
+	if is64Bit {
+		r, _, _ = procEventWrite.Call(uintptr(h), uintptr(unsafe.Pointer(desc)), uintptr(len(data)), uintptr(unsafe.Pointer(p)))
+	} else {
+		r, _, _ = procEventWrite.Call(uintptr(uint32(h)), uintptr(h>>32), uintptr(unsafe.Pointer(desc)), uintptr(len(data)), uintptr(unsafe.Pointer(p)))
+	}
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+func eventWriteTransfer(h uint64, desc *eventDescriptor, data []eventDataDescriptor) error {
+	var p *eventDataDescriptor
+	if len(data) > 0 {
+		p = &data[0]
+	}
+	var r uintptr
+	if is64Bit {
+		r, _, _ = procEventWriteTransfer.Call(uintptr(h), uintptr(unsafe.Pointer(desc)), 0, 0, uintptr(len(data)), uintptr(unsafe.Pointer(p)))
+	} else {
+		r, _, _ = procEventWriteTransfer.Call(uintptr(uint32(h)), uintptr(h>>32), uintptr(unsafe.Pointer(desc)), 0, 0, uintptr(len(data)), uintptr(unsafe.Pointer(p)))
+	}
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+func eventSetInformation(h uint64, class uint32, info []byte) error {
+	if len(info) == 0 {
+		return windows.ERROR_INVALID_PARAMETER
+	}
+	var r uintptr
+	if is64Bit {
+		r, _, _ = procEventSetInformation.Call(uintptr(h), uintptr(class), uintptr(unsafe.Pointer(&info[0])), uintptr(len(info)))
+	} else {
+		r, _, _ = procEventSetInformation.Call(uintptr(uint32(h)), uintptr(h>>32), uintptr(class), uintptr(unsafe.Pointer(&info[0])), uintptr(len(info)))
+	}
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+// uint64Args returns the arguments that pass a 64-bit integer by value.
+func uint64Args(v uint64) []uintptr {
+	if is64Bit {
+		return []uintptr{uintptr(v)}
+	}
+	return []uintptr{uintptr(uint32(v)), uintptr(v >> 32)}
+}
+
+func perfStartProvider(provider *GUID) (windows.Handle, error) {
+	var h windows.Handle
+	r, _, _ := procPerfStartProvider.Call(uintptr(unsafe.Pointer(provider)), 0, uintptr(unsafe.Pointer(&h)))
+	if r != 0 {
+		return 0, windows.Errno(r)
+	}
+	return h, nil
+}
+
+func perfStopProvider(h windows.Handle) error {
+	r, _, _ := procPerfStopProvider.Call(uintptr(h))
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+func perfSetCounterSetInfo(h windows.Handle, template []byte) error {
+	r, _, _ := procPerfSetCounterSetInfo.Call(uintptr(h), uintptr(unsafe.Pointer(&template[0])), uintptr(len(template)))
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+func perfCreateInstance(h windows.Handle, counterSet *GUID, name *uint16, id uint32) (uintptr, error) {
+	r, _, e := procPerfCreateInstance.Call(uintptr(h), uintptr(unsafe.Pointer(counterSet)), uintptr(unsafe.Pointer(name)), uintptr(id))
+	if r == 0 {
+		return 0, callErr(e.(syscall.Errno))
+	}
+	return r, nil
+}
+
+func perfDeleteInstance(h windows.Handle, instance uintptr) error {
+	r, _, _ := procPerfDeleteInstance.Call(uintptr(h), instance)
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+func perfSetULongLongCounterValue(h windows.Handle, instance uintptr, id uint32, v uint64) error {
+	args := append([]uintptr{uintptr(h), instance, uintptr(id)}, uint64Args(v)...)
+	r, _, _ := procPerfSetULongLongCounterValue.Call(args...)
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+func perfIncrementULongLongCounterValue(h windows.Handle, instance uintptr, id uint32, v uint64) error {
+	args := append([]uintptr{uintptr(h), instance, uintptr(id)}, uint64Args(v)...)
+	r, _, _ := procPerfIncrementULongLongCounterValue.Call(args...)
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
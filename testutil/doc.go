@@ -0,0 +1,27 @@
+// Package testutil helps write end-to-end tests against the real service
+// control manager. Install and InstallSelf install a throwaway service
+// under a unique name for a single test and remove it again when the test
+// ends, even if it failed or panicked; the returned Service drives the
+// service and asserts the states it went through:
+//
+//	func TestMain(m *testing.M) {
+//		testutil.Main(m)
+//	}
+//
+//	func TestPause(t *testing.T) {
+//		s := testutil.InstallSelf(t)
+//		s.Start()
+//		s.Pause()
+//		s.Continue()
+//		s.AssertTransitions(winsvc.StateRunning, winsvc.StatePaused, winsvc.StateRunning)
+//	}
+//
+// Every service the package installs is first recorded in a manifest
+// under ManifestDir, and is named with NamePrefix. CleanupAll uses both to
+// remove the services that a crashed or killed test run left behind, and
+// UniqueName generates names for tests that install services themselves.
+//
+// Installing services needs an elevated process, so tests using the
+// package are skipped when not run as an administrator, and on platforms
+// other than Windows.
+package testutil
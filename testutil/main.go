@@ -0,0 +1,95 @@
+//go:build windows
+
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lib-x/winsvc"
+)
+
+// The arguments InstallSelf passes to the test binary to make Main run it
+// as a service.
+const (
+	serviceArg = "-winsvc.testservice="
+	journalArg = "-winsvc.journal="
+)
+
+// Main runs the tests, like calling os.Exit with the result of m.Run,
+// unless the test binary was started by the service control manager as a
+// service installed by InstallSelf. It then runs that service until it is
+// stopped. Tests using InstallSelf must call it from TestMain.
+//
+// Once the tests have run, Main also removes any service they installed
+// that is still there, such as one whose test was stopped by a timeout,
+// and the manifest recording them.
+func Main(m *testing.M) {
+	if name, journal, ok := serviceArgs(os.Args[1:]); ok {
+		os.Exit(runService(name, journal))
+	}
+	code := m.Run()
+	if err := finishManifest(); err != nil {
+		fmt.Fprintf(os.Stderr, "testutil: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+func serviceArgs(args []string) (name, journal string, ok bool) {
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, serviceArg):
+			name, ok = strings.TrimPrefix(arg, serviceArg), true
+		case strings.HasPrefix(arg, journalArg):
+			journal = strings.TrimPrefix(arg, journalArg)
+		}
+	}
+	return name, journal, ok
+}
+
+// runService runs the service installed by InstallSelf. It does nothing
+// but record its lifecycle in the journal.
+func runService(name, journal string) int {
+	if err := winsvc.RunAsService(name, func() {}, func() {}, false, winsvc.WithJournal(journal)); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// InstallSelf installs the running test binary as a service for t, like
+// Install. The service does nothing but accept stop, pause, continue and
+// custom controls and record them, which Journal returns. The test binary
+// must call Main from TestMain.
+func InstallSelf(t testing.TB, options ...winsvc.ServiceOption) *Service {
+	t.Helper()
+	RequireAdmin(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to get test executable path: %v", err)
+	}
+	name := uniqueName(t)
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	s := install(t, name, exe, []string{serviceArg + name, journalArg + journal}, options...)
+	s.journal = journal
+	return s
+}
+
+// Journal returns the lifecycle events the service installed by
+// InstallSelf recorded so far, such as the controls it received. A service
+// running under an account that cannot write to t.TempDir records none.
+func (s *Service) Journal() []winsvc.JournalEntry {
+	s.t.Helper()
+	if s.journal == "" {
+		s.t.Fatalf("test service %s was not installed by InstallSelf", s.Name)
+	}
+	entries, err := winsvc.ReadJournal(s.journal)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.t.Fatalf("failed to read journal of test service %s: %v", s.Name, err)
+	}
+	return entries
+}
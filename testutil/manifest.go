@@ -0,0 +1,262 @@
+//go:build windows
+
+package testutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib-x/winsvc"
+	"golang.org/x/sys/windows"
+)
+
+// ManifestDir is the directory in which each test process records the
+// services it installs, in a file of its own, before installing them.
+// Services are machine-wide, and so is the default, under %ProgramData%.
+var ManifestDir = filepath.Join(programData(), "winsvc-testutil")
+
+func programData() string {
+	if dir := os.Getenv("ProgramData"); dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// manifestEntry records a service installed by a test.
+type manifestEntry struct {
+	Name string    `json:"name"`
+	Test string    `json:"test"`
+	Time time.Time `json:"time"`
+}
+
+// manifest is the manifest file of this process, opened on first use.
+var manifest struct {
+	mu   sync.Mutex
+	path string
+}
+
+// track records in the manifest of this process that test is about to
+// install the named service.
+func track(test, name string) error {
+	manifest.mu.Lock()
+	defer manifest.mu.Unlock()
+	if manifest.path == "" {
+		created, err := processCreated(uint32(os.Getpid()))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(ManifestDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create test service manifest directory: %w", err)
+		}
+		manifest.path = filepath.Join(ManifestDir, manifestName(uint32(os.Getpid()), created))
+	}
+
+	data, err := json.Marshal(manifestEntry{Name: name, Test: test, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(manifest.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open test service manifest: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to record test service %s: %w", name, err)
+	}
+	return nil
+}
+
+// manifestName returns the name of the manifest file of the process with
+// the given ID and creation time. The creation time tells the process
+// apart from a later one reusing its ID.
+func manifestName(pid uint32, created windows.Filetime) string {
+	return fmt.Sprintf("%d-%d.jsonl", pid, created.Nanoseconds())
+}
+
+// processCreated returns the creation time of the process with the given
+// ID.
+func processCreated(pid uint32) (windows.Filetime, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return windows.Filetime{}, err
+	}
+	defer windows.CloseHandle(h)
+	var created, exited, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &created, &exited, &kernel, &user); err != nil {
+		return windows.Filetime{}, err
+	}
+	return created, nil
+}
+
+// running reports whether the process that wrote the manifest file with
+// the given name is still running.
+func running(file string) bool {
+	var pid uint32
+	var created int64
+	if _, err := fmt.Sscanf(file, "%d-%d.jsonl", &pid, &created); err != nil {
+		// Not a manifest; leave it alone.
+		return true
+	}
+	ft, err := processCreated(pid)
+	return err == nil && ft.Nanoseconds() == created
+}
+
+// readManifest returns the names of the services recorded in the manifest
+// file at path.
+func readManifest(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e manifestEntry
+		// A line cut short by a crash is skipped; the name prefix still
+		// finds its service.
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.Name != "" {
+			names = append(names, e.Name)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// CleanupAll stops and removes the services that test processes which are
+// no longer running left behind, such as after a crash or a timeout that
+// killed the test binary, and returns their names. These are the services
+// recorded in the manifests of those processes under ManifestDir, and any
+// service named with NamePrefix that no running process recorded. It is
+// meant to be called from TestMain, or by CI between runs:
+//
+//	func TestMain(m *testing.M) {
+//		if _, err := testutil.CleanupAll(); err != nil {
+//			log.Printf("failed to remove leaked test services: %v", err)
+//		}
+//		testutil.Main(m)
+//	}
+//
+// Removing services needs an elevated process.
+func CleanupAll() ([]string, error) {
+	m, err := winsvc.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+
+	// Services are listed before the manifests are read: any of them was
+	// recorded before being installed, so its record is found below and a
+	// running test's service is never taken for a leftover.
+	prefixed, err := m.List(winsvc.ServiceFilter{NamePattern: NamePrefix + "*"})
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(ManifestDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read test service manifests: %w", err)
+	}
+
+	live := make(map[string]bool)
+	var leftovers, stale []string
+	for _, file := range files {
+		path := filepath.Join(ManifestDir, file.Name())
+		names, err := readManifest(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read test service manifest: %w", err)
+		}
+		if running(file.Name()) {
+			for _, name := range names {
+				live[strings.ToLower(name)] = true
+			}
+			continue
+		}
+		leftovers = append(leftovers, names...)
+		stale = append(stale, path)
+	}
+	for _, s := range prefixed {
+		if !live[strings.ToLower(s.Name)] {
+			leftovers = append(leftovers, s.Name)
+		}
+	}
+
+	var removed []string
+	var errs []error
+	seen := make(map[string]bool)
+	for _, name := range leftovers {
+		if seen[strings.ToLower(name)] || live[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		ok, err := removeService(m, name, DefaultTimeout)
+		if err != nil {
+			errs = append(errs, err)
+		} else if ok {
+			removed = append(removed, name)
+		}
+	}
+	// The manifests are kept if a service could not be removed, so that
+	// the next call tries again.
+	if len(errs) == 0 {
+		for _, path := range stale {
+			os.Remove(path)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// finishManifest removes the services in the manifest of this process that
+// are still installed, which Main does once the tests have run, and then
+// the manifest itself.
+func finishManifest() error {
+	manifest.mu.Lock()
+	defer manifest.mu.Unlock()
+	if manifest.path == "" {
+		return nil
+	}
+	names, err := readManifest(manifest.path)
+	if err != nil {
+		return err
+	}
+	m, err := winsvc.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	var errs []error
+	for _, name := range names {
+		if _, err := removeService(m, name, DefaultTimeout); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return os.Remove(manifest.path)
+}
+
+// removeService stops the named service, forcibly once timeout elapses,
+// and removes it. It reports whether the service existed.
+func removeService(m *winsvc.Manager, name string, timeout time.Duration) (bool, error) {
+	state, err := m.QueryState(name)
+	if errors.Is(err, winsvc.ErrServiceNotFound) {
+		return false, nil
+	}
+	if err == nil && state != winsvc.StateStopped {
+		if err := m.StopForce(name, timeout); err != nil {
+			return true, fmt.Errorf("failed to stop test service %s: %w", name, err)
+		}
+	}
+	if err := m.Remove(context.Background(), name); err != nil && !errors.Is(err, winsvc.ErrServiceNotFound) {
+		return true, fmt.Errorf("failed to remove test service %s: %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestReadManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.jsonl")
+	content := `{"name":"winsvctest-a-00000001","test":"TestA","time":"2026-01-02T03:04:05Z"}` + "\n" +
+		"\n" +
+		`{"test":"TestNoName"}` + "\n" +
+		`{"name":"winsvctest-b-00000002","test":"TestB","time":"2026-01-02T03:04:06Z"}` + "\n" +
+		`{"name":"winsvctest-c-000`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readManifest(path)
+	if err != nil {
+		t.Fatalf("readManifest: %v", err)
+	}
+	if want := []string{"winsvctest-a-00000001", "winsvctest-b-00000002"}; !slices.Equal(got, want) {
+		t.Errorf("readManifest = %q, want %q", got, want)
+	}
+}
+
+func TestRunning(t *testing.T) {
+	pid := uint32(os.Getpid())
+	created, err := processCreated(pid)
+	if err != nil {
+		t.Fatalf("processCreated: %v", err)
+	}
+	later := windows.NsecToFiletime(created.Nanoseconds() + 1e9)
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{manifestName(pid, created), true},
+		{manifestName(pid, later), false},
+		{"notes.txt", true},
+	}
+	for _, tt := range tests {
+		if got := running(tt.file); got != tt.want {
+			t.Errorf("running(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package testutil
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// NamePrefix starts the name of every service the package installs, so
+// that CleanupAll can recognize leftovers that no manifest records.
+const NamePrefix = "winsvctest-"
+
+// UniqueName returns prefix followed by a random suffix, such as
+// "myapp-3f9a0c1e" for prefix "myapp-", so that concurrent and repeated
+// test runs do not collide on service names.
+func UniqueName(prefix string) string {
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	return fmt.Sprintf("%s%x", prefix, suffix)
+}
+
+// uniqueName returns a service name for a test of t that no other test
+// uses.
+func uniqueName(t testing.TB) string {
+	test := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, t.Name())
+	if len(test) > 64 {
+		test = test[:64]
+	}
+	return UniqueName(NamePrefix + test + "-")
+}
@@ -0,0 +1,38 @@
+package testutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUniqueName(t *testing.T) {
+	a, b := UniqueName("myapp-"), UniqueName("myapp-")
+	if !strings.HasPrefix(a, "myapp-") || len(a) != len("myapp-")+8 {
+		t.Errorf("UniqueName(%q) = %q, want the prefix and 8 hex digits", "myapp-", a)
+	}
+	if a == b {
+		t.Errorf("UniqueName returned %q twice", a)
+	}
+}
+
+func TestUniqueNameOfTest(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"plain", "TestUniqueNameOfTest_plain-"},
+		{"with spaces", "TestUniqueNameOfTest_with_spaces-"},
+		{"nested/sub-test", "TestUniqueNameOfTest_nested_sub-test-"},
+		{"ünïcode:*?", "TestUniqueNameOfTest__n_code___-"},
+		{strings.Repeat("x", 80), "TestUniqueNameOfTest_" + strings.Repeat("x", 64-len("TestUniqueNameOfTest_")) + "-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := uniqueName(t)
+			want := NamePrefix + tt.want
+			if !strings.HasPrefix(got, want) || len(got) != len(want)+8 {
+				t.Errorf("uniqueName() = %q, want %q followed by 8 hex digits", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+//go:build windows
+
+package testutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib-x/winsvc"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// DefaultTimeout bounds each operation of a Service whose Timeout is zero.
+const DefaultTimeout = 30 * time.Second
+
+// Service is a service installed for a single test. It is stopped and
+// removed when the test completes. Its methods fail the test when an
+// operation fails.
+type Service struct {
+	// Name is the unique name the service was installed under.
+	Name string
+	// Timeout bounds each operation, DefaultTimeout if zero.
+	Timeout time.Duration
+
+	t testing.TB
+	m *winsvc.Manager
+	// journal is the journal file of a service installed by InstallSelf.
+	journal string
+
+	mu      sync.Mutex
+	states  []winsvc.State
+	watched chan struct{}
+}
+
+// RequireAdmin skips t unless the process is elevated, as installing
+// services requires.
+func RequireAdmin(t testing.TB) {
+	t.Helper()
+	if !winsvc.IsElevated() {
+		t.Skip("installing services requires an elevated process")
+	}
+}
+
+// Install installs the executable at exePath as a service for t, passing it
+// args, and returns it stopped. It starts on demand unless options set
+// another start type. The service is named after t with a random
+// suffix, so that concurrent and repeated runs do not collide, and is
+// stopped, forcibly if need be, and removed along with its event source
+// when t completes, even if t panicked. t is skipped unless the process is
+// elevated.
+func Install(t testing.TB, exePath string, args []string, options ...winsvc.ServiceOption) *Service {
+	t.Helper()
+	return install(t, uniqueName(t), exePath, args, options...)
+}
+
+func install(t testing.TB, name, exePath string, args []string, options ...winsvc.ServiceOption) *Service {
+	t.Helper()
+	RequireAdmin(t)
+	m, err := winsvc.Connect()
+	if err != nil {
+		t.Fatalf("failed to connect to service manager: %v", err)
+	}
+
+	s := &Service{Name: name, t: t, m: m}
+	ctx, cancel := context.WithCancel(context.Background())
+	// Registered before installing, so that a service left behind by an
+	// install that failed halfway is removed too.
+	t.Cleanup(func() {
+		cancel()
+		s.cleanup()
+	})
+
+	if err := track(t.Name(), name); err != nil {
+		t.Fatalf("failed to record test service %s: %v", name, err)
+	}
+	options = append([]winsvc.ServiceOption{winsvc.OnDemandStart()}, options...)
+	if err := m.Install(ctx, exePath, name, args, options...); err != nil {
+		t.Fatalf("failed to install test service %s: %v", name, err)
+	}
+
+	events, err := m.Watch(ctx, name)
+	if err != nil {
+		t.Fatalf("failed to watch test service %s: %v", name, err)
+	}
+	s.watched = make(chan struct{})
+	go func() {
+		defer close(s.watched)
+		for e := range events {
+			s.record(e.Status.State)
+		}
+	}()
+	return s
+}
+
+// cleanup stops and removes the service.
+func (s *Service) cleanup() {
+	defer s.m.Disconnect()
+	if s.watched != nil {
+		<-s.watched
+	}
+	if _, err := removeService(s.m, s.Name, s.timeout()); err != nil {
+		s.t.Error(err)
+	}
+}
+
+func (s *Service) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (s *Service) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout())
+}
+
+// record appends a state seen by the watch, unless it is the last one.
+func (s *Service) record(state winsvc.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.states); n > 0 && s.states[n-1] == state {
+		return
+	}
+	s.states = append(s.states, state)
+}
+
+// Start starts the service, passing it args, and waits for it to run.
+func (s *Service) Start(args ...string) {
+	s.t.Helper()
+	ctx, cancel := s.context()
+	defer cancel()
+	if _, err := s.m.StartWait(ctx, s.Name, args...); err != nil {
+		s.t.Fatalf("failed to start test service %s: %v", s.Name, err)
+	}
+}
+
+// Stop stops the service and waits for it to stop.
+func (s *Service) Stop() {
+	s.t.Helper()
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.m.Stop(ctx, s.Name); err != nil {
+		s.t.Fatalf("failed to stop test service %s: %v", s.Name, err)
+	}
+}
+
+// Pause pauses the service and waits for it to pause.
+func (s *Service) Pause() {
+	s.t.Helper()
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.m.Pause(ctx, s.Name); err != nil {
+		s.t.Fatalf("failed to pause test service %s: %v", s.Name, err)
+	}
+}
+
+// Continue resumes the service and waits for it to run.
+func (s *Service) Continue() {
+	s.t.Helper()
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.m.Continue(ctx, s.Name); err != nil {
+		s.t.Fatalf("failed to continue test service %s: %v", s.Name, err)
+	}
+}
+
+// Restart stops the service and starts it again.
+func (s *Service) Restart() {
+	s.t.Helper()
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.m.Restart(ctx, s.Name); err != nil {
+		s.t.Fatalf("failed to restart test service %s: %v", s.Name, err)
+	}
+}
+
+// Control sends a control code, such as a custom one between 128 and 255,
+// to the service without waiting for a state change.
+func (s *Service) Control(code uint32) {
+	s.t.Helper()
+	m, err := mgr.Connect()
+	if err != nil {
+		s.t.Fatalf("failed to connect to service manager: %v", err)
+	}
+	defer m.Disconnect()
+	service, err := m.OpenService(s.Name)
+	if err != nil {
+		s.t.Fatalf("could not access test service %s: %v", s.Name, err)
+	}
+	defer service.Close()
+	if _, err := service.Control(svc.Cmd(code)); err != nil {
+		s.t.Fatalf("failed to send %s to test service %s: %v", winsvc.ControlName(code), s.Name, err)
+	}
+}
+
+// State returns the current state of the service.
+func (s *Service) State() winsvc.State {
+	s.t.Helper()
+	state, err := s.m.QueryState(s.Name)
+	if err != nil {
+		s.t.Fatalf("failed to query test service %s: %v", s.Name, err)
+	}
+	return state
+}
+
+// WaitState waits until the service is in state, failing the test if it
+// does not get there within the timeout.
+func (s *Service) WaitState(state winsvc.State) {
+	s.t.Helper()
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.m.WaitForState(ctx, s.Name, state); err != nil {
+		s.t.Fatalf("test service %s did not reach %v: %v", s.Name, state, err)
+	}
+}
+
+// AssertState reports an error if the service is not in state.
+func (s *Service) AssertState(state winsvc.State) {
+	s.t.Helper()
+	if got := s.State(); got != state {
+		s.t.Errorf("test service %s is %v, want %v", s.Name, got, state)
+	}
+}
+
+// Transitions returns the states the service went through since it was
+// installed, without repeating consecutive ones.
+func (s *Service) Transitions() []winsvc.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]winsvc.State(nil), s.states...)
+}
+
+// AssertTransitions reports an error unless the service went through the
+// given states in order since it was installed. Other states may come
+// between them, so pending states can be left out. As state changes are
+// observed asynchronously, it waits up to the timeout for the last ones.
+func (s *Service) AssertTransitions(states ...winsvc.State) {
+	s.t.Helper()
+	deadline := time.Now().Add(s.timeout())
+	for {
+		seen := s.Transitions()
+		if containsInOrder(seen, states) {
+			return
+		}
+		if time.Now().After(deadline) {
+			s.t.Errorf("test service %s went through %v, want %v", s.Name, seen, states)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// containsInOrder reports whether want is a subsequence of seen.
+func containsInOrder(seen, want []winsvc.State) bool {
+	i := 0
+	for _, state := range seen {
+		if i < len(want) && state == want[i] {
+			i++
+		}
+	}
+	return i == len(want)
+}
@@ -0,0 +1,79 @@
+//go:build !windows
+
+// This file declares the API of the package for platforms other than
+// Windows, so that cross-platform test suites build. Tests that install
+// services are skipped there. See the Windows build for the documentation.
+
+package testutil
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lib-x/winsvc"
+)
+
+const DefaultTimeout = 30 * time.Second
+
+var ManifestDir string
+
+type Service struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func RequireAdmin(t testing.TB) {
+	t.Helper()
+	t.Skip("services are only supported on windows")
+}
+
+func Install(t testing.TB, exePath string, args []string, options ...winsvc.ServiceOption) *Service {
+	t.Helper()
+	RequireAdmin(t)
+	return nil
+}
+
+func InstallSelf(t testing.TB, options ...winsvc.ServiceOption) *Service {
+	t.Helper()
+	RequireAdmin(t)
+	return nil
+}
+
+func CleanupAll() ([]string, error) {
+	return nil, winsvc.ErrUnsupportedPlatform
+}
+
+func Main(m *testing.M) {
+	os.Exit(m.Run())
+}
+
+func (s *Service) Start(args ...string) {}
+
+func (s *Service) Stop() {}
+
+func (s *Service) Pause() {}
+
+func (s *Service) Continue() {}
+
+func (s *Service) Restart() {}
+
+func (s *Service) Control(code uint32) {}
+
+func (s *Service) State() winsvc.State {
+	return 0
+}
+
+func (s *Service) WaitState(state winsvc.State) {}
+
+func (s *Service) AssertState(state winsvc.State) {}
+
+func (s *Service) Transitions() []winsvc.State {
+	return nil
+}
+
+func (s *Service) AssertTransitions(states ...winsvc.State) {}
+
+func (s *Service) Journal() []winsvc.JournalEntry {
+	return nil
+}
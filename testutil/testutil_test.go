@@ -0,0 +1,43 @@
+package testutil_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lib-x/winsvc"
+	"github.com/lib-x/winsvc/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+func TestInstallSelf(t *testing.T) {
+	s := testutil.InstallSelf(t)
+	s.AssertState(winsvc.StateStopped)
+
+	s.Start()
+	s.Pause()
+	s.Continue()
+	s.Control(200)
+	s.Stop()
+	s.AssertTransitions(winsvc.StateRunning, winsvc.StatePaused, winsvc.StateRunning, winsvc.StateStopped)
+
+	var controls []uint32
+	for _, e := range s.Journal() {
+		if e.Event == winsvc.JournalControl {
+			controls = append(controls, e.Control)
+		}
+	}
+	if !slices.Contains(controls, 200) {
+		t.Errorf("journal recorded controls %v, want 200 among them", controls)
+	}
+}
+
+func TestInstallSelfRestart(t *testing.T) {
+	s := testutil.InstallSelf(t, winsvc.DisplayName("testutil restart"))
+	s.Start()
+	s.Restart()
+	s.AssertState(winsvc.StateRunning)
+	s.AssertTransitions(winsvc.StateRunning, winsvc.StateStopped, winsvc.StateRunning)
+}
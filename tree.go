@@ -0,0 +1,244 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// StopServiceTree stops all active services that depend on the named
+// service, then the service itself, waiting for each one to stop before
+// moving on. Dependents are stopped in the order the service control
+// manager reports them, which is the reverse of their start order.
+func StopServiceTree(ctx context.Context, name string) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.StopTree(ctx, name)
+	})
+}
+
+// StopTree stops the named service after its active dependents, like
+// StopServiceTree.
+func (m *Manager) StopTree(ctx context.Context, name string) (err error) {
+	defer func() { m.audit("stop-tree", name, nil, err) }()
+	s, err := m.openService(name, windows.SERVICE_ENUMERATE_DEPENDENTS)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	dependents, err := s.ListDependentServices(svc.Active)
+	if err != nil {
+		return fmt.Errorf("could not list dependent services: %w", scmError(err))
+	}
+
+	for _, dep := range dependents {
+		if err := m.stopAndWait(ctx, dep); err != nil {
+			return fmt.Errorf("failed to stop dependent service %s: %w", dep, err)
+		}
+	}
+
+	return m.stopAndWait(ctx, name)
+}
+
+// ListDependentServices returns the services that depend on the named
+// service together with their current states. If recursive is false only
+// services that list the named service as a direct dependency are returned;
+// otherwise transitive dependents are included too.
+func ListDependentServices(name string, recursive bool) ([]DependentService, error) {
+	var result []DependentService
+	err := withManager(context.Background(), func(m *Manager) error {
+		var err error
+		result, err = m.ListDependentServices(name, recursive)
+		return err
+	})
+	return result, err
+}
+
+// ListDependentServices returns the services that depend on the named
+// service, like the package-level ListDependentServices.
+func (m *Manager) ListDependentServices(name string, recursive bool) ([]DependentService, error) {
+	var result []DependentService
+	seen := map[string]bool{strings.ToLower(name): true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		names, err := m.dependentNames(parent)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range names {
+			key := strings.ToLower(dep)
+			if seen[key] {
+				continue
+			}
+
+			s, err := m.openService(dep, windows.SERVICE_QUERY_STATUS|windows.SERVICE_QUERY_CONFIG)
+			if err != nil {
+				return nil, err
+			}
+			direct := true
+			if !recursive {
+				direct, err = dependsOn(s, parent)
+			}
+			var status svc.Status
+			if err == nil && direct {
+				status, err = s.Query()
+			}
+			s.Close()
+			if err != nil {
+				return nil, fmt.Errorf("could not query service %s: %w", dep, err)
+			}
+			if !direct {
+				continue
+			}
+
+			seen[key] = true
+			result = append(result, DependentService{Name: dep, State: State(status.State)})
+			if recursive {
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (m *Manager) dependentNames(name string) ([]string, error) {
+	s, err := m.openService(name, windows.SERVICE_ENUMERATE_DEPENDENTS)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	names, err := s.ListDependentServices(svc.AnyActivity)
+	if err != nil {
+		return nil, fmt.Errorf("could not list dependent services of %s: %w", name, scmError(err))
+	}
+	return names, nil
+}
+
+// dependsOn reports whether s lists name as a direct dependency.
+func dependsOn(s *mgr.Service, name string) (bool, error) {
+	config, err := s.Config()
+	if err != nil {
+		return false, err
+	}
+	for _, dep := range config.Dependencies {
+		if strings.EqualFold(dep, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StartServiceTree starts the named service after making sure every service
+// it depends on, directly or transitively, is running. Dependencies are
+// started depth-first so that each service starts only after its own
+// dependencies. Load order group dependencies are not followed.
+func StartServiceTree(ctx context.Context, name string) error {
+	return withManager(ctx, func(m *Manager) error {
+		return m.StartTree(ctx, name)
+	})
+}
+
+// StartTree starts the named service after its dependencies, like
+// StartServiceTree.
+func (m *Manager) StartTree(ctx context.Context, name string) (err error) {
+	defer func() { m.audit("start-tree", name, nil, err) }()
+	return m.startTree(ctx, name, map[string]bool{})
+}
+
+func (m *Manager) startTree(ctx context.Context, name string, visited map[string]bool) error {
+	key := strings.ToLower(name)
+	if visited[key] {
+		return nil
+	}
+	visited[key] = true
+
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS|windows.SERVICE_QUERY_CONFIG|windows.SERVICE_START)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	config, err := s.Config()
+	if err != nil {
+		return fmt.Errorf("could not query config of service %s: %w", name, err)
+	}
+	for _, dep := range config.Dependencies {
+		// Group dependencies are prefixed with SC_GROUP_IDENTIFIER.
+		if strings.HasPrefix(dep, "+") {
+			continue
+		}
+		if err := m.startTree(ctx, dep, visited); err != nil {
+			return err
+		}
+	}
+
+	status, err := s.Query()
+	if err != nil {
+		return fmt.Errorf("could not query status of service %s: %w", name, err)
+	}
+	switch status.State {
+	case svc.Running:
+		return nil
+	case svc.StartPending, svc.ContinuePending:
+		return m.waitRunning(ctx, s)
+	case svc.Paused, svc.PausePending:
+		return fmt.Errorf("service %s is paused", name)
+	case svc.StopPending:
+		if err := m.waitStatus(ctx, s, status, svc.Stopped); err != nil {
+			return err
+		}
+	}
+
+	if config.StartType == windows.SERVICE_DISABLED {
+		return &DisabledServiceError{Name: name}
+	}
+	if err := s.Start(); err != nil {
+		if !errors.Is(err, windows.ERROR_SERVICE_ALREADY_RUNNING) {
+			return fmt.Errorf("could not start service %s: %w", name, scmError(err))
+		}
+	}
+
+	return m.waitRunning(ctx, s)
+}
+
+// stopAndWait stops the named service unless it is already stopped or
+// stopping, and waits until it is stopped or ctx is done.
+func (m *Manager) stopAndWait(ctx context.Context, name string) error {
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS|windows.SERVICE_STOP)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	status, err := s.Query()
+	if err != nil {
+		return fmt.Errorf("could not query service status: %w", err)
+	}
+	switch status.State {
+	case svc.Stopped, svc.StopPending:
+	default:
+		status, err = s.Control(svc.Stop)
+		if err != nil {
+			// The service may have begun stopping on its own between the
+			// query and the control request.
+			if !errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+				return fmt.Errorf("could not send control=%d: %w", svc.Stop, scmError(err))
+			}
+			status = svc.Status{State: svc.StopPending}
+		}
+	}
+
+	return m.waitStatus(ctx, s, status, svc.Stopped)
+}
@@ -0,0 +1,118 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceTriggerInfo mirrors SERVICE_TRIGGER_INFO.
+type serviceTriggerInfo struct {
+	Count    uint32
+	Triggers *serviceTrigger
+	Reserved *byte
+}
+
+// serviceTrigger mirrors SERVICE_TRIGGER.
+type serviceTrigger struct {
+	TriggerType    uint32
+	Action         uint32
+	TriggerSubtype *windows.GUID
+	DataItemCount  uint32
+	DataItems      *serviceTriggerDataItem
+}
+
+// serviceTriggerDataItem mirrors SERVICE_TRIGGER_SPECIFIC_DATA_ITEM.
+type serviceTriggerDataItem struct {
+	DataType uint32
+	Size     uint32
+	Data     *byte
+}
+
+// triggers returns the triggers of the service with handle s, or nil if
+// the service is local and this version of Windows has no triggers. The
+// local version says nothing about a remote host's, so a remote service is
+// always queried and an unsupported query fails.
+func triggers(s *mgr.Service, local bool) ([]ServiceTrigger, error) {
+	if local && !capTriggers.supported() {
+		return nil, nil
+	}
+	n := uint32(1024)
+	for {
+		b := make([]byte, n)
+		err := windows.QueryServiceConfig2(s.Handle, windows.SERVICE_CONFIG_TRIGGER_INFO, &b[0], n, &n)
+		if err == nil {
+			return decodeTriggers((*serviceTriggerInfo)(unsafe.Pointer(&b[0]))), nil
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER || n <= uint32(len(b)) {
+			return nil, fmt.Errorf("could not query service triggers: %w", scmError(err))
+		}
+	}
+}
+
+func decodeTriggers(info *serviceTriggerInfo) []ServiceTrigger {
+	if info.Count == 0 || info.Triggers == nil {
+		return nil
+	}
+	list := make([]ServiceTrigger, 0, info.Count)
+	for _, t := range unsafe.Slice(info.Triggers, info.Count) {
+		trigger := ServiceTrigger{Type: t.TriggerType, Action: t.Action}
+		if t.TriggerSubtype != nil {
+			trigger.Subtype = t.TriggerSubtype.String()
+		}
+		if t.DataItems != nil {
+			for _, d := range unsafe.Slice(t.DataItems, t.DataItemCount) {
+				item := TriggerData{Type: d.DataType}
+				if d.Data != nil && d.Size > 0 {
+					item.Data = append([]byte(nil), unsafe.Slice(d.Data, d.Size)...)
+				}
+				trigger.Data = append(trigger.Data, item)
+			}
+		}
+		list = append(list, trigger)
+	}
+	return list
+}
+
+// setTriggers replaces the triggers of the service with handle s.
+func setTriggers(s *mgr.Service, list []ServiceTrigger) error {
+	var info serviceTriggerInfo
+	items := make([]serviceTrigger, len(list))
+	for i, t := range list {
+		items[i] = serviceTrigger{TriggerType: t.Type, Action: t.Action}
+		if t.Subtype != "" {
+			// GUIDFromString wants the braces String writes.
+			subtype, err := windows.GUIDFromString("{" + strings.Trim(t.Subtype, "{}") + "}")
+			if err != nil {
+				return fmt.Errorf("invalid trigger subtype %q: %w", t.Subtype, err)
+			}
+			items[i].TriggerSubtype = &subtype
+		}
+		if len(t.Data) > 0 {
+			data := make([]serviceTriggerDataItem, len(t.Data))
+			for j, d := range t.Data {
+				data[j] = serviceTriggerDataItem{DataType: d.Type, Size: uint32(len(d.Data))}
+				if len(d.Data) > 0 {
+					data[j].Data = &d.Data[0]
+				}
+			}
+			items[i].DataItemCount = uint32(len(data))
+			items[i].DataItems = &data[0]
+		}
+	}
+	// No triggers deletes those the service has.
+	if len(items) > 0 {
+		info.Count = uint32(len(items))
+		info.Triggers = &items[0]
+	}
+	err := windows.ChangeServiceConfig2(s.Handle, windows.SERVICE_CONFIG_TRIGGER_INFO, (*byte)(unsafe.Pointer(&info)))
+	if err != nil {
+		return fmt.Errorf("failed to set service triggers: %w", scmError(err))
+	}
+	return nil
+}
@@ -0,0 +1,577 @@
+package winsvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// State is the current state of a service.
+type State uint32
+
+const (
+	StateStopped         State = 1 // SERVICE_STOPPED
+	StateStartPending    State = 2 // SERVICE_START_PENDING
+	StateStopPending     State = 3 // SERVICE_STOP_PENDING
+	StateRunning         State = 4 // SERVICE_RUNNING
+	StateContinuePending State = 5 // SERVICE_CONTINUE_PENDING
+	StatePausePending    State = 6 // SERVICE_PAUSE_PENDING
+	StatePaused          State = 7 // SERVICE_PAUSED
+)
+
+var stateNames = map[State]string{
+	StateStopped:         "Stopped",
+	StateStartPending:    "StartPending",
+	StateStopPending:     "StopPending",
+	StateRunning:         "Running",
+	StateContinuePending: "ContinuePending",
+	StatePausePending:    "PausePending",
+	StatePaused:          "Paused",
+}
+
+// String returns the name of the state, such as "Running".
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("State(%d)", uint32(s))
+}
+
+// MarshalText implements encoding.TextMarshaler. Unknown values are
+// encoded as "State(n)" so that they survive a round trip.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *State) UnmarshalText(text []byte) error {
+	for state, name := range stateNames {
+		if strings.EqualFold(name, string(text)) {
+			*s = state
+			return nil
+		}
+	}
+	var n uint32
+	if _, err := fmt.Sscanf(string(text), "State(%d)", &n); err == nil {
+		*s = State(n)
+		return nil
+	}
+	return fmt.Errorf("unknown service state %q", text)
+}
+
+// StartType is the start type of a service.
+type StartType uint32
+
+const (
+	StartTypeBoot      StartType = 0 // SERVICE_BOOT_START
+	StartTypeSystem    StartType = 1 // SERVICE_SYSTEM_START
+	StartTypeAutomatic StartType = 2 // SERVICE_AUTO_START
+	StartTypeManual    StartType = 3 // SERVICE_DEMAND_START
+	StartTypeDisabled  StartType = 4 // SERVICE_DISABLED
+)
+
+var startTypeNames = map[StartType]string{
+	StartTypeBoot:      "Boot",
+	StartTypeSystem:    "System",
+	StartTypeAutomatic: "Automatic",
+	StartTypeManual:    "Manual",
+	StartTypeDisabled:  "Disabled",
+}
+
+// String returns the name of the start type, such as "Automatic".
+func (t StartType) String() string {
+	if name, ok := startTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("StartType(%d)", uint32(t))
+}
+
+// MarshalText implements encoding.TextMarshaler. Unknown values are
+// encoded as "StartType(n)" so that they survive a round trip.
+func (t StartType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *StartType) UnmarshalText(text []byte) error {
+	for startType, name := range startTypeNames {
+		if strings.EqualFold(name, string(text)) {
+			*t = startType
+			return nil
+		}
+	}
+	var n uint32
+	if _, err := fmt.Sscanf(string(text), "StartType(%d)", &n); err == nil {
+		*t = StartType(n)
+		return nil
+	}
+	return fmt.Errorf("unknown start type %q", text)
+}
+
+// RecoveryActionType is the action the service control manager takes when
+// a service fails.
+type RecoveryActionType uint32
+
+const (
+	RecoveryNone    RecoveryActionType = 0 // SC_ACTION_NONE
+	RecoveryRestart RecoveryActionType = 1 // SC_ACTION_RESTART
+	RecoveryReboot  RecoveryActionType = 2 // SC_ACTION_REBOOT
+	RecoveryCommand RecoveryActionType = 3 // SC_ACTION_RUN_COMMAND
+)
+
+var recoveryActionNames = map[RecoveryActionType]string{
+	RecoveryNone:    "None",
+	RecoveryRestart: "Restart",
+	RecoveryReboot:  "Reboot",
+	RecoveryCommand: "RunCommand",
+}
+
+// String returns the name of the action type, such as "Restart".
+func (t RecoveryActionType) String() string {
+	if name, ok := recoveryActionNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("RecoveryActionType(%d)", uint32(t))
+}
+
+// MarshalText implements encoding.TextMarshaler. Unknown values are
+// encoded as "RecoveryActionType(n)" so that they survive a round trip.
+func (t RecoveryActionType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *RecoveryActionType) UnmarshalText(text []byte) error {
+	for actionType, name := range recoveryActionNames {
+		if strings.EqualFold(name, string(text)) {
+			*t = actionType
+			return nil
+		}
+	}
+	var n uint32
+	if _, err := fmt.Sscanf(string(text), "RecoveryActionType(%d)", &n); err == nil {
+		*t = RecoveryActionType(n)
+		return nil
+	}
+	return fmt.Errorf("unknown recovery action type %q", text)
+}
+
+// RecoveryAction is one step of a service's failure actions.
+type RecoveryAction struct {
+	Type  RecoveryActionType
+	Delay time.Duration
+}
+
+type recoveryActionJSON struct {
+	Type    RecoveryActionType `json:"type"`
+	DelayMs int64              `json:"delayMs"`
+}
+
+// MarshalJSON encodes Delay in milliseconds.
+func (a RecoveryAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(recoveryActionJSON{Type: a.Type, DelayMs: a.Delay.Milliseconds()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *RecoveryAction) UnmarshalJSON(data []byte) error {
+	var v recoveryActionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = RecoveryAction{Type: v.Type, Delay: time.Duration(v.DelayMs) * time.Millisecond}
+	return nil
+}
+
+// RecoveryConfig is the failure actions configuration of a service.
+type RecoveryConfig struct {
+	Actions []RecoveryAction
+	// ResetPeriod is how long without failures resets the failure count.
+	ResetPeriod   time.Duration
+	RebootMessage string
+	Command       string
+	// OnNonCrashFailures reports whether the actions also run when the
+	// service stops with a non-zero exit code, not just when it crashes.
+	OnNonCrashFailures bool
+}
+
+type recoveryConfigJSON struct {
+	Actions            []RecoveryAction `json:"actions"`
+	ResetPeriodSeconds int64            `json:"resetPeriodSeconds"`
+	RebootMessage      string           `json:"rebootMessage"`
+	Command            string           `json:"command"`
+	OnNonCrashFailures bool             `json:"onNonCrashFailures"`
+}
+
+// MarshalJSON encodes ResetPeriod in seconds.
+func (c RecoveryConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(recoveryConfigJSON{
+		Actions:            c.Actions,
+		ResetPeriodSeconds: int64(c.ResetPeriod / time.Second),
+		RebootMessage:      c.RebootMessage,
+		Command:            c.Command,
+		OnNonCrashFailures: c.OnNonCrashFailures,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *RecoveryConfig) UnmarshalJSON(data []byte) error {
+	var v recoveryConfigJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = RecoveryConfig{
+		Actions:            v.Actions,
+		ResetPeriod:        time.Duration(v.ResetPeriodSeconds) * time.Second,
+		RebootMessage:      v.RebootMessage,
+		Command:            v.Command,
+		OnNonCrashFailures: v.OnNonCrashFailures,
+	}
+	return nil
+}
+
+// OSVersion is a version of Windows, such as 10.0.19045 for Windows 10
+// 22H2.
+type OSVersion struct {
+	Major uint32 `json:"major"`
+	Minor uint32 `json:"minor"`
+	Build uint32 `json:"build"`
+}
+
+// String returns the version as major.minor.build.
+func (v OSVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Build)
+}
+
+// AtLeast reports whether v is the given version or a later one.
+func (v OSVersion) AtLeast(major, minor, build uint32) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Build >= build
+}
+
+// ServiceConfig is the configuration of an installed service.
+type ServiceConfig struct {
+	Name           string           `json:"name"`
+	DisplayName    string           `json:"displayName"`
+	Description    string           `json:"description"`
+	BinaryPath     string           `json:"binaryPath"`
+	ServiceType    uint32           `json:"serviceType"`
+	StartType      StartType        `json:"startType"`
+	DelayedStart   bool             `json:"delayedStart"`
+	ErrorControl   uint32           `json:"errorControl"`
+	Account        string           `json:"account"`
+	Dependencies   []string         `json:"dependencies"`
+	LoadOrderGroup string           `json:"loadOrderGroup"`
+	SidType        uint32           `json:"sidType"`
+	Triggers       []ServiceTrigger `json:"triggers"`
+}
+
+// ServiceTrigger is an event that makes the service control manager start
+// or stop a service.
+type ServiceTrigger struct {
+	// Type is the SERVICE_TRIGGER_TYPE_* value of the event.
+	Type uint32 `json:"type"`
+	// Action is SERVICE_TRIGGER_ACTION_SERVICE_START (1) or
+	// SERVICE_TRIGGER_ACTION_SERVICE_STOP (2).
+	Action uint32 `json:"action"`
+	// Subtype is the GUID identifying the event, such as a device
+	// interface class or an ETW provider.
+	Subtype string `json:"subtype"`
+	// Data lists the conditions the event must also meet.
+	Data []TriggerData `json:"data,omitempty"`
+}
+
+// TriggerData is a condition of a ServiceTrigger.
+type TriggerData struct {
+	// Type is the SERVICE_TRIGGER_DATA_TYPE_* value describing Data.
+	Type uint32 `json:"type"`
+	Data []byte `json:"data"`
+}
+
+// ServiceStatus is a detailed snapshot of a service's current status,
+// as reported by QueryServiceStatusEx.
+type ServiceStatus struct {
+	State                   State    `json:"state"`
+	ProcessID               uint32   `json:"pid"`
+	ServiceType             uint32   `json:"serviceType"`
+	ControlsAccepted        Accepted `json:"controlsAccepted"`
+	Win32ExitCode           uint32   `json:"win32ExitCode"`
+	ServiceSpecificExitCode uint32   `json:"serviceSpecificExitCode"`
+	CheckPoint              uint32   `json:"checkPoint"`
+	WaitHint                uint32   `json:"waitHint"`
+	// RunsInSystemProcess reports whether the service runs in a system
+	// process that must always be running (SERVICE_RUNS_IN_SYSTEM_PROCESS).
+	RunsInSystemProcess bool `json:"runsInSystemProcess"`
+}
+
+// Accepted is the set of controls a service accepts. Its values are those
+// of svc.Accepted, so the two convert to each other.
+type Accepted uint32
+
+const (
+	AcceptStop                  Accepted = 0x001 // SERVICE_ACCEPT_STOP
+	AcceptPauseAndContinue      Accepted = 0x002 // SERVICE_ACCEPT_PAUSE_CONTINUE
+	AcceptShutdown              Accepted = 0x004 // SERVICE_ACCEPT_SHUTDOWN
+	AcceptParamChange           Accepted = 0x008 // SERVICE_ACCEPT_PARAMCHANGE
+	AcceptNetBindChange         Accepted = 0x010 // SERVICE_ACCEPT_NETBINDCHANGE
+	AcceptHardwareProfileChange Accepted = 0x020 // SERVICE_ACCEPT_HARDWAREPROFILECHANGE
+	AcceptPowerEvent            Accepted = 0x040 // SERVICE_ACCEPT_POWEREVENT
+	AcceptSessionChange         Accepted = 0x080 // SERVICE_ACCEPT_SESSIONCHANGE
+	AcceptPreShutdown           Accepted = 0x100 // SERVICE_ACCEPT_PRESHUTDOWN
+)
+
+// ServiceInfo describes a service returned by ListServices.
+type ServiceInfo struct {
+	Name        string        `json:"name"`
+	DisplayName string        `json:"displayName"`
+	Status      ServiceStatus `json:"status"`
+}
+
+// ServiceFilter selects services in ListServices. Zero-valued fields match
+// every service; a service must match all non-zero fields to be returned.
+type ServiceFilter struct {
+	// States matches services in any of the given states.
+	States []State
+	// StartTypes matches services with any of the given start types.
+	StartTypes []StartType
+	// NamePattern is a case-insensitive path.Match pattern applied to the
+	// service name, such as "MyProduct*".
+	NamePattern string
+	// BinaryPathContains matches services whose binary path contains the
+	// given substring, compared case-insensitively.
+	BinaryPathContains string
+}
+
+// DependentService is a service that depends on another service.
+type DependentService struct {
+	Name  string `json:"name"`
+	State State  `json:"state"`
+}
+
+// StatusEvent is a service status change reported by WatchService.
+type StatusEvent struct {
+	Time   time.Time     `json:"time"`
+	Status ServiceStatus `json:"status"`
+	// Deleted is set on the final event if the service was marked for deletion.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// ExitCode holds the exit codes a service reported when it last stopped.
+type ExitCode struct {
+	Win32ExitCode           uint32 `json:"win32ExitCode"`
+	ServiceSpecificExitCode uint32 `json:"serviceSpecificExitCode"`
+}
+
+// FailureReason combines a service's last exit code with the most recent
+// Service Control Manager event about it terminating unexpectedly.
+type FailureReason struct {
+	ExitCode
+	// Event is nil if no matching event was found.
+	Event *FailureEvent `json:"event,omitempty"`
+}
+
+// FailureEvent is a Service Control Manager event recording an unexpected
+// service termination.
+type FailureEvent struct {
+	// EventID is 7031 (terminated unexpectedly, recovery action taken)
+	// or 7034 (terminated unexpectedly).
+	EventID uint32    `json:"eventId"`
+	Time    time.Time `json:"time"`
+	// Data holds the event's insertion strings in order.
+	Data []string `json:"data"`
+}
+
+// BatchResult is the outcome of a batch operation on one service.
+type BatchResult struct {
+	Name string
+	// Status is the service's status after the operation. It is the zero
+	// value if the status could not be queried.
+	Status ServiceStatus
+	Err    error
+}
+
+// SetResult is the outcome of StartServiceSet or StopServiceSet for one service.
+type SetResult struct {
+	Name   string
+	Status ServiceStatus
+	Err    error
+	// Skipped is set if the service was not touched because an operation
+	// it had to wait for failed.
+	Skipped  bool
+	Duration time.Duration
+}
+
+// WaitPolicy controls how long and how often functions that wait for a
+// service to change state poll its status when status change notifications
+// are unavailable.
+type WaitPolicy struct {
+	// InitialInterval is the delay before the first poll.
+	InitialInterval time.Duration
+	// BackoffFactor multiplies the interval after every poll. Values
+	// below 1 are treated as 1.
+	BackoffFactor float64
+	// MaxInterval caps the interval. Zero means no cap.
+	MaxInterval time.Duration
+	// Timeout bounds each wait. Zero means the wait is bounded only by
+	// its context.
+	Timeout time.Duration
+}
+
+// HandlerThresholds sets how long the service's handlers may take before
+// RunAsService logs a warning about them. Zero fields take their defaults.
+type HandlerThresholds struct {
+	// Stop bounds the stop function, 10 seconds by default. Windows
+	// terminates services that take much longer to stop at shutdown.
+	Stop time.Duration
+	// Control bounds the handling of pause, continue and parameter change
+	// requests, 1 second by default.
+	Control time.Duration
+}
+
+// HardeningReport describes the least-privilege settings of a service.
+type HardeningReport struct {
+	Account string `json:"account"`
+	// VirtualAccount is set if the service runs as NT SERVICE\<name>.
+	VirtualAccount bool   `json:"virtualAccount"`
+	SidType        uint32 `json:"sidType"`
+	// RequiredPrivileges lists the privileges the service is limited to,
+	// or is empty if it gets every privilege of its account.
+	RequiredPrivileges []string `json:"requiredPrivileges"`
+}
+
+// ProcessStats is a snapshot of the resource usage of a service process.
+// For services sharing a process it covers the whole process.
+type ProcessStats struct {
+	PID uint32
+	// KernelTime and UserTime are the CPU time the process has spent in
+	// kernel and user mode; CPUTime is their sum.
+	KernelTime time.Duration
+	UserTime   time.Duration
+	CPUTime    time.Duration
+	// WorkingSet and PrivateBytes are in bytes.
+	WorkingSet   uint64
+	PrivateBytes uint64
+	HandleCount  uint32
+	ThreadCount  uint32
+}
+
+// StopReason is a stop reason code recorded by the service control manager
+// when a service is stopped with StopServiceWithReason. It combines one
+// flag, one major and one minor reason, such as
+// StopReasonPlanned | StopReasonMajorApplication | StopReasonMinorMaintenance.
+type StopReason uint32
+
+// Stop reason flags.
+const (
+	StopReasonUnplanned StopReason = 0x10000000
+	StopReasonCustom    StopReason = 0x20000000
+	StopReasonPlanned   StopReason = 0x40000000
+)
+
+// Major stop reasons.
+const (
+	StopReasonMajorOther           StopReason = 0x00010000
+	StopReasonMajorHardware        StopReason = 0x00020000
+	StopReasonMajorOperatingSystem StopReason = 0x00030000
+	StopReasonMajorSoftware        StopReason = 0x00040000
+	StopReasonMajorApplication     StopReason = 0x00050000
+	StopReasonMajorNone            StopReason = 0x00060000
+)
+
+// Minor stop reasons.
+const (
+	StopReasonMinorOther                   StopReason = 0x01
+	StopReasonMinorMaintenance             StopReason = 0x02
+	StopReasonMinorInstallation            StopReason = 0x03
+	StopReasonMinorUpgrade                 StopReason = 0x04
+	StopReasonMinorReconfig                StopReason = 0x05
+	StopReasonMinorHung                    StopReason = 0x06
+	StopReasonMinorUnstable                StopReason = 0x07
+	StopReasonMinorDisk                    StopReason = 0x08
+	StopReasonMinorNetworkCard             StopReason = 0x09
+	StopReasonMinorEnvironment             StopReason = 0x0a
+	StopReasonMinorHardwareDriver          StopReason = 0x0b
+	StopReasonMinorOtherDriver             StopReason = 0x0c
+	StopReasonMinorServicePack             StopReason = 0x0d
+	StopReasonMinorSoftwareUpdate          StopReason = 0x0e
+	StopReasonMinorSecurityFix             StopReason = 0x0f
+	StopReasonMinorSecurity                StopReason = 0x10
+	StopReasonMinorNetworkConnectivity     StopReason = 0x11
+	StopReasonMinorWMI                     StopReason = 0x12
+	StopReasonMinorServicePackUninstall    StopReason = 0x13
+	StopReasonMinorSoftwareUpdateUninstall StopReason = 0x14
+	StopReasonMinorSecurityFixUninstall    StopReason = 0x15
+	StopReasonMinorMMC                     StopReason = 0x16
+	StopReasonMinorNone                    StopReason = 0x17
+)
+
+// MitigationPolicy selects process mitigation policies that RunAsService
+// applies to the service process with SetProcessMitigationPolicy before
+// the service starts. Policies cannot be relaxed again once applied.
+type MitigationPolicy uint32
+
+const (
+	// MitigateDEP enables data execution prevention permanently. 64-bit
+	// processes always have it, so it only affects 32-bit builds.
+	MitigateDEP MitigationPolicy = 1 << iota
+	// MitigateDynamicCode prohibits generating or modifying executable
+	// code at run time.
+	MitigateDynamicCode
+	// MitigateImageLoad refuses to load images from remote locations or
+	// with a low mandatory label, and prefers System32 when resolving
+	// DLLs.
+	MitigateImageLoad
+	// MitigateExtensionPoints disables legacy extension points such as
+	// AppInit DLLs and window hooks.
+	MitigateExtensionPoints
+)
+
+// MitigationBaseline is a hardening baseline suitable for most Go services.
+const MitigationBaseline = MitigateDEP | MitigateDynamicCode | MitigateImageLoad | MitigateExtensionPoints
+
+// ProtectionLevel is the protection a service is launched with.
+type ProtectionLevel uint32
+
+const (
+	ProtectionNone ProtectionLevel = iota
+	ProtectionWindows
+	ProtectionWindowsLight
+	// ProtectionAntimalwareLight runs the service as a protected process
+	// light, which antimalware services whose driver is signed for early
+	// launch can request.
+	ProtectionAntimalwareLight
+)
+
+// CatalogEventType says whether a service was created or deleted.
+type CatalogEventType string
+
+const (
+	ServiceCreated CatalogEventType = "created"
+	ServiceDeleted CatalogEventType = "deleted"
+)
+
+// CatalogEvent reports a service being installed or removed on the machine.
+type CatalogEvent struct {
+	Time time.Time        `json:"time"`
+	Type CatalogEventType `json:"type"`
+	Name string           `json:"name"`
+	// Err is set, with Type and Name empty, on the final event of a watch
+	// that ended because the notifications failed rather than because ctx
+	// was done.
+	Err error `json:"-"`
+}
+
+// Service is the program a service binary runs, as passed to Manage.
+type Service interface {
+	// Start is called once the service is running, like the start
+	// function of RunAsService.
+	Start()
+	// Stop is called when the service is asked to stop, like the stop
+	// function of RunAsService.
+	Stop()
+}
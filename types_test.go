@@ -0,0 +1,120 @@
+package winsvc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStateText(t *testing.T) {
+	tests := []struct {
+		state State
+		text  string
+	}{
+		{StateStopped, "Stopped"},
+		{StateStartPending, "StartPending"},
+		{StateStopPending, "StopPending"},
+		{StateRunning, "Running"},
+		{StateContinuePending, "ContinuePending"},
+		{StatePausePending, "PausePending"},
+		{StatePaused, "Paused"},
+		{State(42), "State(42)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			text, err := tt.state.MarshalText()
+			if err != nil {
+				t.Fatalf("MarshalText: %v", err)
+			}
+			if string(text) != tt.text {
+				t.Errorf("MarshalText = %q, want %q", text, tt.text)
+			}
+			var got State
+			if err := got.UnmarshalText(text); err != nil {
+				t.Fatalf("UnmarshalText(%q): %v", text, err)
+			}
+			if got != tt.state {
+				t.Errorf("UnmarshalText(%q) = %d, want %d", text, got, tt.state)
+			}
+		})
+	}
+}
+
+func TestStartTypeText(t *testing.T) {
+	tests := []struct {
+		startType StartType
+		text      string
+	}{
+		{StartTypeBoot, "Boot"},
+		{StartTypeSystem, "System"},
+		{StartTypeAutomatic, "Automatic"},
+		{StartTypeManual, "Manual"},
+		{StartTypeDisabled, "Disabled"},
+		{StartType(9), "StartType(9)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			text, err := tt.startType.MarshalText()
+			if err != nil {
+				t.Fatalf("MarshalText: %v", err)
+			}
+			if string(text) != tt.text {
+				t.Errorf("MarshalText = %q, want %q", text, tt.text)
+			}
+			var got StartType
+			if err := got.UnmarshalText(text); err != nil {
+				t.Fatalf("UnmarshalText(%q): %v", text, err)
+			}
+			if got != tt.startType {
+				t.Errorf("UnmarshalText(%q) = %d, want %d", text, got, tt.startType)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTextIgnoresCase(t *testing.T) {
+	var state State
+	if err := state.UnmarshalText([]byte("running")); err != nil || state != StateRunning {
+		t.Errorf("State.UnmarshalText(running) = %v, %v; want Running", state, err)
+	}
+	var startType StartType
+	if err := startType.UnmarshalText([]byte("MANUAL")); err != nil || startType != StartTypeManual {
+		t.Errorf("StartType.UnmarshalText(MANUAL) = %v, %v; want Manual", startType, err)
+	}
+}
+
+func TestUnmarshalTextUnknown(t *testing.T) {
+	for _, text := range []string{"", "Started", "State(x)"} {
+		var state State
+		if err := state.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("State.UnmarshalText(%q) = %v, want error", text, state)
+		}
+	}
+	for _, text := range []string{"", "Auto", "StartType(x)"} {
+		var startType StartType
+		if err := startType.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("StartType.UnmarshalText(%q) = %v, want error", text, startType)
+		}
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	in := struct {
+		State     State     `json:"state"`
+		StartType StartType `json:"startType"`
+	}{StatePaused, StartTypeAutomatic}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"state":"Paused","startType":"Automatic"}`; string(b) != want {
+		t.Errorf("json.Marshal = %s, want %s", b, want)
+	}
+	out := in
+	out.State, out.StartType = 0, 0
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("json.Unmarshal(%s) = %+v, want %+v", b, out, in)
+	}
+}
@@ -0,0 +1,185 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// pollInterval is how often the service is queried by default when status
+// change notifications are unavailable.
+const pollInterval = 300 * time.Millisecond
+
+// DefaultWaitPolicy returns the policy of the functions that take no
+// context, such as StopService. A Manager connected without WithWaitPolicy
+// polls at the same interval, but its waits are bounded only by their
+// context.
+func DefaultWaitPolicy() WaitPolicy {
+	return WaitPolicy{
+		InitialInterval: pollInterval,
+		BackoffFactor:   1,
+		MaxInterval:     pollInterval,
+		Timeout:         10 * time.Second,
+	}
+}
+
+// WithWaitPolicy makes the Manager wait for services to change state
+// according to policy.
+func WithWaitPolicy(policy WaitPolicy) ConnectOption {
+	return func(c *connectConfig) {
+		c.wait = policy
+	}
+}
+
+// withDefaults is like withManager for the functions that take no context,
+// whose waits follow DefaultWaitPolicy.
+func withDefaults(fn func(context.Context, *Manager) error) error {
+	ctx := context.Background()
+	m, err := ConnectCtx(ctx, WithWaitPolicy(DefaultWaitPolicy()))
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return fn(ctx, m)
+}
+
+// boundedWaits returns m or, if m has no wait policy, a copy of m sharing
+// its connection whose waits follow DefaultWaitPolicy, for methods that
+// take no context.
+func (m *Manager) boundedWaits() *Manager {
+	if m.wait != (WaitPolicy{}) {
+		return m
+	}
+	c := *m
+	c.wait = DefaultWaitPolicy()
+	return &c
+}
+
+func (p WaitPolicy) next(interval time.Duration) time.Duration {
+	if p.BackoffFactor > 1 {
+		interval = time.Duration(float64(interval) * p.BackoffFactor)
+	}
+	if p.MaxInterval > 0 && interval > p.MaxInterval {
+		interval = p.MaxInterval
+	}
+	return interval
+}
+
+// waitStatus waits until s reaches state to or ctx is done. status is the
+// most recently observed status of s.
+func (m *Manager) waitStatus(ctx context.Context, s *mgr.Service, status svc.Status, to svc.State) error {
+	if status.State == to {
+		return nil
+	}
+	return m.waitUntil(ctx, s, to, stateMask(to), func(status ServiceStatus) (bool, error) {
+		return status.State == State(to), nil
+	})
+}
+
+// waitRunning waits until a service that has just been started is running,
+// fails by stopping again, or ctx is done.
+func (m *Manager) waitRunning(ctx context.Context, s *mgr.Service) error {
+	mask := stateMask(svc.Running) | stateMask(svc.Stopped)
+	return m.waitUntil(ctx, s, svc.Running, mask, func(status ServiceStatus) (bool, error) {
+		switch status.State {
+		case StateRunning:
+			return true, nil
+		case StateStopped:
+			code := ExitCode{
+				Win32ExitCode:           status.Win32ExitCode,
+				ServiceSpecificExitCode: status.ServiceSpecificExitCode,
+			}
+			if err := code.Err(); err != nil {
+				return true, fmt.Errorf("service %s failed to start: %w", s.Name, err)
+			}
+			return true, fmt.Errorf("service %s stopped while starting", s.Name)
+		}
+		return false, nil
+	})
+}
+
+// waitUntil checks the status of s until check reports done or ctx is done.
+// Between checks it waits for a status change notification matching mask,
+// falling back to polling at the intervals of m's wait policy if
+// notifications cannot be registered. target is only used to describe a timeout.
+//
+// The policy's Timeout is extended while the service reports progress, the
+// way the service control manager does: whenever the service's CheckPoint
+// advances, the deadline moves to at least WaitHint from now.
+func (m *Manager) waitUntil(ctx context.Context, s *mgr.Service, target svc.State, mask uint32, check func(ServiceStatus) (bool, error)) error {
+	policy := m.wait
+	var deadline time.Time
+	if policy.Timeout > 0 {
+		deadline = time.Now().Add(policy.Timeout)
+	}
+	interval := policy.InitialInterval
+	if interval <= 0 {
+		interval = pollInterval
+	}
+
+	var checkPoint uint32
+	useNotify := true
+	for {
+		status, err := queryStatus(s)
+		if err != nil {
+			return fmt.Errorf("could not retrieve service status: %w", err)
+		}
+		if done, err := check(status); done || err != nil {
+			return err
+		}
+
+		if !deadline.IsZero() {
+			now := time.Now()
+			if status.CheckPoint > checkPoint {
+				hinted := now.Add(time.Duration(status.WaitHint) * time.Millisecond)
+				if hinted.After(deadline) {
+					deadline = hinted
+				}
+			}
+			if now.After(deadline) {
+				return fmt.Errorf("timeout waiting for service to go to state=%d: %w", target, timeoutError(context.DeadlineExceeded))
+			}
+		}
+		checkPoint = status.CheckPoint
+
+		if err := waitChange(ctx, deadline, m.host, s.Name, mask, &useNotify, interval); err != nil {
+			return fmt.Errorf("timeout waiting for service to go to state=%d: %w", target, timeoutError(err))
+		}
+		if !useNotify {
+			interval = policy.next(interval)
+		}
+	}
+}
+
+// waitChange waits for the next moment the service should be checked
+// again: a notification matching mask, the end of the polling interval once
+// notifications have failed, or the deadline. It returns an error only when
+// ctx is done.
+func waitChange(ctx context.Context, deadline time.Time, host, name string, mask uint32, useNotify *bool, interval time.Duration) error {
+	waitCtx := ctx
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	if *useNotify {
+		err := waitNotify(waitCtx, host, name, mask)
+		if err == nil || waitCtx.Err() != nil {
+			return ctx.Err()
+		}
+		*useNotify = false
+	}
+
+	select {
+	case <-waitCtx.Done():
+		return ctx.Err()
+	case <-time.After(interval):
+		return nil
+	}
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitPolicyNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   WaitPolicy
+		interval time.Duration
+		want     time.Duration
+	}{
+		{"constant", WaitPolicy{BackoffFactor: 1}, 300 * time.Millisecond, 300 * time.Millisecond},
+		{"factor below 1", WaitPolicy{BackoffFactor: 0.5}, 300 * time.Millisecond, 300 * time.Millisecond},
+		{"zero factor", WaitPolicy{}, 300 * time.Millisecond, 300 * time.Millisecond},
+		{"backoff", WaitPolicy{BackoffFactor: 2, MaxInterval: time.Second}, 300 * time.Millisecond, 600 * time.Millisecond},
+		{"capped", WaitPolicy{BackoffFactor: 2, MaxInterval: time.Second}, 600 * time.Millisecond, time.Second},
+		{"no cap", WaitPolicy{BackoffFactor: 2}, time.Minute, 2 * time.Minute},
+		{"above cap", WaitPolicy{BackoffFactor: 1, MaxInterval: time.Second}, 2 * time.Second, time.Second},
+		{"default", DefaultWaitPolicy(), pollInterval, pollInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.next(tt.interval); got != tt.want {
+				t.Errorf("next(%v) = %v, want %v", tt.interval, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitPolicyBackoffSequence(t *testing.T) {
+	p := WaitPolicy{InitialInterval: 100 * time.Millisecond, BackoffFactor: 1.5, MaxInterval: 400 * time.Millisecond}
+	want := []time.Duration{
+		150 * time.Millisecond,
+		225 * time.Millisecond,
+		337500 * time.Microsecond,
+		400 * time.Millisecond,
+		400 * time.Millisecond,
+	}
+	interval := p.InitialInterval
+	for i, w := range want {
+		interval = p.next(interval)
+		if interval != w {
+			t.Fatalf("interval %d = %v, want %v", i+1, interval, w)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+// WatchService streams the status of the named service every time its
+// state changes, starting with its current status, until ctx is done or the
+// service is deleted. The channel is closed when the watch ends.
+func WatchService(ctx context.Context, name string) (<-chan StatusEvent, error) {
+	return watch(ctx, "", name)
+}
+
+// Watch streams the status of the named service, like WatchService. The
+// watch keeps its own connection, so it may outlive m, but a connection
+// made with ConnectAs should stay open while the watch runs.
+func (m *Manager) Watch(ctx context.Context, name string) (<-chan StatusEvent, error) {
+	return watch(ctx, m.host, name)
+}
+
+func watch(ctx context.Context, host, name string) (<-chan StatusEvent, error) {
+	events := make(chan StatusEvent)
+	ready := make(chan error, 1)
+	go watchService(ctx, host, name, events, ready)
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func watchService(ctx context.Context, host, name string, events chan<- StatusEvent, ready chan<- error) {
+	defer close(events)
+
+	// Notifications are delivered as APCs to the registering thread.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	scm, h, err := openNotifyHandle(host, name)
+	if err != nil {
+		ready <- err
+		return
+	}
+	defer windows.CloseServiceHandle(scm)
+	ready <- nil
+
+	send := func(e StatusEvent) bool {
+		select {
+		case events <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// Registering for the current state would fire immediately, so each
+	// registration asks for every state except the last one seen.
+	var last State
+	err = notifyLoop(ctx, h, allStatesMask|windows.SERVICE_NOTIFY_DELETE_PENDING, func(n *windows.SERVICE_NOTIFY) uint32 {
+		if n.NotificationTriggered&windows.SERVICE_NOTIFY_DELETE_PENDING != 0 {
+			send(StatusEvent{Time: time.Now(), Status: newServiceStatus(&n.ServiceStatus), Deleted: true})
+			return 0
+		}
+		status := newServiceStatus(&n.ServiceStatus)
+		last = status.State
+		if !send(StatusEvent{Time: time.Now(), Status: status}) {
+			return 0
+		}
+		return (allStatesMask &^ stateMask(svc.State(last))) | windows.SERVICE_NOTIFY_DELETE_PENDING
+	})
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+
+	pollService(ctx, host, name, last, send)
+}
+
+// pollService reports status changes of the named service by polling, for
+// when status change notifications are unavailable.
+func pollService(ctx context.Context, host, name string, last State, send func(StatusEvent) bool) {
+	m, err := ConnectRemoteCtx(ctx, host)
+	if err != nil {
+		return
+	}
+	defer m.Disconnect()
+
+	s, err := m.openService(name, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return
+	}
+	defer s.Close()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := queryStatus(s)
+		if err != nil {
+			return
+		}
+		if status.State != last {
+			last = status.State
+			if !send(StatusEvent{Time: time.Now(), Status: status}) {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+//go:build windows
+
+package wrap
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// baseDir returns the directory relative paths in a Config are resolved
+// against, that of the wrapper executable. The working directory of a
+// service, System32, is rarely what was meant.
+func baseDir() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate wrapper executable: %w", err)
+	}
+	return filepath.Dir(exe), nil
+}
+
+// command returns the command running the program described by c for the
+// named service. It expands the program's environment variables in the
+// program, its arguments and the working directory, and resolves relative
+// paths against the directory of the wrapper executable. A program given
+// by bare name is looked up there, then in the program's PATH.
+func (c Config) command(service string) (*exec.Cmd, error) {
+	base, err := baseDir()
+	if err != nil {
+		return nil, err
+	}
+	env, err := c.Env.environment(service)
+	if err != nil {
+		return nil, err
+	}
+
+	program := env.expand(c.Program)
+	if !filepath.IsAbs(program) {
+		local := filepath.Join(base, program)
+		if strings.ContainsAny(program, `\/`) {
+			program = local
+		} else if _, err := os.Stat(local); err == nil {
+			program = local
+		} else if path, err := lookPath(program, env); err == nil {
+			program = path
+		}
+	}
+
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = env.expand(arg)
+	}
+
+	cmd := exec.Command(program, args...)
+	if cmd.Err != nil {
+		return nil, fmt.Errorf("failed to locate %s: %w", c.Program, cmd.Err)
+	}
+	cmd.Env = env.list()
+	// A process group of its own lets the program be sent CTRL_BREAK
+	// without affecting the wrapper. The priority class is set at creation
+	// too, for hook commands, which run outside the job.
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP | uint32(c.Priority)}
+
+	dir := env.expand(c.Dir)
+	switch {
+	case dir == "":
+		dir = filepath.Dir(cmd.Path)
+	case !filepath.IsAbs(dir):
+		dir = filepath.Join(base, dir)
+	}
+	cmd.Dir = dir
+	return cmd, nil
+}
+
+// lookPath looks up the executable name in the PATH of env, trying the
+// extensions of its PATHEXT.
+func lookPath(name string, env *environ) (string, error) {
+	exts := []string{""}
+	if filepath.Ext(name) == "" {
+		pathext, ok := env.lookup("PATHEXT")
+		if !ok {
+			pathext = ".com;.exe;.bat;.cmd"
+		}
+		exts = strings.Split(strings.ToLower(pathext), ";")
+	}
+	path, _ := env.lookup("PATH")
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue
+		}
+		for _, ext := range exts {
+			candidate := filepath.Join(dir, name+ext)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate, nil
+			}
+		}
+	}
+	return "", exec.ErrNotFound
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package wrap
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"tool.exe", "script.cmd"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.exe"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	env := newEnviron([]string{"PATH=relative;" + dir, "PATHEXT=.COM;.EXE;.CMD"})
+	for _, tt := range []struct {
+		name, want string
+	}{
+		{"tool", filepath.Join(dir, "tool.exe")},
+		{"tool.exe", filepath.Join(dir, "tool.exe")},
+		{"script", filepath.Join(dir, "script.cmd")},
+		{"sub", ""},
+		{"missing", ""},
+	} {
+		got, err := lookPath(tt.name, env)
+		if tt.want == "" {
+			if !errors.Is(err, exec.ErrNotFound) {
+				t.Errorf("lookPath(%q) = %q, %v, want ErrNotFound", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("lookPath(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package wrap hosts an arbitrary executable as a Windows service: the
+// program is started when the service starts and stopped when the service
+// stops, so existing binaries such as Java or Python applications can run
+// as services without a separate wrapper like NSSM or WinSW.
+//
+// A wrapper is a small Go program installed as the service:
+//
+//	func main() {
+//		cfg := wrap.Config{
+//			Program: `C:\Program Files\Java\bin\java.exe`,
+//			Args:    []string{"-jar", `C:\apps\myapp.jar`},
+//		}
+//		if err := wrap.Run("MyApp", cfg, !winsvc.InServiceMode()); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// The package is only functional on Windows. On other platforms Run and
+// RunGroup fail with winsvc.ErrUnsupportedPlatform.
+package wrap
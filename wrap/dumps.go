@@ -0,0 +1,140 @@
+//go:build windows
+
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// DumpConfig configures the collection of crash dumps of the program.
+type DumpConfig struct {
+	// Dir is the directory dumps are stored in.
+	Dir string
+	// Full includes the whole process memory in dumps, which makes them
+	// much larger. By default they hold the threads, stacks and handles.
+	Full bool
+	// MaxDumps is the number of dumps kept, 10 by default. The oldest are
+	// deleted first.
+	MaxDumps int
+}
+
+func (c DumpConfig) withDefaults() DumpConfig {
+	if c.MaxDumps <= 0 {
+		c.MaxDumps = 10
+	}
+	return c
+}
+
+const localDumpsKeyPath = `SOFTWARE\Microsoft\Windows\Windows Error Reporting\LocalDumps`
+
+// InstallLocalDumps makes Windows Error Reporting write a dump to c.Dir
+// whenever a process running the executable program crashes, keeping
+// c.MaxDumps of them. Only the file name of program is used, so the
+// setting applies to every executable of that name. Call it when
+// installing a wrapper service, as it requires administrator rights.
+func InstallLocalDumps(program string, c DumpConfig) error {
+	if c.Dir == "" {
+		return errors.New("no dump directory")
+	}
+	c = c.withDefaults()
+	dumpType := uint32(1) // mini dump
+	if c.Full {
+		dumpType = 2
+	}
+
+	k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, localDumpsKeyPath+`\`+filepath.Base(program), registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open LocalDumps key: %w", err)
+	}
+	defer k.Close()
+	if err := k.SetExpandStringValue("DumpFolder", c.Dir); err != nil {
+		return fmt.Errorf("failed to set dump folder: %w", err)
+	}
+	if err := k.SetDWordValue("DumpCount", uint32(c.MaxDumps)); err != nil {
+		return fmt.Errorf("failed to set dump count: %w", err)
+	}
+	if err := k.SetDWordValue("DumpType", dumpType); err != nil {
+		return fmt.Errorf("failed to set dump type: %w", err)
+	}
+	return nil
+}
+
+// RemoveLocalDumps removes the settings made by InstallLocalDumps for
+// program. Removing settings that do not exist is not an error.
+func RemoveLocalDumps(program string) error {
+	err := registry.DeleteKey(registry.LOCAL_MACHINE, localDumpsKeyPath+`\`+filepath.Base(program))
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return fmt.Errorf("failed to delete LocalDumps key: %w", err)
+	}
+	return nil
+}
+
+const (
+	miniDumpWithFullMemory = 0x2
+	miniDumpWithHandleData = 0x4
+	miniDumpWithThreadInfo = 0x1000
+)
+
+// writeDump writes a dump of the process with the given ID to the dump
+// directory, deleting the oldest dumps beyond MaxDumps. It is used before
+// terminating a program that is hung rather than crashed, which Windows
+// Error Reporting does not catch.
+func writeDump(c DumpConfig, program string, pid int) (string, error) {
+	c = c.withDefaults()
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create dump directory: %w", err)
+	}
+
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_INFORMATION|windows.PROCESS_VM_READ|windows.PROCESS_DUP_HANDLE, false, uint32(pid))
+	if err != nil {
+		return "", fmt.Errorf("failed to open process: %w", err)
+	}
+	defer windows.CloseHandle(h)
+
+	base := strings.TrimSuffix(filepath.Base(program), filepath.Ext(program))
+	path := filepath.Join(c.Dir, fmt.Sprintf("%s-%s.%d.dmp", base, time.Now().UTC().Format("20060102T150405"), pid))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create dump file: %w", err)
+	}
+
+	dumpType := uint32(miniDumpWithHandleData | miniDumpWithThreadInfo)
+	if c.Full {
+		dumpType |= miniDumpWithFullMemory
+	}
+	err = miniDumpWriteDump(h, uint32(pid), windows.Handle(f.Fd()), dumpType)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write dump: %w", err)
+	}
+
+	pruneDumps(c, base)
+	return path, nil
+}
+
+// pruneDumps deletes the dumps of program base beyond MaxDumps.
+func pruneDumps(c DumpConfig, base string) {
+	matches, err := filepath.Glob(filepath.Join(c.Dir, base+"-*.dmp"))
+	if err != nil {
+		return
+	}
+	// The timestamp in the name sorts chronologically.
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	for i, path := range matches {
+		if i >= c.MaxDumps {
+			os.Remove(path)
+		}
+	}
+}
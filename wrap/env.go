@@ -0,0 +1,169 @@
+//go:build windows
+
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// EnvConfig configures the environment of the program.
+type EnvConfig struct {
+	// Clean starts the program with only the variables Windows itself
+	// needs, SystemRoot, SystemDrive and windir, instead of the wrapper's
+	// environment.
+	Clean bool
+	// Vars are added to the environment, in the form "NAME=value",
+	// replacing variables of the same name.
+	Vars []string
+	// FromParameters adds the values of the service's
+	// Parameters\Environment registry key, read each time the program
+	// starts, so variables can be changed without reinstalling the
+	// service. They replace Vars of the same name.
+	FromParameters bool
+	// Path, if set, replaces PATH. References to other variables, such as
+	// %SystemRoot%, are expanded.
+	Path string
+}
+
+// cleanVars are the variables kept in a clean environment.
+var cleanVars = []string{"SystemRoot", "SystemDrive", "windir"}
+
+// environ is an environment. Variable names are case-insensitive, as on
+// Windows.
+type environ struct {
+	names  map[string]string // upper-case name to name
+	values map[string]string // upper-case name to value
+}
+
+func newEnviron(vars []string) *environ {
+	e := &environ{names: map[string]string{}, values: map[string]string{}}
+	for _, kv := range vars {
+		// Skip the per-drive variables such as "=C:=C:\".
+		name, value, ok := strings.Cut(kv, "=")
+		if ok && name != "" {
+			e.set(name, value)
+		}
+	}
+	return e
+}
+
+func (e *environ) set(name, value string) {
+	key := strings.ToUpper(name)
+	e.names[key] = name
+	e.values[key] = value
+}
+
+func (e *environ) lookup(name string) (string, bool) {
+	v, ok := e.values[strings.ToUpper(name)]
+	return v, ok
+}
+
+// expand replaces the %NAME% references to variables in s with their
+// values, as the command prompt does. References to undefined variables
+// are left as they are.
+func (e *environ) expand(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '%')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i+1:], '%')
+		if j < 0 {
+			break
+		}
+		name := s[i+1 : i+1+j]
+		if v, ok := e.lookup(name); ok && name != "" {
+			b.WriteString(s[:i])
+			b.WriteString(v)
+			s = s[i+j+2:]
+			continue
+		}
+		// Keep the first % and look for a reference from the second.
+		b.WriteString(s[:i+1+j])
+		s = s[i+1+j:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// list returns the environment in the form "NAME=value", sorted by name.
+func (e *environ) list() []string {
+	keys := make([]string, 0, len(e.values))
+	for key := range e.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	vars := make([]string, len(keys))
+	for i, key := range keys {
+		vars[i] = e.names[key] + "=" + e.values[key]
+	}
+	return vars
+}
+
+// environment returns the environment of the program of the named service.
+func (c EnvConfig) environment(service string) (*environ, error) {
+	var e *environ
+	if c.Clean {
+		e = newEnviron(nil)
+		for _, name := range cleanVars {
+			if v, ok := os.LookupEnv(name); ok {
+				e.set(name, v)
+			}
+		}
+	} else {
+		e = newEnviron(os.Environ())
+	}
+
+	for _, kv := range c.Vars {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid environment variable %q", kv)
+		}
+		e.set(name, value)
+	}
+	if c.FromParameters {
+		if err := readParameterVars(service, e); err != nil {
+			return nil, err
+		}
+	}
+	if c.Path != "" {
+		e.set("PATH", e.expand(c.Path))
+	}
+	return e, nil
+}
+
+// readParameterVars sets the values of the Parameters\Environment key of
+// the named service in e. A missing key adds nothing.
+func readParameterVars(service string, e *environ) error {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Services\`+service+`\Parameters\Environment`, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open environment parameters key: %w", err)
+	}
+	defer k.Close()
+
+	names, err := k.ReadValueNames(0)
+	if err != nil {
+		return fmt.Errorf("failed to read environment parameters: %w", err)
+	}
+	for _, name := range names {
+		value, typ, err := k.GetStringValue(name)
+		if err != nil {
+			return fmt.Errorf("failed to read environment parameter %s: %w", name, err)
+		}
+		if typ == registry.EXPAND_SZ {
+			value = e.expand(value)
+		}
+		e.set(name, value)
+	}
+	return nil
+}
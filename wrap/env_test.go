@@ -0,0 +1,62 @@
+//go:build windows
+
+package wrap
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEnvironExpand(t *testing.T) {
+	e := newEnviron([]string{`SystemRoot=C:\Windows`, `HOME=C:\Users\me`, "A=a", "B=b", `=C:=C:\`})
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{`%SystemRoot%\System32`, `C:\Windows\System32`},
+		{`%systemroot%\System32`, `C:\Windows\System32`},
+		{`%UNDEFINED%\bin`, `%UNDEFINED%\bin`},
+		{"100%", "100%"},
+		{"%%", "%%"},
+		{"50% of %HOME%", `50% of C:\Users\me`},
+		{"%A%%B%", "ab"},
+		{"%A", "%A"},
+	} {
+		if got := e.expand(tt.in); got != tt.want {
+			t.Errorf("expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEnvironList(t *testing.T) {
+	e := newEnviron([]string{"Path=a", `=C:=C:\`, "b=2"})
+	e.set("PATH", "c")
+	if got, want := e.list(), []string{"b=2", "PATH=c"}; !slices.Equal(got, want) {
+		t.Errorf("list() = %q, want %q", got, want)
+	}
+}
+
+func TestEnvConfigEnvironment(t *testing.T) {
+	t.Setenv("SystemRoot", `C:\Windows`)
+	t.Setenv("WRAP_TEST_UNRELATED", "1")
+	e, err := EnvConfig{
+		Clean: true,
+		Vars:  []string{"TOOLS=C:\\Tools", "MODE=prod"},
+		Path:  `%TOOLS%\bin;%SystemRoot%\System32`,
+	}.environment("MyService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.lookup("WRAP_TEST_UNRELATED"); ok {
+		t.Error("clean environment kept an unrelated variable")
+	}
+	if got, want := e.expand("%PATH%"), `C:\Tools\bin;C:\Windows\System32`; got != want {
+		t.Errorf("PATH = %q, want %q", got, want)
+	}
+	if got, _ := e.lookup("mode"); got != "prod" {
+		t.Errorf("MODE = %q, want %q", got, "prod")
+	}
+
+	if _, err := (EnvConfig{Vars: []string{"NOVALUE"}}).environment("MyService"); err == nil {
+		t.Error("environment accepted a variable without a value")
+	}
+}
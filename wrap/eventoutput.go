@@ -0,0 +1,108 @@
+//go:build windows
+
+package wrap
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/lib-x/winsvc"
+)
+
+// eventMaxLine is the longest line written as one event; longer lines are
+// split, as by winsvc.EventLogWriter.
+const eventMaxLine = 16 << 10
+
+// eventWriter writes each line written to it as an event, with a severity
+// guessed from the line.
+type eventWriter struct {
+	l     winsvc.Logger
+	level winsvc.Severity
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func newEventWriter(l winsvc.Logger, level winsvc.Severity) *eventWriter {
+	return &eventWriter{l: l, level: level}
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			if len(w.buf) < eventMaxLine {
+				break
+			}
+			i = eventMaxLine
+		}
+		line := string(bytes.TrimRight(w.buf[:i], "\r"))
+		w.buf = w.buf[min(i+1, len(w.buf)):]
+		if strings.TrimSpace(line) != "" {
+			w.emit(line)
+		}
+	}
+	return len(p), nil
+}
+
+// flush logs the unterminated last line, if any.
+func (w *eventWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if line := string(bytes.TrimRight(w.buf, "\r")); strings.TrimSpace(line) != "" {
+		w.emit(line)
+	}
+	w.buf = nil
+}
+
+// emit logs line. Failures are ignored, so the program is not blocked by
+// an unavailable event log.
+func (w *eventWriter) emit(line string) {
+	switch lineSeverity(line, w.level) {
+	case winsvc.SeverityError:
+		w.l.Error(2, line)
+	case winsvc.SeverityWarning:
+		w.l.Warning(2, line)
+	default:
+		w.l.Info(2, line)
+	}
+}
+
+// severityWords are the words that mark a line as an error or a warning,
+// as commonly written by logging libraries.
+var severityWords = []struct {
+	words    []string
+	severity winsvc.Severity
+}{
+	{[]string{"FATAL", "PANIC", "CRITICAL", "ERROR", "ERR", "EXCEPTION"}, winsvc.SeverityError},
+	{[]string{"WARNING", "WARN"}, winsvc.SeverityWarning},
+	{[]string{"INFO", "DEBUG", "TRACE"}, winsvc.SeverityInfo},
+}
+
+// lineSeverity guesses the severity of line from the first severity word
+// in its first 64 bytes, where logging libraries put the level, or returns
+// def.
+func lineSeverity(line string, def winsvc.Severity) winsvc.Severity {
+	head := line
+	if len(head) > 64 {
+		head = head[:64]
+	}
+	fields := strings.FieldsFunc(strings.ToUpper(head), func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+	for _, f := range fields {
+		for _, sw := range severityWords {
+			for _, word := range sw.words {
+				if f == word {
+					return sw.severity
+				}
+			}
+		}
+	}
+	return def
+}
@@ -0,0 +1,161 @@
+//go:build windows
+
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lib-x/winsvc"
+)
+
+// HealthRule selects which children of a group must keep running for the
+// service to keep running.
+type HealthRule int
+
+const (
+	// RequireAll stops the service as soon as one child is given up on,
+	// because it exited without being restarted or exhausted its restart
+	// policy. It is the default.
+	RequireAll HealthRule = iota
+	// RequireAny keeps the service running until every child has been
+	// given up on.
+	RequireAny
+)
+
+// Group describes the programs a wrapper service runs side by side, such
+// as an application and its sidecars. Each child is supervised on its own,
+// with its own restart policy and health probe; Rule decides when the
+// service as a whole stops.
+type Group struct {
+	Children []Config
+	Rule     HealthRule
+	// Logger receives the wrapper's messages and those of RunAsService, and
+	// is the logger of the children that have none. If nil, messages go to
+	// the event log source named after the service, or to the console in
+	// debug mode.
+	Logger winsvc.Logger
+}
+
+// RunGroup runs the programs of g as the named Windows service, like Run.
+// When the service stops, the children are stopped concurrently. Children
+// capturing their output to files need distinct output directories.
+func RunGroup(name string, g Group, isDebug bool, options ...winsvc.RunOption) error {
+	if len(g.Children) == 0 {
+		return errors.New("no program to run")
+	}
+	for _, c := range g.Children {
+		if err := c.validate(); err != nil {
+			return err
+		}
+	}
+	if g.Logger == nil {
+		l, err := openLog(name, isDebug)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		g.Logger = l
+	}
+
+	gs := &groupSupervisor{rule: g.Rule, logger: g.Logger, exit: make(chan error, 1), stopped: make(chan struct{})}
+	for _, c := range g.Children {
+		if c.Logger == nil {
+			c.Logger = g.Logger
+		}
+		out, err := openOutput(c.Output, c.Logger)
+		if err != nil {
+			gs.closeOutput()
+			return err
+		}
+		gs.children = append(gs.children, newSupervisor(name, c, out))
+	}
+	defer gs.closeOutput()
+
+	options = append(options, winsvc.WithLogger(g.Logger), winsvc.ExitWhen(gs.exit))
+	for _, s := range gs.children {
+		if s.config().Reload != nil {
+			options = append(options, winsvc.OnParamChange(gs.reload))
+			break
+		}
+	}
+	return winsvc.RunAsService(name, gs.start, gs.stop, isDebug, options...)
+}
+
+// groupSupervisor runs the supervisors of the children of a group.
+type groupSupervisor struct {
+	children []*supervisor
+	rule     HealthRule
+	logger   winsvc.Logger
+	exit     chan error
+	// stopped is closed when the service is asked to stop.
+	stopped chan struct{}
+}
+
+// start starts the children and reports the end of the service on exit
+// once the rule says so. It is called by RunAsService once the service is
+// running.
+func (g *groupSupervisor) start() {
+	ended := make(chan error, len(g.children))
+	for _, s := range g.children {
+		go s.start()
+		go func() {
+			select {
+			case err := <-s.exit:
+				ended <- err
+			case <-g.stopped:
+			}
+		}()
+	}
+
+	var first error
+	for remaining := len(g.children); remaining > 0; remaining-- {
+		var err error
+		select {
+		case err = <-ended:
+		case <-g.stopped:
+			return
+		}
+		if g.rule == RequireAll || len(g.children) == 1 {
+			g.exit <- err
+			return
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+		g.logger.Warning(1, fmt.Sprintf("%d of %d programs still running", remaining-1, len(g.children)))
+	}
+	g.exit <- first
+}
+
+// stop stops the children concurrently and waits for them. It is called by
+// RunAsService when the service is asked to stop.
+func (g *groupSupervisor) stop() {
+	close(g.stopped)
+	var wg sync.WaitGroup
+	for _, s := range g.children {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.stop()
+		}()
+	}
+	wg.Wait()
+}
+
+// reload reloads the configuration of the children. It is called by
+// RunAsService on SERVICE_CONTROL_PARAMCHANGE.
+func (g *groupSupervisor) reload() {
+	for _, s := range g.children {
+		s.reload()
+	}
+}
+
+// closeOutput closes the output of the children. The children may have
+// reopened it after a reload.
+func (g *groupSupervisor) closeOutput() {
+	for _, s := range g.children {
+		s.out.Close()
+	}
+}
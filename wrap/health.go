@@ -0,0 +1,177 @@
+//go:build windows
+
+package wrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// HealthProbe periodically checks that the program is healthy. After
+// Failures consecutive failed checks the program is stopped, as when the
+// service stops, and restarted according to the restart policy.
+//
+// A probe with TCP set connects to that address, one with HTTP set sends a
+// GET request to that URL, and one with neither checks that the process is
+// still alive.
+type HealthProbe struct {
+	// TCP is the host:port the probe connects to.
+	TCP string
+	// HTTP is the URL the probe requests.
+	HTTP string
+	// Status is the HTTP status the probe expects, any 2xx status if zero.
+	Status int
+	// Interval is the time between checks, 10 seconds by default.
+	Interval time.Duration
+	// Timeout bounds each check, 5 seconds by default.
+	Timeout time.Duration
+	// StartPeriod is the time given to the program to start before the
+	// first check, Interval by default.
+	StartPeriod time.Duration
+	// Failures is the number of consecutive failed checks that recycle the
+	// program, 3 by default.
+	Failures int
+}
+
+func (p HealthProbe) withDefaults() HealthProbe {
+	if p.Interval <= 0 {
+		p.Interval = 10 * time.Second
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = 5 * time.Second
+	}
+	if p.StartPeriod <= 0 {
+		p.StartPeriod = p.Interval
+	}
+	if p.Failures <= 0 {
+		p.Failures = 3
+	}
+	return p
+}
+
+// check runs the probe once against the process with the given ID.
+func (p HealthProbe) check(pid int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+
+	switch {
+	case p.TCP != "":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", p.TCP)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case p.HTTP != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.HTTP, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if p.Status != 0 && resp.StatusCode != p.Status || p.Status == 0 && resp.StatusCode/100 != 2 {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+	default:
+		return processAlive(pid)
+	}
+}
+
+// stillActive is the exit code of a running process, STILL_ACTIVE.
+const stillActive = 259
+
+// processAlive returns an error unless the process with the given ID is
+// running.
+func processAlive(pid int) error {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("failed to open process: %w", err)
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return fmt.Errorf("failed to query process: %w", err)
+	}
+	if code != stillActive {
+		return fmt.Errorf("process exited with code %d", code)
+	}
+	return nil
+}
+
+// probeResult is one entry of the probe history.
+type probeResult struct {
+	time time.Time
+	err  error
+}
+
+// probeHistorySize is the number of results logged when the program is
+// recycled.
+const probeHistorySize = 10
+
+// errUnhealthy is reported when the program was recycled by its health
+// probe.
+var errUnhealthy = errors.New("failed its health probe")
+
+// watchHealth probes the child with the given ID, which started with cfg,
+// until done is closed or the service stops, recycling the child once the
+// probe fails too often. It sends errUnhealthy on recycled before
+// recycling it.
+func (s *supervisor) watchHealth(cfg Config, pid int, done <-chan struct{}, recycled chan<- error) {
+	p := cfg.Health.withDefaults()
+	var history []probeResult
+	failures := 0
+	wait := p.StartPeriod
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.stopped:
+			return
+		case <-time.After(wait):
+		}
+		wait = p.Interval
+
+		err := p.check(pid)
+		history = append(history, probeResult{time: time.Now(), err: err})
+		if len(history) > probeHistorySize {
+			history = history[1:]
+		}
+		if err == nil {
+			failures = 0
+			continue
+		}
+		failures++
+		cfg.Logger.Warning(1, fmt.Sprintf("%s health probe failed (%d of %d): %v", cfg.Program, failures, p.Failures, err))
+		if failures < p.Failures || s.isStopping() {
+			continue
+		}
+
+		cfg.Logger.Error(1, fmt.Sprintf("recycling %s after %d failed health probes; recent probes:\n%s", cfg.Program, failures, formatHistory(history)))
+		recycled <- errUnhealthy
+		s.terminate(cfg, pid, done)
+		return
+	}
+}
+
+func formatHistory(history []probeResult) string {
+	var b strings.Builder
+	for _, r := range history {
+		result := "ok"
+		if r.err != nil {
+			result = r.err.Error()
+		}
+		fmt.Fprintf(&b, "%s %s\n", r.time.UTC().Format(time.RFC3339), result)
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+//go:build windows
+
+package wrap
+
+import (
+	"fmt"
+	"time"
+)
+
+// HookFailure selects what a failing hook does.
+type HookFailure int
+
+const (
+	// HookFail makes a failing hook fail the run of the program: a failed
+	// pre-start hook keeps the program from starting, and a failed
+	// post-stop hook reports the run as failed. The restart policy then
+	// applies as if the program had failed. It is the default.
+	HookFail HookFailure = iota
+	// HookIgnore logs the failure and carries on.
+	HookIgnore
+)
+
+// Hook is a command run before the program starts or after it stops, such
+// as a schema migration or a cache flush. Hooks run with the working
+// directory, environment and output capture of the program, and their
+// Program and Args are resolved the same way.
+type Hook struct {
+	Program string
+	Args    []string
+	// Timeout is how long the hook may run before it is killed and
+	// considered failed, 1 minute by default.
+	Timeout time.Duration
+	// OnFailure selects what happens when the hook fails.
+	OnFailure HookFailure
+}
+
+// runHooks runs hooks in order with the working directory, environment and
+// output of cfg, stopping at the first failing one whose policy is
+// HookFail. stage names the hooks in messages.
+func (s *supervisor) runHooks(cfg Config, stage string, hooks []Hook) error {
+	for _, h := range hooks {
+		err := s.runHook(cfg, h)
+		if err == nil {
+			cfg.Logger.Info(1, fmt.Sprintf("%s hook %s succeeded", stage, h.Program))
+			continue
+		}
+		err = fmt.Errorf("%s hook %s failed: %w", stage, h.Program, err)
+		if h.OnFailure == HookIgnore {
+			cfg.Logger.Warning(1, err.Error())
+			continue
+		}
+		cfg.Logger.Error(1, err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *supervisor) runHook(c Config, h Hook) error {
+	c.Program, c.Args = h.Program, h.Args
+	cmd, err := c.command(s.name)
+	if err != nil {
+		return err
+	}
+	s.out.attach(cmd)
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+}
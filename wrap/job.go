@@ -0,0 +1,186 @@
+//go:build windows
+
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// JobLimits restricts the resources the program and its descendants may
+// use, so a runaway program cannot take down the host. Zero fields mean no
+// limit.
+type JobLimits struct {
+	// CPURate is the share of the machine's CPU time the processes may use
+	// together, in percent, from 0.01 to 100. It is a hard cap.
+	CPURate float64
+	// MinWorkingSet and MaxWorkingSet bound the working set of each
+	// process, in bytes. MinWorkingSet defaults to 1 MiB when only
+	// MaxWorkingSet is set.
+	MinWorkingSet uintptr
+	MaxWorkingSet uintptr
+	// MaxProcessMemory limits the memory each process may commit, in bytes.
+	MaxProcessMemory uintptr
+	// MaxJobMemory limits the memory the processes may commit together, in
+	// bytes.
+	MaxJobMemory uintptr
+	// MaxProcesses limits the number of processes running at once,
+	// including the program itself.
+	MaxProcesses uint32
+}
+
+const (
+	jobObjectCPURateControlEnable  = 0x1
+	jobObjectCPURateControlHardCap = 0x4
+)
+
+// jobObjectCPURateControlInformation is JOBOBJECT_CPU_RATE_CONTROL_INFORMATION
+// with its CpuRate member.
+type jobObjectCPURateControlInformation struct {
+	ControlFlags uint32
+	CPURate      uint32
+}
+
+// job is a job object holding the child and every process it starts.
+// Closing the job terminates the processes still in it, so descendants the
+// child leaves behind do not outlive it, and the wrapper crashing closes
+// the job along with its other handles.
+type job struct {
+	h windows.Handle
+}
+
+// newJob creates a job enforcing the limits, priority class and affinity
+// of c.
+func newJob(c Config) (*job, error) {
+	h, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job object: %w", err)
+	}
+	j := &job{h: h}
+	if err := j.limit(c.Limits, c.Priority, c.Affinity); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *job) limit(limits JobLimits, priority PriorityClass, affinity uintptr) error {
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+	basic := &info.BasicLimitInformation
+	basic.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if limits.MaxWorkingSet != 0 {
+		basic.LimitFlags |= windows.JOB_OBJECT_LIMIT_WORKINGSET
+		basic.MinimumWorkingSetSize = limits.MinWorkingSet
+		if basic.MinimumWorkingSetSize == 0 {
+			basic.MinimumWorkingSetSize = 1 << 20
+		}
+		basic.MaximumWorkingSetSize = limits.MaxWorkingSet
+	}
+	if limits.MaxProcessMemory != 0 {
+		basic.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_MEMORY
+		info.ProcessMemoryLimit = limits.MaxProcessMemory
+	}
+	if limits.MaxJobMemory != 0 {
+		basic.LimitFlags |= windows.JOB_OBJECT_LIMIT_JOB_MEMORY
+		info.JobMemoryLimit = limits.MaxJobMemory
+	}
+	if priority != 0 {
+		basic.LimitFlags |= windows.JOB_OBJECT_LIMIT_PRIORITY_CLASS
+		basic.PriorityClass = uint32(priority)
+	}
+	if affinity != 0 {
+		basic.LimitFlags |= windows.JOB_OBJECT_LIMIT_AFFINITY
+		basic.Affinity = affinity
+	}
+	if limits.MaxProcesses != 0 {
+		basic.LimitFlags |= windows.JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+		basic.ActiveProcessLimit = limits.MaxProcesses
+	}
+	if _, err := windows.SetInformationJobObject(j.h, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		return fmt.Errorf("failed to configure job object: %w", err)
+	}
+
+	if limits.CPURate == 0 {
+		return nil
+	}
+	// CpuRate is in hundredths of a percent.
+	rate := jobObjectCPURateControlInformation{
+		ControlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap,
+		CPURate:      uint32(limits.CPURate * 100),
+	}
+	if rate.CPURate == 0 {
+		rate.CPURate = 1
+	}
+	if _, err := windows.SetInformationJobObject(j.h, windows.JobObjectCpuRateControlInformation,
+		uintptr(unsafe.Pointer(&rate)), uint32(unsafe.Sizeof(rate))); err != nil {
+		return fmt.Errorf("failed to set CPU rate of job object: %w", err)
+	}
+	return nil
+}
+
+// assign places the process with the given ID in the job. Processes it
+// started before being assigned are not contained, so it should be created
+// suspended, assigned, and only then resumed.
+func (j *job) assign(pid int) error {
+	p, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(p)
+	if err := windows.AssignProcessToJobObject(j.h, p); err != nil {
+		return fmt.Errorf("failed to assign process %d to job object: %w", pid, err)
+	}
+	return nil
+}
+
+// resumeProcess resumes the threads of the process with the given ID,
+// which was created with CREATE_SUSPENDED.
+func resumeProcess(pid int) error {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return fmt.Errorf("failed to list threads of process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(snap)
+
+	resumed := false
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	for err = windows.Thread32First(snap, &entry); err == nil; err = windows.Thread32Next(snap, &entry) {
+		if entry.OwnerProcessID != uint32(pid) {
+			continue
+		}
+		t, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			return fmt.Errorf("failed to open thread %d of process %d: %w", entry.ThreadID, pid, err)
+		}
+		_, err = windows.ResumeThread(t)
+		windows.CloseHandle(t)
+		if err != nil {
+			return fmt.Errorf("failed to resume process %d: %w", pid, err)
+		}
+		resumed = true
+	}
+	if !errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return fmt.Errorf("failed to list threads of process %d: %w", pid, err)
+	}
+	if !resumed {
+		return fmt.Errorf("failed to resume process %d: no threads found", pid)
+	}
+	return nil
+}
+
+// terminate terminates every process in the job.
+func (j *job) terminate() error {
+	if err := windows.TerminateJobObject(j.h, 1); err != nil {
+		return fmt.Errorf("failed to terminate job object: %w", err)
+	}
+	return nil
+}
+
+// Close closes the job, terminating the processes still in it.
+func (j *job) Close() error {
+	return windows.CloseHandle(j.h)
+}
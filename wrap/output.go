@@ -0,0 +1,118 @@
+//go:build windows
+
+package wrap
+
+import (
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/lib-x/winsvc"
+)
+
+// OutputConfig configures the capture of the child's standard output and
+// standard error, to files, the event log or both. Services run in session
+// 0 have no console, so their output is lost unless captured.
+type OutputConfig struct {
+	// Dir is the directory of the log files, stdout.log and stderr.log. If
+	// empty, no files are written. winsvc.ServiceLogDir returns a suitable
+	// directory.
+	Dir string
+	// Rotation sets when a file is rotated and how many rotated files are
+	// kept. Rotated files are named stdout-<time>.log and stderr-<time>.log.
+	Rotation winsvc.FileLogRotation
+	// Compress gzips rotated files, adding a .gz suffix to their names,
+	// like Rotation.Compress.
+	Compress bool
+	// EventLog writes each line of output as an event with ID 2 to the
+	// wrapper's logger, by default the service's event log source, in
+	// addition to the files in Dir if set. Lines from standard output are
+	// informational and those from standard error warnings, unless they
+	// look like errors or warnings; identical lines are collapsed and the
+	// events are rate limited.
+	EventLog bool
+	// EventLogRate is the number of events written per minute at most, 60
+	// by default. Lines beyond it are dropped and counted.
+	EventLogRate int
+}
+
+// output holds the writers capturing the child's output.
+type output struct {
+	files          []*winsvc.RotatingFile
+	stdout, stderr io.Writer
+	events         *winsvc.RateLimitedLogger
+	eventWriters   []*eventWriter
+}
+
+func openOutput(cfg OutputConfig, l winsvc.Logger) (*output, error) {
+	o := &output{}
+	var stdout, stderr []io.Writer
+	if cfg.Dir != "" {
+		rotation := cfg.Rotation
+		rotation.Compress = rotation.Compress || cfg.Compress
+		for _, name := range []string{"stdout", "stderr"} {
+			f, err := winsvc.NewRotatingFile(cfg.Dir, name, rotation)
+			if err != nil {
+				o.Close()
+				return nil, err
+			}
+			o.files = append(o.files, f)
+		}
+		stdout = append(stdout, o.files[0])
+		stderr = append(stderr, o.files[1])
+	}
+	if cfg.EventLog {
+		rate := cfg.EventLogRate
+		if rate <= 0 {
+			rate = 60
+		}
+		o.events = winsvc.NewRateLimitedLogger(l, 10*time.Second, rate)
+		o.eventWriters = []*eventWriter{
+			newEventWriter(o.events, winsvc.SeverityInfo),
+			newEventWriter(o.events, winsvc.SeverityWarning),
+		}
+		stdout = append(stdout, o.eventWriters[0])
+		stderr = append(stderr, o.eventWriters[1])
+	}
+	o.stdout = combineWriters(stdout)
+	o.stderr = combineWriters(stderr)
+	return o, nil
+}
+
+func combineWriters(ws []io.Writer) io.Writer {
+	switch len(ws) {
+	case 0:
+		return nil
+	case 1:
+		return ws[0]
+	}
+	return io.MultiWriter(ws...)
+}
+
+// attach directs the output of cmd to the configured destinations.
+func (o *output) attach(cmd *exec.Cmd) {
+	if o.stdout != nil {
+		cmd.Stdout = o.stdout
+	}
+	if o.stderr != nil {
+		cmd.Stderr = o.stderr
+	}
+}
+
+func (o *output) Close() error {
+	var err error
+	for _, f := range o.files {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	for _, w := range o.eventWriters {
+		w.flush()
+	}
+	if o.events != nil {
+		if ferr := o.events.Flush(); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
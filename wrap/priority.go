@@ -0,0 +1,16 @@
+//go:build windows
+
+package wrap
+
+import "golang.org/x/sys/windows"
+
+// PriorityClass is the scheduling priority class of a process.
+type PriorityClass uint32
+
+const (
+	PriorityIdle        PriorityClass = windows.IDLE_PRIORITY_CLASS
+	PriorityBelowNormal PriorityClass = windows.BELOW_NORMAL_PRIORITY_CLASS
+	PriorityNormal      PriorityClass = windows.NORMAL_PRIORITY_CLASS
+	PriorityAboveNormal PriorityClass = windows.ABOVE_NORMAL_PRIORITY_CLASS
+	PriorityHigh        PriorityClass = windows.HIGH_PRIORITY_CLASS
+)
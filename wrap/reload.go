@@ -0,0 +1,161 @@
+//go:build windows
+
+package wrap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// ReloadFile returns a Config.Reload function that reads the JSON file at
+// path over the configuration, so the file need only hold the fields it
+// changes, such as {"Args": ["-Xmx2g", "-jar", "app.jar"]}. Durations are
+// given in nanoseconds.
+func ReloadFile(path string) func(*Config) error {
+	return func(c *Config) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read configuration: %w", err)
+		}
+		if err := json.Unmarshal(data, c); err != nil {
+			return fmt.Errorf("failed to parse configuration %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// ReloadParameters returns a Config.Reload function that reads the
+// following values of the named service's Parameters registry key, when
+// present, over the configuration:
+//
+//	Program      REG_SZ        Program
+//	Args         REG_MULTI_SZ  Args
+//	Dir          REG_SZ        Dir
+//	Environment  REG_MULTI_SZ  Env.Vars, as NAME=value strings
+//	OutputDir    REG_SZ        Output.Dir
+func ReloadParameters(service string) func(*Config) error {
+	return func(c *Config) error {
+		k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Services\`+service+`\Parameters`, registry.QUERY_VALUE)
+		if err != nil {
+			if errors.Is(err, registry.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("failed to open parameters key: %w", err)
+		}
+		defer k.Close()
+
+		for name, dst := range map[string]*string{"Program": &c.Program, "Dir": &c.Dir, "OutputDir": &c.Output.Dir} {
+			v, _, err := k.GetStringValue(name)
+			if errors.Is(err, registry.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read parameter %s: %w", name, err)
+			}
+			*dst = v
+		}
+		for name, dst := range map[string]*[]string{"Args": &c.Args, "Environment": &c.Env.Vars} {
+			v, _, err := k.GetStringsValue(name)
+			if errors.Is(err, registry.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read parameter %s: %w", name, err)
+			}
+			*dst = v
+		}
+		return nil
+	}
+}
+
+// clone returns a copy of c sharing no slices or pointers with it, so
+// that Reload can modify it while c is in use.
+func (c Config) clone() Config {
+	c.Args = slices.Clone(c.Args)
+	c.Env.Vars = slices.Clone(c.Env.Vars)
+	c.Shutdown.Methods = slices.Clone(c.Shutdown.Methods)
+	c.PreStart = cloneHooks(c.PreStart)
+	c.PostStop = cloneHooks(c.PostStop)
+	if c.Health != nil {
+		h := *c.Health
+		c.Health = &h
+	}
+	if c.Dumps != nil {
+		d := *c.Dumps
+		c.Dumps = &d
+	}
+	return c
+}
+
+func cloneHooks(hooks []Hook) []Hook {
+	hooks = slices.Clone(hooks)
+	for i := range hooks {
+		hooks[i].Args = slices.Clone(hooks[i].Args)
+	}
+	return hooks
+}
+
+// reload reads the child's configuration again with cfg.Reload. The new
+// configuration takes effect the next time the child starts.
+func (s *supervisor) reload() {
+	s.mu.Lock()
+	cur := s.cfg
+	next := cur
+	if s.pending != nil {
+		next = *s.pending
+	}
+	s.mu.Unlock()
+
+	if next.Reload == nil {
+		return
+	}
+	next = next.clone()
+	logger, reload := next.Logger, next.Reload
+	err := reload(&next)
+	next.Logger, next.Reload = logger, reload
+	if err == nil {
+		err = next.validate()
+	}
+	if err != nil {
+		cur.Logger.Error(1, fmt.Sprintf("failed to reload configuration of %s: %v", cur.Program, err))
+		return
+	}
+
+	s.mu.Lock()
+	s.pending = &next
+	s.mu.Unlock()
+	cur.Logger.Info(1, fmt.Sprintf("reloaded configuration of %s; it applies from the next start of %s", cur.Program, next.Program))
+}
+
+// applyReload makes the configuration read by reload, if any, current, and
+// returns the current configuration. It is called before the child starts,
+// which uses the returned copy.
+func (s *supervisor) applyReload() Config {
+	s.mu.Lock()
+	cur, next := s.cfg, s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if next == nil {
+		return cur
+	}
+
+	if next.Output != cur.Output {
+		out, err := openOutput(next.Output, next.Logger)
+		if err != nil {
+			cur.Logger.Error(1, fmt.Sprintf("failed to reopen the output of %s, keeping the previous one: %v", next.Program, err))
+			next.Output = cur.Output
+		} else {
+			s.out.Close()
+			s.out = out
+		}
+	}
+	s.mu.Lock()
+	s.cfg = *next
+	s.mu.Unlock()
+	return *next
+}
@@ -0,0 +1,112 @@
+//go:build windows
+
+package wrap
+
+import (
+	"fmt"
+	"time"
+)
+
+// RestartMode selects when a wrapper restarts its program after it exits.
+type RestartMode int
+
+const (
+	// RestartNever stops the service when the program exits. It is the
+	// default.
+	RestartNever RestartMode = iota
+	// RestartOnFailure restarts the program when it exits with a non-zero
+	// code or cannot be started, and stops the service when it exits with
+	// code 0.
+	RestartOnFailure
+	// RestartAlways restarts the program whenever it exits.
+	RestartAlways
+)
+
+// RestartPolicy configures how a wrapper restarts its program. Zero fields
+// take their defaults.
+type RestartPolicy struct {
+	Mode RestartMode
+	// Delay is the wait before the first restart, 1 second by default. It
+	// doubles with each consecutive restart, up to MaxDelay.
+	Delay time.Duration
+	// MaxDelay bounds the wait between restarts, 1 minute by default.
+	MaxDelay time.Duration
+	// MaxRestarts is the number of restarts allowed within Window, 5 by
+	// default. Once exceeded, the wrapper gives up and the service stops
+	// with the program's last error.
+	MaxRestarts int
+	// Window is the period over which restarts are counted, 10 minutes by
+	// default.
+	Window time.Duration
+	// ResetAfter is how long the program must run for the delay to start
+	// over from Delay, 1 minute by default.
+	ResetAfter time.Duration
+}
+
+func (p RestartPolicy) withDefaults() RestartPolicy {
+	if p.Delay <= 0 {
+		p.Delay = time.Second
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = time.Minute
+	}
+	if p.MaxRestarts <= 0 {
+		p.MaxRestarts = 5
+	}
+	if p.Window <= 0 {
+		p.Window = 10 * time.Minute
+	}
+	if p.ResetAfter <= 0 {
+		p.ResetAfter = time.Minute
+	}
+	return p
+}
+
+// restarter tracks the restarts of a program under a policy.
+type restarter struct {
+	policy   RestartPolicy
+	delay    time.Duration
+	restarts []time.Time
+}
+
+func newRestarter(p RestartPolicy) *restarter {
+	p = p.withDefaults()
+	return &restarter{policy: p, delay: p.Delay}
+}
+
+// next reports whether a program that ran for ran and exited with err
+// should be restarted, and after which delay. It returns an error once the
+// policy is exhausted.
+func (r *restarter) next(ran time.Duration, err error) (time.Duration, bool, error) {
+	switch r.policy.Mode {
+	case RestartNever:
+		return 0, false, nil
+	case RestartOnFailure:
+		if err == nil {
+			return 0, false, nil
+		}
+	}
+
+	now := time.Now()
+	recent := r.restarts[:0]
+	for _, t := range r.restarts {
+		if now.Sub(t) < r.policy.Window {
+			recent = append(recent, t)
+		}
+	}
+	r.restarts = recent
+	if len(r.restarts) >= r.policy.MaxRestarts {
+		return 0, false, fmt.Errorf("restarted %d times within %v, giving up", len(r.restarts), r.policy.Window)
+	}
+	r.restarts = append(r.restarts, now)
+
+	if ran >= r.policy.ResetAfter {
+		r.delay = r.policy.Delay
+	}
+	delay := r.delay
+	r.delay *= 2
+	if r.delay > r.policy.MaxDelay {
+		r.delay = r.policy.MaxDelay
+	}
+	return delay, true, nil
+}
@@ -0,0 +1,107 @@
+//go:build windows
+
+package wrap
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRestarter(t *testing.T) {
+	failed := errors.New("exit status 1")
+	type exit struct {
+		ran time.Duration
+		err error
+	}
+	type decision struct {
+		delay   time.Duration
+		restart bool
+		giveUp  bool
+	}
+	tests := []struct {
+		name   string
+		policy RestartPolicy
+		exits  []exit
+		want   []decision
+	}{
+		{
+			name:   "never",
+			policy: RestartPolicy{},
+			exits:  []exit{{0, failed}},
+			want:   []decision{{}},
+		},
+		{
+			name:   "on failure after success",
+			policy: RestartPolicy{Mode: RestartOnFailure},
+			exits:  []exit{{0, nil}},
+			want:   []decision{{}},
+		},
+		{
+			name:   "always after success",
+			policy: RestartPolicy{Mode: RestartAlways},
+			exits:  []exit{{0, nil}},
+			want:   []decision{{delay: time.Second, restart: true}},
+		},
+		{
+			name:   "backoff",
+			policy: RestartPolicy{Mode: RestartOnFailure, Delay: time.Second, MaxDelay: 5 * time.Second, MaxRestarts: 10},
+			exits:  []exit{{0, failed}, {0, failed}, {0, failed}, {0, failed}, {0, failed}},
+			want: []decision{
+				{delay: time.Second, restart: true},
+				{delay: 2 * time.Second, restart: true},
+				{delay: 4 * time.Second, restart: true},
+				{delay: 5 * time.Second, restart: true},
+				{delay: 5 * time.Second, restart: true},
+			},
+		},
+		{
+			name:   "reset after a long run",
+			policy: RestartPolicy{Mode: RestartOnFailure, MaxRestarts: 10},
+			exits:  []exit{{0, failed}, {0, failed}, {2 * time.Minute, failed}, {0, failed}},
+			want: []decision{
+				{delay: time.Second, restart: true},
+				{delay: 2 * time.Second, restart: true},
+				{delay: time.Second, restart: true},
+				{delay: 2 * time.Second, restart: true},
+			},
+		},
+		{
+			name:   "gives up",
+			policy: RestartPolicy{Mode: RestartAlways, MaxRestarts: 2},
+			exits:  []exit{{0, failed}, {0, nil}, {0, failed}},
+			want: []decision{
+				{delay: time.Second, restart: true},
+				{delay: 2 * time.Second, restart: true},
+				{giveUp: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRestarter(tt.policy)
+			for i, e := range tt.exits {
+				delay, restart, err := r.next(e.ran, e.err)
+				got := decision{delay: delay, restart: restart, giveUp: err != nil}
+				if got != tt.want[i] {
+					t.Fatalf("exit %d: next(%v, %v) = %v, %v, %v; want %+v", i+1, e.ran, e.err, delay, restart, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRestartPolicyDefaults(t *testing.T) {
+	got := RestartPolicy{Mode: RestartAlways}.withDefaults()
+	want := RestartPolicy{
+		Mode:        RestartAlways,
+		Delay:       time.Second,
+		MaxDelay:    time.Minute,
+		MaxRestarts: 5,
+		Window:      10 * time.Minute,
+		ResetAfter:  time.Minute,
+	}
+	if got != want {
+		t.Errorf("withDefaults() = %+v, want %+v", got, want)
+	}
+}
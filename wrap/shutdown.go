@@ -0,0 +1,202 @@
+//go:build windows
+
+package wrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// StopMethod is a way of asking the program to exit gracefully.
+type StopMethod int
+
+const (
+	// StopCtrlBreak sends CTRL_BREAK to the program's console process
+	// group, which console programs treat like Ctrl+C.
+	StopCtrlBreak StopMethod = iota + 1
+	// StopWMClose posts WM_CLOSE to the program's top-level windows.
+	StopWMClose
+	// StopHook calls ShutdownConfig.Hook.
+	StopHook
+)
+
+func (m StopMethod) String() string {
+	switch m {
+	case StopCtrlBreak:
+		return "CTRL_BREAK"
+	case StopWMClose:
+		return "WM_CLOSE"
+	case StopHook:
+		return "shutdown hook"
+	}
+	return fmt.Sprintf("StopMethod(%d)", int(m))
+}
+
+// ShutdownConfig configures how the program is stopped when the service
+// stops. Each method is tried in turn, given Wait for the program to exit,
+// and the program and its descendants are terminated once all have failed.
+type ShutdownConfig struct {
+	// Methods are the methods tried, in order. By default they are
+	// StopHook, if Hook is set, then StopCtrlBreak and StopWMClose.
+	Methods []StopMethod
+	// Wait is how long each method is given, 5 seconds by default.
+	Wait time.Duration
+	// Hook is called by StopHook. An http:// or https:// URL receives an
+	// empty POST request and must answer with a 2xx status; a named pipe
+	// path such as \\.\pipe\myapp receives the line "stop".
+	Hook string
+}
+
+func (c ShutdownConfig) withDefaults() ShutdownConfig {
+	if len(c.Methods) == 0 {
+		if c.Hook != "" {
+			c.Methods = append(c.Methods, StopHook)
+		}
+		c.Methods = append(c.Methods, StopCtrlBreak, StopWMClose)
+	}
+	if c.Wait <= 0 {
+		c.Wait = 5 * time.Second
+	}
+	return c
+}
+
+// shutdown asks the child with the given process ID, which started with
+// c, to exit using each configured method in turn, and reports whether it
+// exited, which done being closed signals.
+func (s *supervisor) shutdown(c Config, pid int, done <-chan struct{}) bool {
+	cfg := c.Shutdown.withDefaults()
+	for _, method := range cfg.Methods {
+		began := time.Now()
+		if err := requestStop(method, pid, cfg); err != nil {
+			c.Logger.Warning(1, fmt.Sprintf("failed to stop %s with %v: %v", c.Program, method, err))
+			continue
+		}
+		c.Logger.Info(1, fmt.Sprintf("sent %v to %s", method, c.Program))
+		select {
+		case <-done:
+			c.Logger.Info(1, fmt.Sprintf("%s exited %v after %v", c.Program, time.Since(began).Round(time.Millisecond), method))
+			return true
+		case <-time.After(cfg.Wait):
+			c.Logger.Warning(1, fmt.Sprintf("%s did not exit within %v of %v", c.Program, cfg.Wait, method))
+		}
+	}
+	return false
+}
+
+func requestStop(method StopMethod, pid int, cfg ShutdownConfig) error {
+	switch method {
+	case StopCtrlBreak:
+		return sendCtrlBreak(uint32(pid))
+	case StopWMClose:
+		return closeWindows(uint32(pid))
+	case StopHook:
+		if cfg.Hook == "" {
+			return errors.New("no shutdown hook configured")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Wait)
+		defer cancel()
+		return callHook(ctx, cfg.Hook)
+	}
+	return fmt.Errorf("unknown stop method %d", int(method))
+}
+
+// consoleMu serializes attaching to the consoles of children, as a process
+// has at most one console.
+var consoleMu sync.Mutex
+
+// sendCtrlBreak sends CTRL_BREAK to the console process group of the
+// process with the given ID, which was started with
+// CREATE_NEW_PROCESS_GROUP. A service has no console, so it attaches to the
+// child's for the duration of the call; in debug mode, it already shares
+// it.
+func sendCtrlBreak(pid uint32) error {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+
+	err := attachConsole(pid)
+	if err != nil && !errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+		return fmt.Errorf("failed to attach to console: %w", err)
+	}
+	if err == nil {
+		defer freeConsole()
+	}
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, pid)
+}
+
+// closeWindows posts WM_CLOSE to the visible and hidden top-level windows
+// of the process with the given ID.
+func closeWindows(pid uint32) error {
+	windowsMu.Lock()
+	defer windowsMu.Unlock()
+	closing = closeRequest{pid: pid}
+	err := windows.EnumWindows(enumWindowsCallback, nil)
+	if err != nil && closing.posted == 0 {
+		return fmt.Errorf("failed to enumerate windows: %w", err)
+	}
+	if closing.posted == 0 {
+		return errors.New("no windows found")
+	}
+	return nil
+}
+
+type closeRequest struct {
+	pid    uint32
+	posted int
+}
+
+var (
+	// windowsMu guards closing, the request enumWindowsCallback serves.
+	windowsMu sync.Mutex
+	closing   closeRequest
+	// enumWindowsCallback is created once, as Windows callbacks are never
+	// freed.
+	enumWindowsCallback = windows.NewCallback(func(hwnd windows.HWND, _ uintptr) uintptr {
+		var pid uint32
+		if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err == nil && pid == closing.pid {
+			if postMessage(hwnd, wmClose, 0, 0) == nil {
+				closing.posted++
+			}
+		}
+		return 1
+	})
+)
+
+// callHook calls the shutdown hook at target, a URL or a named pipe path.
+func callHook(ctx context.Context, target string) error {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("shutdown hook answered %s", resp.Status)
+		}
+		return nil
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open shutdown hook: %w", err)
+	}
+	defer f.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		f.SetWriteDeadline(deadline)
+	}
+	if _, err := f.WriteString("stop\n"); err != nil {
+		return fmt.Errorf("failed to write to shutdown hook: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+//go:build windows
+
+package wrap
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+	moduser32   = windows.NewLazySystemDLL("user32.dll")
+	moddbghelp  = windows.NewLazySystemDLL("dbghelp.dll")
+
+	procAttachConsole = modkernel32.NewProc("AttachConsole")
+	procFreeConsole   = modkernel32.NewProc("FreeConsole")
+
+	procPostMessageW = moduser32.NewProc("PostMessageW")
+
+	procMiniDumpWriteDump = moddbghelp.NewProc("MiniDumpWriteDump")
+)
+
+const wmClose = 0x0010
+
+// callErr converts the errno returned by a failed proc call into an error.
+func callErr(e syscall.Errno) error {
+	if e == 0 {
+		return syscall.EINVAL
+	}
+	return e
+}
+
+func attachConsole(pid uint32) error {
+	r, _, e := procAttachConsole.Call(uintptr(pid))
+	if r == 0 {
+		return callErr(e.(syscall.Errno))
+	}
+	return nil
+}
+
+func freeConsole() error {
+	r, _, e := procFreeConsole.Call()
+	if r == 0 {
+		return callErr(e.(syscall.Errno))
+	}
+	return nil
+}
+
+func postMessage(hwnd windows.HWND, msg uint32, wparam, lparam uintptr) error {
+	r, _, e := procPostMessageW.Call(uintptr(hwnd), uintptr(msg), wparam, lparam)
+	if r == 0 {
+		return callErr(e.(syscall.Errno))
+	}
+	return nil
+}
+
+func miniDumpWriteDump(process windows.Handle, pid uint32, file windows.Handle, dumpType uint32) error {
+	r, _, e := procMiniDumpWriteDump.Call(uintptr(process), uintptr(pid), uintptr(file), uintptr(dumpType), 0, 0, 0)
+	if r == 0 {
+		return callErr(e.(syscall.Errno))
+	}
+	return nil
+}
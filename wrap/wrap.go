@@ -0,0 +1,339 @@
+//go:build windows
+
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/lib-x/winsvc"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/debug"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// Config describes the program a wrapper service runs.
+type Config struct {
+	// Program is the path of the executable to run. A relative path is
+	// resolved against the directory of the wrapper executable, and a bare
+	// name is looked up there, then in PATH.
+	Program string
+	// Args are the arguments passed to Program.
+	Args []string
+	// Dir is the working directory of the program, by default the directory
+	// containing it. A relative path is resolved against the directory of
+	// the wrapper executable.
+	//
+	// References to the program's environment variables such as
+	// %ProgramData% are expanded in Program, Args and Dir each time the
+	// program starts.
+	Dir string
+	// Env configures the environment of the program, by default that of
+	// the wrapper.
+	Env EnvConfig
+	// Logger receives the wrapper's messages and those of RunAsService. If
+	// nil, they go to the event log source named after the service, or to
+	// the console in debug mode.
+	Logger winsvc.Logger `json:"-"`
+	// Output configures the capture of the program's standard output and
+	// standard error, which are discarded by default.
+	Output OutputConfig
+	// Restart sets whether and how the program is restarted when it exits.
+	// By default it is not, and the service stops.
+	Restart RestartPolicy
+	// Limits restricts the resources of the program and its descendants.
+	Limits JobLimits
+	// Priority is the priority class of the program and its descendants.
+	// If zero, it is inherited from the wrapper, normally PriorityNormal.
+	Priority PriorityClass
+	// Affinity is the mask of the processors the program and its
+	// descendants may run on, bit 0 being the first processor. If zero,
+	// they may run on all of them.
+	Affinity uintptr
+	// Shutdown configures how the program is asked to exit when the
+	// service stops, before it is terminated.
+	Shutdown ShutdownConfig
+	// Health, if set, periodically checks the program and recycles it when
+	// it is unhealthy.
+	Health *HealthProbe
+	// Dumps, if set, writes a dump of the program before it is terminated
+	// for not stopping or not passing its health probe, so hangs can be
+	// diagnosed. Use InstallLocalDumps to collect dumps of crashes.
+	Dumps *DumpConfig
+	// PreStart are run, in order, each time before the program starts.
+	PreStart []Hook
+	// PostStop are run, in order, each time after the program exits,
+	// including when the service stops.
+	PostStop []Hook
+	// Reload, if set, is called with a copy of the configuration when the
+	// service receives SERVICE_CONTROL_PARAMCHANGE, as sent by
+	// "sc paramchange", and updates it. The updated configuration takes
+	// effect the next time the program starts; Restart and Logger keep
+	// their original values. ReloadFile and ReloadParameters return
+	// suitable functions.
+	Reload func(*Config) error `json:"-"`
+}
+
+// Run runs the program described by cfg as the named Windows service, like
+// winsvc.RunAsService, until the service is stopped. If the program exits
+// on its own, it is restarted as configured by cfg.Restart; otherwise, or
+// once the restart policy is exhausted, the service stops too, reporting a
+// failure unless the program exited with code 0.
+func Run(name string, cfg Config, isDebug bool, options ...winsvc.RunOption) error {
+	return RunGroup(name, Group{Children: []Config{cfg}, Logger: cfg.Logger}, isDebug, options...)
+}
+
+// validate checks the parts of c that would otherwise only fail when the
+// program starts.
+func (c Config) validate() error {
+	if c.Program == "" {
+		return fmt.Errorf("no program to run")
+	}
+	if c.Limits.CPURate < 0 || c.Limits.CPURate > 100 {
+		return fmt.Errorf("CPU rate %v is not between 0 and 100 percent", c.Limits.CPURate)
+	}
+	return nil
+}
+
+// openLog opens the log RunAsService would use for the named service.
+func openLog(name string, isDebug bool) (debug.Log, error) {
+	if isDebug {
+		return debug.New(name), nil
+	}
+	l, err := eventlog.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return l, nil
+}
+
+// supervisor runs the child process of a wrapper service.
+type supervisor struct {
+	name string
+	out  *output
+	exit chan error
+
+	// stopped is closed when the service is asked to stop, and finished
+	// when start returns.
+	stopped  chan struct{}
+	finished chan struct{}
+
+	mu sync.Mutex
+	// cfg is the configuration the child starts with next. The child, its
+	// hooks and those stopping it use a copy taken when it starts, so that
+	// applyReload may replace cfg meanwhile.
+	cfg Config
+	// child is the configuration the running child started with.
+	child    Config
+	job      *job
+	pid      int
+	done     chan struct{}
+	stopping bool
+	// pending is the configuration reloaded since the child last started.
+	pending *Config
+}
+
+func newSupervisor(name string, cfg Config, out *output) *supervisor {
+	return &supervisor{
+		name:     name,
+		cfg:      cfg,
+		out:      out,
+		exit:     make(chan error, 1),
+		stopped:  make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+}
+
+// start runs the child, restarting it as the policy allows, until the
+// service is stopped or the child is not restarted. It is called by
+// RunAsService once the service is running.
+func (s *supervisor) start() {
+	defer close(s.finished)
+	r := newRestarter(s.config().Restart)
+	for {
+		began := time.Now()
+		cfg, err := s.run()
+		if s.isStopping() {
+			return
+		}
+
+		delay, restart, rerr := r.next(time.Since(began), err)
+		if rerr != nil {
+			cfg.Logger.Error(1, fmt.Sprintf("%s: %v", cfg.Program, rerr))
+			if err == nil {
+				err = rerr
+			}
+		}
+		if !restart {
+			s.exit <- err
+			return
+		}
+		if err != nil {
+			cfg.Logger.Warning(1, fmt.Sprintf("%v; restarting in %v", err, delay))
+		} else {
+			cfg.Logger.Info(1, fmt.Sprintf("%s exited; restarting in %v", cfg.Program, delay))
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-s.stopped:
+			return
+		}
+	}
+}
+
+// config returns the configuration the child starts with next.
+func (s *supervisor) config() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+func (s *supervisor) isStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping
+}
+
+// errStopped is returned by runChild when the service stopped before the
+// child could start.
+var errStopped = errors.New("service stopped")
+
+// run runs the child between its pre-start and post-stop hooks, with the
+// configuration it returns.
+func (s *supervisor) run() (Config, error) {
+	cfg := s.applyReload()
+	if err := s.runHooks(cfg, "pre-start", cfg.PreStart); err != nil {
+		return cfg, err
+	}
+	err := s.runChild(cfg)
+	if errors.Is(err, errStopped) {
+		return cfg, nil
+	}
+	if herr := s.runHooks(cfg, "post-stop", cfg.PostStop); herr != nil && err == nil {
+		err = herr
+	}
+	return cfg, err
+}
+
+// runChild starts the child with cfg in a job object and waits for it to
+// exit. Processes the child started that are still running are then
+// terminated.
+func (s *supervisor) runChild(cfg Config) error {
+	cmd, err := cfg.command(s.name)
+	if err != nil {
+		return err
+	}
+	s.out.attach(cmd)
+	done := make(chan struct{})
+
+	j, err := newJob(cfg)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return errStopped
+	}
+	// The child starts suspended and runs only once it is in the job, so
+	// that every process it starts is contained too.
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_SUSPENDED
+	if err := cmd.Start(); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to start %s: %w", cfg.Program, err)
+	}
+	if err := j.assign(cmd.Process.Pid); err != nil {
+		s.mu.Unlock()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	if err := resumeProcess(cmd.Process.Pid); err != nil {
+		s.mu.Unlock()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	s.child, s.job, s.pid, s.done = cfg, j, cmd.Process.Pid, done
+	s.mu.Unlock()
+	cfg.Logger.Info(1, fmt.Sprintf("started %s with pid %d", cfg.Program, cmd.Process.Pid))
+
+	recycled := make(chan error, 1)
+	if cfg.Health != nil {
+		go s.watchHealth(cfg, cmd.Process.Pid, done, recycled)
+	}
+
+	err = cmd.Wait()
+	s.mu.Lock()
+	s.job = nil
+	s.mu.Unlock()
+	close(done)
+	select {
+	case rerr := <-recycled:
+		return fmt.Errorf("%s %w", cfg.Program, rerr)
+	default:
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("%s exited: %w", cfg.Program, err)
+		}
+		return fmt.Errorf("failed to wait for %s: %w", cfg.Program, err)
+	}
+	return nil
+}
+
+// stop asks the child to exit as configured by cfg.Shutdown, terminates it
+// and its descendants if it does not, and waits for it to exit and for the
+// post-stop hooks to run. It is called by RunAsService when the service is
+// asked to stop.
+func (s *supervisor) stop() {
+	s.mu.Lock()
+	s.stopping = true
+	cfg, pid, done := s.child, s.pid, s.done
+	s.mu.Unlock()
+	close(s.stopped)
+
+	defer func() { <-s.finished }()
+	if done == nil {
+		return
+	}
+	select {
+	case <-done:
+		return
+	default:
+	}
+	s.terminate(cfg, pid, done)
+}
+
+// terminate stops the child with the given ID, which started with cfg, as
+// configured by cfg.Shutdown, terminates it and its descendants if it does
+// not exit, and waits for done to be closed.
+func (s *supervisor) terminate(cfg Config, pid int, done <-chan struct{}) {
+	if s.shutdown(cfg, pid, done) {
+		return
+	}
+
+	if cfg.Dumps != nil {
+		if path, err := writeDump(*cfg.Dumps, cfg.Program, pid); err != nil {
+			cfg.Logger.Warning(1, fmt.Sprintf("failed to dump %s: %v", cfg.Program, err))
+		} else {
+			cfg.Logger.Info(1, fmt.Sprintf("wrote dump of %s to %s", cfg.Program, path))
+		}
+	}
+	cfg.Logger.Warning(1, fmt.Sprintf("terminating %s", cfg.Program))
+	s.mu.Lock()
+	if s.job != nil {
+		if err := s.job.terminate(); err != nil {
+			cfg.Logger.Warning(1, err.Error())
+		}
+	}
+	s.mu.Unlock()
+	<-done
+}
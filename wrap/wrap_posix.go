@@ -0,0 +1,156 @@
+//go:build !windows
+
+// This file declares the API of the package for platforms other than
+// Windows, so that cross-platform programs build. See the Windows build for
+// the documentation.
+
+package wrap
+
+import (
+	"time"
+
+	"github.com/lib-x/winsvc"
+)
+
+const (
+	RequireAll          HealthRule    = 0
+	RequireAny          HealthRule    = 1
+	HookFail            HookFailure   = 0
+	HookIgnore          HookFailure   = 1
+	PriorityIdle        PriorityClass = 64
+	PriorityBelowNormal PriorityClass = 16384
+	PriorityNormal      PriorityClass = 32
+	PriorityAboveNormal PriorityClass = 32768
+	PriorityHigh        PriorityClass = 128
+	RestartNever        RestartMode   = 0
+	RestartOnFailure    RestartMode   = 1
+	RestartAlways       RestartMode   = 2
+	StopCtrlBreak       StopMethod    = 1
+	StopWMClose         StopMethod    = 2
+	StopHook            StopMethod    = 3
+)
+
+type DumpConfig struct {
+	Dir      string
+	Full     bool
+	MaxDumps int
+}
+
+type EnvConfig struct {
+	Clean          bool
+	Vars           []string
+	FromParameters bool
+	Path           string
+}
+
+type HealthRule int
+
+type Group struct {
+	Children []Config
+	Rule     HealthRule
+	Logger   winsvc.Logger
+}
+
+type HealthProbe struct {
+	TCP         string
+	HTTP        string
+	Status      int
+	Interval    time.Duration
+	Timeout     time.Duration
+	StartPeriod time.Duration
+	Failures    int
+}
+
+type HookFailure int
+
+type Hook struct {
+	Program   string
+	Args      []string
+	Timeout   time.Duration
+	OnFailure HookFailure
+}
+
+type JobLimits struct {
+	CPURate          float64
+	MinWorkingSet    uintptr
+	MaxWorkingSet    uintptr
+	MaxProcessMemory uintptr
+	MaxJobMemory     uintptr
+	MaxProcesses     uint32
+}
+
+type OutputConfig struct {
+	Dir          string
+	Rotation     winsvc.FileLogRotation
+	Compress     bool
+	EventLog     bool
+	EventLogRate int
+}
+
+type PriorityClass uint32
+
+type RestartMode int
+
+type RestartPolicy struct {
+	Mode        RestartMode
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	MaxRestarts int
+	Window      time.Duration
+	ResetAfter  time.Duration
+}
+
+type StopMethod int
+
+type ShutdownConfig struct {
+	Methods []StopMethod
+	Wait    time.Duration
+	Hook    string
+}
+
+type Config struct {
+	Program  string
+	Args     []string
+	Dir      string
+	Env      EnvConfig
+	Logger   winsvc.Logger `json:"-"`
+	Output   OutputConfig
+	Restart  RestartPolicy
+	Limits   JobLimits
+	Priority PriorityClass
+	Affinity uintptr
+	Shutdown ShutdownConfig
+	Health   *HealthProbe
+	Dumps    *DumpConfig
+	PreStart []Hook
+	PostStop []Hook
+	Reload   func(*Config) error `json:"-"`
+}
+
+func InstallLocalDumps(program string, c DumpConfig) error {
+	return winsvc.ErrUnsupportedPlatform
+}
+
+func RemoveLocalDumps(program string) error {
+	return winsvc.ErrUnsupportedPlatform
+}
+
+func RunGroup(name string, g Group, isDebug bool, options ...winsvc.RunOption) error {
+	return winsvc.ErrUnsupportedPlatform
+}
+
+func ReloadFile(path string) func(*Config) error {
+	return nil
+}
+
+func ReloadParameters(service string) func(*Config) error {
+	return nil
+}
+
+func Run(name string, cfg Config, isDebug bool, options ...winsvc.RunOption) error {
+	return winsvc.ErrUnsupportedPlatform
+}
+
+func (m StopMethod) String() string {
+	return ""
+}
@@ -0,0 +1,13 @@
+module github.com/lib-x/winsvc/zaplog
+
+go 1.22
+
+require (
+	github.com/lib-x/winsvc v0.0.0-20261014063515-b8ca2c9754c2
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+)
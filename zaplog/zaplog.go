@@ -0,0 +1,92 @@
+// Package zaplog provides a zap core that writes log entries to a Windows
+// event log, so services built on zap can tee their records into the event
+// log source registered for them by winsvc.
+//
+//	elog, err := eventlog.Open("MyService")
+//	...
+//	logger := zap.New(zapcore.NewTee(
+//		existingCore,
+//		zaplog.NewCore(elog, zapcore.InfoLevel),
+//	))
+package zaplog
+
+import (
+	"github.com/lib-x/winsvc"
+	"go.uber.org/zap/zapcore"
+)
+
+// EventID is the event ID entries are written with.
+const EventID = 1
+
+// Core is a zapcore.Core writing to an event log. Entries at error level
+// and above become error events, warnings become warning events, and
+// everything else information events.
+type Core struct {
+	zapcore.LevelEnabler
+	log winsvc.Logger
+	enc zapcore.Encoder
+}
+
+// NewCore returns a Core writing entries enabled by level to log, which is
+// usually an *eventlog.Log but may be any winsvc.Logger. Entries are
+// encoded like zap's console encoder without timestamps, which the event
+// log records itself.
+func NewCore(log winsvc.Logger, level zapcore.LevelEnabler) *Core {
+	cfg := zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	return NewCoreWithEncoder(log, zapcore.NewConsoleEncoder(cfg), level)
+}
+
+// NewCoreWithEncoder is like NewCore but encodes entries with enc, for
+// example a JSON encoder.
+func NewCoreWithEncoder(log winsvc.Logger, enc zapcore.Encoder, level zapcore.LevelEnabler) *Core {
+	return &Core{LevelEnabler: level, log: log, enc: enc}
+}
+
+// With implements zapcore.Core.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &Core{LevelEnabler: c.LevelEnabler, log: c.log, enc: enc}
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := buf.String()
+	buf.Free()
+
+	switch {
+	case ent.Level >= zapcore.ErrorLevel:
+		return c.log.Error(EventID, msg)
+	case ent.Level == zapcore.WarnLevel:
+		return c.log.Warning(EventID, msg)
+	default:
+		return c.log.Info(EventID, msg)
+	}
+}
+
+// Sync implements zapcore.Core. Event log writes are not buffered.
+func (c *Core) Sync() error {
+	return nil
+}